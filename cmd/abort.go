@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"k8sexec/pkg/k8sexec"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ExitAborted is the exit status of runs interrupted by SIGINT or SIGTERM.
+const ExitAborted = 130
+
+// ErrAborted is returned by runs interrupted by SIGINT or SIGTERM once their
+// partial report has been printed.
+var ErrAborted = errors.New("run aborted")
+
+// ErrFailedFast is returned by runs aborted by --fail-fast once their partial
+// report has been printed.
+var ErrFailedFast = errors.New("run aborted after a failure")
+
+// RunAborted marks a report of a run interrupted before all containers
+// completed, by a signal or by the first failure with --fail-fast.
+type RunAborted struct {
+	Signal string `json:"Signal,omitempty"`
+	// Failed is the namespace/pod/container whose failure aborted the run
+	// with --fail-fast.
+	Failed string `json:"Failed,omitempty"`
+	// Completed counts containers the command completed in, Skipped those
+	// it was not started in or canceled.
+	Completed int `json:"Completed"`
+	Skipped   int `json:"Skipped"`
+}
+
+// interruptContext returns a context canceled on the first SIGINT or
+// SIGTERM, after which the default handling is restored so that a second
+// one kills kubex at once. The received signal is sent to the returned
+// channel.
+func interruptContext() (context.Context, <-chan os.Signal) {
+	ctx, cancel := context.WithCancel(context.Background())
+	received := make(chan os.Signal, 1)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		signal.Stop(signals)
+		received <- sig
+		cancel()
+	}()
+	return ctx, received
+}
+
+// abortedRun returns the marker of a run interrupted by sig.
+func abortedRun(sig os.Signal, statuses []*k8sexec.ExecutionStatus) *RunAborted {
+	return countAborted(&RunAborted{Signal: sig.String()}, statuses)
+}
+
+// countAborted counts completed and skipped containers of an aborted run.
+func countAborted(aborted *RunAborted, statuses []*k8sexec.ExecutionStatus) *RunAborted {
+	for _, status := range statuses {
+		if status.ErrorKind == k8sexec.KindCanceled {
+			aborted.Skipped++
+		} else {
+			aborted.Completed++
+		}
+	}
+	return aborted
+}
+
+// printRunAborted prints the marker of an interrupted run in the text format.
+func printRunAborted(w io.Writer, aborted *RunAborted) {
+	cause := "by " + aborted.Signal
+	if aborted.Failed != "" {
+		cause = "after " + aborted.Failed + " failed"
+	}
+	fmt.Fprintf(w, "RUN ABORTED %s: %d containers completed, %d skipped\n", cause, aborted.Completed, aborted.Skipped)
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"k8sexec/pkg/k8sexec"
+	"strings"
+)
+
+// Modes of --annotation-commands.
+const (
+	// annotationCommandsInstead executes commands declared by pods
+	// instead of the given command.
+	annotationCommandsInstead = "instead"
+	// annotationCommandsAlso executes them after the given command.
+	annotationCommandsAlso = "also"
+)
+
+var (
+	annotationCommands string
+	commandAnnotation  string
+)
+
+// annotatedCommand returns the command declared by the pod of target with
+// the --command-annotation, executed with sh -c in all of its containers,
+// nil when it declares none.
+func annotatedCommand(target k8sexec.Target) []string {
+	_pod := lookupPod(target)
+	if _pod == nil {
+		return nil
+	}
+	command := strings.TrimSpace(_pod.Annotations[commandAnnotation])
+	if command == "" {
+		return nil
+	}
+	return []string{"sh", "-c", command}
+}
+
+// annotatedTargets returns targets whose pods declare a command.
+func annotatedTargets(targets []k8sexec.Target) []k8sexec.Target {
+	var annotated []k8sexec.Target
+	for _, target := range targets {
+		if annotatedCommand(target) != nil {
+			annotated = append(annotated, target)
+		}
+	}
+	return annotated
+}
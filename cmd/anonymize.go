@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var (
+	anonymize    bool
+	anonymizeMap string
+)
+
+// Prefixes of pseudonyms by the names of report fields they replace.
+var anonymizedFields = map[string]string{
+	"Namespace": "ns",
+	"Pod":       "pod",
+	"Node":      "node",
+	"NodeName":  "node",
+	"Image":     "image",
+	"ImageID":   "image",
+}
+
+// minAnonymizedLength is the length of names below which they are not
+// replaced within other text, e.g. outputs, as they would match too often.
+const minAnonymizedLength = 4
+
+// AnonymizeMapping maps pseudonyms to the names they replace. Key makes
+// pseudonyms deterministic for reports anonymized with the same mapping file,
+// while they cannot be reversed by hashing guessed names without it.
+type AnonymizeMapping struct {
+	Key   string            `json:"Key"`
+	Names map[string]string `json:"Names"`
+}
+
+// anonymizer replaces names of pods, namespaces, nodes and images in reports
+// with pseudonyms.
+type anonymizer struct {
+	mapping    *AnonymizeMapping
+	key        []byte
+	pseudonyms map[string]string
+}
+
+// anonymizeReport replaces names in report in place and records them in the
+// --anonymize-map file, which is created when it does not exist.
+func anonymizeReport(report any) error {
+	mapping := &AnonymizeMapping{}
+	data, err := os.ReadFile(anonymizeMap)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return err
+		}
+		mapping.Key = hex.EncodeToString(key)
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(data, mapping); err != nil {
+			return err
+		}
+	}
+	if mapping.Names == nil {
+		mapping.Names = map[string]string{}
+	}
+
+	key, err := hex.DecodeString(mapping.Key)
+	if err != nil {
+		return err
+	}
+	a := &anonymizer{mapping: mapping, key: key, pseudonyms: map[string]string{}}
+	value := reflect.ValueOf(report)
+	a.walk(value, "", a.collect)
+	replacer := a.replacer()
+	a.walk(value, "", func(name string, s reflect.Value) {
+		if pseudonym, ok := a.pseudonyms[s.String()]; ok && anonymizedFields[name] != "" {
+			s.SetString(pseudonym)
+		} else {
+			s.SetString(replacer.Replace(s.String()))
+		}
+	})
+
+	if data, err = json.MarshalIndent(mapping, "", "    "); err != nil {
+		return err
+	}
+	return os.WriteFile(anonymizeMap, data, 0o600)
+}
+
+// collect assigns pseudonyms to names in fields replaced by them.
+func (a *anonymizer) collect(name string, s reflect.Value) {
+	prefix := anonymizedFields[name]
+	if prefix == "" || s.String() == "" {
+		return
+	}
+	if _, ok := a.pseudonyms[s.String()]; ok {
+		return
+	}
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(prefix + "\x00" + s.String()))
+	pseudonym := prefix + "-" + hex.EncodeToString(mac.Sum(nil))[:10]
+	a.pseudonyms[s.String()] = pseudonym
+	a.mapping.Names[pseudonym] = s.String()
+}
+
+// replacer replaces names within text, longer ones first so that e.g. a pod
+// name is replaced rather than its namespace prefix.
+func (a *anonymizer) replacer() *strings.Replacer {
+	var names []string
+	for name := range a.pseudonyms {
+		if len(name) >= minAnonymizedLength {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	var pairs []string
+	for _, name := range names {
+		pairs = append(pairs, name, a.pseudonyms[name])
+	}
+	return strings.NewReplacer(pairs...)
+}
+
+// walk calls visit for settable strings reachable from v with the name of
+// the struct field holding them.
+func (a *anonymizer) walk(v reflect.Value, name string, visit func(name string, s reflect.Value)) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			a.walk(v.Elem(), name, visit)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				a.walk(v.Field(i), v.Type().Field(i).Name, visit)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			a.walk(v.Index(i), name, visit)
+		}
+	case reflect.Map:
+		// values of maps are not settable, they are walked in copies
+		for _, key := range v.MapKeys() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			a.walk(elem, name, visit)
+			v.SetMapIndex(key, elem)
+		}
+	case reflect.String:
+		if v.CanSet() {
+			visit(name, v)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"k8sexec/pkg/k8sexec"
+	"k8sexec/pkg/schema"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BenchReport holds latencies of executions measured by bench.
+type BenchReport struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	Metadata      *RunMetadata   `json:"metadata,omitempty"`
+	Namespace     string         `json:"Namespace"`
+	Command       []string       `json:"Command"`
+	Iterations    int            `json:"Iterations"`
+	Parallel      int            `json:"Parallel"`
+	Elapsed       time.Duration  `json:"Elapsed"`
+	Throughput    float64        `json:"Throughput"`
+	Setup         *LatencyStats  `json:"Setup"`
+	Runtime       *LatencyStats  `json:"Runtime"`
+	Containers    []*BenchTarget `json:"Containers"`
+}
+
+// BenchTarget holds latencies measured in a container.
+type BenchTarget struct {
+	Namespace string        `json:"Namespace"`
+	Pod       string        `json:"Pod"`
+	Container string        `json:"Container"`
+	Setup     *LatencyStats `json:"Setup"`
+	Runtime   *LatencyStats `json:"Runtime"`
+	Errors    int           `json:"Errors"`
+	LastError string        `json:"LastError,omitempty"`
+}
+
+// LatencyStats summarizes durations.
+type LatencyStats struct {
+	Min  time.Duration `json:"Min"`
+	Avg  time.Duration `json:"Avg"`
+	P50  time.Duration `json:"P50"`
+	P95  time.Duration `json:"P95"`
+	Max  time.Duration `json:"Max"`
+	Runs int           `json:"Runs"`
+}
+
+var benchIterations int
+
+var benchCmd = &cobra.Command{
+	Use:   "bench [command...]",
+	Short: "Measures latencies of executions in the selected containers",
+	Long: `Repeatedly executes a trivial command, or the given one, in the selected containers and
+measures the time it takes to set up an execution, until the command starts, separately from
+the runtime of the command. Run it with different --parallel values to size them for a cluster.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runBench(args)
+	},
+}
+
+// benchSample is a single measured execution.
+type benchSample struct {
+	setup, runtime time.Duration
+	err            error
+}
+
+// firstWrite records when something is written to it first.
+type firstWrite struct {
+	once sync.Once
+	at   time.Time
+}
+
+func (w *firstWrite) Write(p []byte) (int, error) {
+	w.once.Do(func() { w.at = time.Now() })
+	return len(p), nil
+}
+
+func runBench(args []string) error {
+	if err := validateOptions(); err != nil {
+		return err
+	}
+	if benchIterations < 1 {
+		return fmt.Errorf("--iterations must be at least 1")
+	}
+	if len(args) == 0 {
+		args = []string{"true"}
+	}
+
+	k8s := newK8SExec()
+	targets, err := loadTargets(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	if dryRun != "" && !simulate {
+		return printTargets(targets)
+	}
+
+	// the new line printed by the shell marks the start of the command
+	command := append([]string{"sh", "-c", `echo; exec "$@"`, "sh"}, args...)
+
+	samples := make([][]benchSample, len(targets))
+	for i := range samples {
+		samples[i] = make([]benchSample, benchIterations)
+	}
+
+	sem := make(chan struct{}, max(parallel, 1))
+	var wg sync.WaitGroup
+	started := time.Now()
+	for iteration := 0; iteration < benchIterations; iteration++ {
+		for i, target := range targets {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				samples[i][iteration] = benchExec(k8s, target, command)
+			}()
+		}
+	}
+	wg.Wait()
+	elapsed := time.Since(started)
+
+	report := &BenchReport{
+		SchemaVersion: schema.Version,
+		Namespace:     namespace,
+		Command:       args,
+		Iterations:    benchIterations,
+		Parallel:      max(parallel, 1),
+		Elapsed:       elapsed,
+		Throughput:    float64(len(targets)*benchIterations) / elapsed.Seconds(),
+	}
+	var allSetup, allRuntime []time.Duration
+	for i, target := range targets {
+		item := &BenchTarget{Namespace: target.Namespace, Pod: target.Pod, Container: target.Container}
+		var setup, runtime []time.Duration
+		for _, sample := range samples[i] {
+			if sample.err != nil {
+				item.Errors++
+				item.LastError = sample.err.Error()
+				continue
+			}
+			setup = append(setup, sample.setup)
+			runtime = append(runtime, sample.runtime)
+		}
+		item.Setup, item.Runtime = latencyStats(setup), latencyStats(runtime)
+		allSetup, allRuntime = append(allSetup, setup...), append(allRuntime, runtime...)
+		report.Containers = append(report.Containers, item)
+	}
+	report.Setup, report.Runtime = latencyStats(allSetup), latencyStats(allRuntime)
+
+	return printReport(report)
+}
+
+func benchExec(k8s *k8sexec.K8SExec, target k8sexec.Target, command []string) benchSample {
+	stdout := &firstWrite{}
+	started := time.Now()
+	result, err := k8s.Executor().Run(context.TODO(), target, k8sexec.Command{Args: command}, k8sexec.IO{
+		Stdout: stdout,
+		Stderr: io.Discard,
+	})
+	finished := time.Now()
+
+	switch {
+	case err != nil:
+		return benchSample{err: err}
+	case result.ExitCode != 0:
+		return benchSample{err: &k8sexec.ErrNonZeroExit{Code: result.ExitCode}}
+	case stdout.at.IsZero():
+		return benchSample{err: fmt.Errorf("no output received")}
+	}
+	return benchSample{setup: stdout.at.Sub(started), runtime: finished.Sub(stdout.at)}
+}
+
+func latencyStats(durations []time.Duration) *LatencyStats {
+	if len(durations) == 0 {
+		return nil
+	}
+	sorted := append([]time.Duration(nil), durations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	return &LatencyStats{
+		Min:  sorted[0],
+		Avg:  total / time.Duration(len(sorted)),
+		P50:  sorted[len(sorted)*50/100],
+		P95:  sorted[(len(sorted)*95+99)/100-1],
+		Max:  sorted[len(sorted)-1],
+		Runs: len(sorted),
+	}
+}
+
+func (s *LatencyStats) String() string {
+	if s == nil {
+		return "no successful runs"
+	}
+	return fmt.Sprintf("min %v avg %v p50 %v p95 %v max %v (%d runs)", s.Min.Round(time.Millisecond), s.Avg.Round(time.Millisecond),
+		s.P50.Round(time.Millisecond), s.P95.Round(time.Millisecond), s.Max.Round(time.Millisecond), s.Runs)
+}
+
+// WriteText implements output.TextWriter.
+func (r *BenchReport) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "BENCH: %q, %d iterations, parallel %d\n", r.Command, r.Iterations, r.Parallel)
+	fmt.Fprintf(w, "Namespace: %s\n", r.Namespace)
+	for _, item := range r.Containers {
+		fmt.Fprintf(w, "CONTAINER: %s/%s\n", item.Pod, item.Container)
+		fmt.Fprintf(w, "  setup:   %s\n", item.Setup)
+		fmt.Fprintf(w, "  runtime: %s\n", item.Runtime)
+		if item.Errors > 0 {
+			fmt.Fprintf(w, "  errors:  %d, last: %s\n", item.Errors, item.LastError)
+		}
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Setup:      %s\n", r.Setup)
+	fmt.Fprintf(w, "Runtime:    %s\n", r.Runtime)
+	fmt.Fprintf(w, "Elapsed:    %v, %.1f executions per second\n", r.Elapsed.Round(time.Millisecond), r.Throughput)
+	return nil
+}
+
+func init() {
+	benchCmd.Flags().IntVar(&benchIterations, "iterations", 10, "number of executions in each container")
+	benchCmd.Flags().SetInterspersed(false)
+	cmd.AddCommand(benchCmd)
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// cacheTTL is how long listings of the cluster are reused from the disk
+// cache by later invocations, 0 disables the cache.
+var cacheTTL time.Duration
+
+// cached returns the value cached under key for the current cluster when it
+// is younger than --cache-ttl, otherwise it calls load and caches its result.
+// Failures to read or write the cache only bypass it.
+func cached[T any](key string, load func() (T, error)) (T, error) {
+	if cacheTTL <= 0 {
+		return load()
+	}
+
+	path := cachePath(key)
+	if fi, err := os.Stat(path); err == nil && time.Since(fi.ModTime()) < cacheTTL {
+		if data, err := os.ReadFile(path); err == nil {
+			var value T
+			if json.Unmarshal(data, &value) == nil {
+				return value, nil
+			}
+		}
+	}
+
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+	if data, err := json.Marshal(value); err == nil && os.MkdirAll(filepath.Dir(path), 0o700) == nil {
+		// renamed once complete, so that concurrent invocations never
+		// read partially written files
+		if os.WriteFile(path+".tmp", data, 0o600) == nil {
+			_ = os.Rename(path+".tmp", path)
+		}
+	}
+	return value, nil
+}
+
+// cachePath returns the file key is cached in, separate for each cluster and
+// identity kubex connects with.
+func cachePath(key string) string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	sum := sha256.Sum256(append(cacheIdentity(), append([]byte{0}, key...)...))
+	return filepath.Join(dir, "kubex", hex.EncodeToString(sum[:])+".json")
+}
+
+// cacheIdentity returns the cluster and credentials kubex connects with, so
+// that listings are not shared by users authenticated differently, e.g. with
+// client certificates, exec or auth provider plugins or inline tokens.
+func cacheIdentity() []byte {
+	if config == nil {
+		return nil
+	}
+	identity := struct {
+		Context, AuthInfo            string
+		Host, Username, Password     string
+		BearerToken, BearerTokenFile string
+		CertFile, KeyFile            string
+		CertData                     []byte
+		ExecProvider                 *clientcmdapi.ExecConfig
+		AuthProvider                 *clientcmdapi.AuthProviderConfig
+		Impersonate                  rest.ImpersonationConfig
+	}{
+		Host:            config.Host,
+		Username:        config.Username,
+		Password:        config.Password,
+		BearerToken:     config.BearerToken,
+		BearerTokenFile: config.BearerTokenFile,
+		CertFile:        config.CertFile,
+		KeyFile:         config.KeyFile,
+		CertData:        config.CertData,
+		ExecProvider:    config.ExecProvider,
+		AuthProvider:    config.AuthProvider,
+		Impersonate:     config.Impersonate,
+	}
+	if clientConfig != nil {
+		if rawConfig, err := clientConfig.RawConfig(); err == nil {
+			identity.Context = rawConfig.CurrentContext
+			if kubeContext, ok := rawConfig.Contexts[rawConfig.CurrentContext]; ok {
+				identity.AuthInfo = kubeContext.AuthInfo
+			}
+		}
+	}
+	data, _ := json.Marshal(identity)
+	return data
+}
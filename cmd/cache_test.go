@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"testing"
+)
+
+func TestCachePathIdentity(t *testing.T) {
+	previous := config
+	t.Cleanup(func() { config = previous })
+
+	identities := map[string]*rest.Config{
+		"certificate":       {Host: "https://cluster", TLSClientConfig: rest.TLSClientConfig{CertData: []byte("alice")}},
+		"other certificate": {Host: "https://cluster", TLSClientConfig: rest.TLSClientConfig{CertData: []byte("bob")}},
+		"token":             {Host: "https://cluster", BearerToken: "alice"},
+		"other token":       {Host: "https://cluster", BearerToken: "bob"},
+		"exec plugin":       {Host: "https://cluster", ExecProvider: &clientcmdapi.ExecConfig{Command: "login", Args: []string{"alice"}}},
+		"other exec plugin": {Host: "https://cluster", ExecProvider: &clientcmdapi.ExecConfig{Command: "login", Args: []string{"bob"}}},
+		"auth provider":     {Host: "https://cluster", AuthProvider: &clientcmdapi.AuthProviderConfig{Name: "oidc", Config: map[string]string{"client-id": "alice"}}},
+		"other cluster":     {Host: "https://other", BearerToken: "alice"},
+	}
+	paths := map[string]string{}
+	for name, identity := range identities {
+		config = identity
+		path := cachePath("pods")
+		if other, ok := paths[path]; ok {
+			t.Errorf("%s and %s share cache file %s", name, other, path)
+		}
+		paths[path] = name
+		if cachePath("pods") != path {
+			t.Errorf("cache file of %s is not stable", name)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"k8sexec/pkg/k8sexec"
+	"os"
+	"strings"
+)
+
+var (
+	canary         bool
+	canaryExitCode int
+)
+
+// runCanary executes the command in the canary target and prints its result
+// to stderr. The sweep continues with the remaining targets when the canary
+// exits with --canary-exit-code or, without it, when confirmed on the
+// terminal, otherwise an error is returned.
+func runCanary(ctx context.Context, k8s *k8sexec.K8SExec, target k8sexec.Target, remaining int, args []string, opts k8sexec.ExecOptions) (*k8sexec.ExecutionStatus, error) {
+	status := k8s.ExecAll(ctx, []k8sexec.Target{target}, args, opts)[0]
+	_, _ = fmt.Fprintln(os.Stderr, "CANARY:")
+	printStatus(os.Stderr, status, false)
+
+	if canaryExitCode >= 0 {
+		if status.RetCode != canaryExitCode || (status.ErrorKind != "" && status.ErrorKind != k8sexec.KindNonZeroExit) {
+			return status, fmt.Errorf("canary %s/%s exited with %d instead of %d, the command was not executed in the remaining %d containers",
+				status.Pod, status.Container, status.RetCode, canaryExitCode, remaining)
+		}
+		return status, nil
+	}
+
+	// stdin may hold the script executed in containers
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return status, errors.New("--canary requires a terminal to confirm the sweep, or --canary-exit-code")
+	}
+	defer tty.Close()
+	_, _ = fmt.Fprintf(os.Stderr, "Execute the command in the remaining %d containers? [y/N] ", remaining)
+	answer, _ := bufio.NewReader(tty).ReadString('\n')
+	if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+		return status, errors.New("sweep canceled after the canary")
+	}
+	return status, nil
+}
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"k8sexec/pkg/k8sexec"
+	"os"
+	"slices"
+	"sync"
+)
+
+var (
+	checkpointPath string
+	resume         bool
+)
+
+// CheckpointHeader is the first line of a checkpoint file identifying the
+// sweep, the following lines are statuses of completed targets.
+type CheckpointHeader struct {
+	Namespace string   `json:"Namespace"`
+	Args      []string `json:"Args"`
+	// Stdin is the SHA-256 digest of stdin of the command.
+	Stdin string `json:"Stdin"`
+}
+
+// checkpoint records statuses of completed targets of a sweep in a file as
+// they complete, so that an interrupted sweep can be resumed.
+type checkpoint struct {
+	mu      sync.Mutex
+	file    *os.File
+	enc     *json.Encoder
+	done    map[k8sexec.Target]*k8sexec.ExecutionStatus
+	targets []k8sexec.Target
+}
+
+// openCheckpoint creates the checkpoint file at path for the sweep of
+// header, or with resume loads statuses recorded in it for the same sweep
+// and appends to it.
+func openCheckpoint(path string, header CheckpointHeader, resume bool) (*checkpoint, error) {
+	c := &checkpoint{done: map[k8sexec.Target]*k8sexec.ExecutionStatus{}}
+
+	if resume {
+		if err := c.load(path, header); err != nil {
+			return nil, err
+		}
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		c.file = file
+		c.enc = json.NewEncoder(file)
+		return c, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	c.file = file
+	c.enc = json.NewEncoder(file)
+	if err := c.enc.Encode(header); err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// load reads statuses recorded in the checkpoint file at path. A partially
+// written last line of an interrupted sweep is ignored.
+func (c *checkpoint) load(path string, header CheckpointHeader) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	if !scanner.Scan() {
+		return fmt.Errorf("checkpoint %s is empty", path)
+	}
+	var recorded CheckpointHeader
+	if err := json.Unmarshal(scanner.Bytes(), &recorded); err != nil {
+		return fmt.Errorf("invalid checkpoint %s: %w", path, err)
+	}
+	if recorded.Namespace != header.Namespace || recorded.Stdin != header.Stdin || !slices.Equal(recorded.Args, header.Args) {
+		return fmt.Errorf("checkpoint %s was recorded for a different command or namespace", path)
+	}
+
+	for scanner.Scan() {
+		var status k8sexec.ExecutionStatus
+		if err := json.Unmarshal(scanner.Bytes(), &status); err != nil {
+			continue
+		}
+		target := k8sexec.Target{Namespace: status.Namespace, Pod: status.Pod, Container: status.Container}
+		if _, ok := c.done[target]; !ok {
+			c.targets = append(c.targets, target)
+		}
+		c.done[target] = &status
+	}
+	return scanner.Err()
+}
+
+// Record writes status to the checkpoint file unless the command was
+// canceled, e.g. because the sweep was interrupted. Failures are reported on
+// stderr, they do not fail the sweep.
+func (c *checkpoint) Record(status *k8sexec.ExecutionStatus) {
+	if status.ErrorKind == k8sexec.KindCanceled {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.enc.Encode(status); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Failed to record a checkpoint: %v\n", err)
+	}
+}
+
+// Remaining returns targets without a recorded status.
+func (c *checkpoint) Remaining(targets []k8sexec.Target) []k8sexec.Target {
+	var remaining []k8sexec.Target
+	for _, target := range targets {
+		if _, ok := c.done[target]; !ok {
+			remaining = append(remaining, target)
+		}
+	}
+	return remaining
+}
+
+// Merge returns statuses of targets, recorded ones from the checkpoint and
+// the others from statuses in the order of Remaining. Recorded statuses of
+// targets no longer selected follow them.
+func (c *checkpoint) Merge(targets []k8sexec.Target, statuses []*k8sexec.ExecutionStatus) []*k8sexec.ExecutionStatus {
+	merged := make([]*k8sexec.ExecutionStatus, 0, len(targets))
+	selected := map[k8sexec.Target]bool{}
+	for _, target := range targets {
+		selected[target] = true
+		if status, ok := c.done[target]; ok {
+			merged = append(merged, status)
+		} else if len(statuses) > 0 {
+			merged = append(merged, statuses[0])
+			statuses = statuses[1:]
+		}
+	}
+	for _, target := range c.targets {
+		if !selected[target] {
+			merged = append(merged, c.done[target])
+		}
+	}
+	return merged
+}
+
+// Close closes the checkpoint file.
+func (c *checkpoint) Close() error {
+	return c.file.Close()
+}
+
+// checkpointHeader identifies the sweep of args with stdin.
+func checkpointHeader(args []string, stdin []byte) CheckpointHeader {
+	digest := sha256.Sum256(stdin)
+	return CheckpointHeader{Namespace: namespace, Args: args, Stdin: hex.EncodeToString(digest[:])}
+}
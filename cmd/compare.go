@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	corev1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8sexec/pkg/checks"
+	"k8sexec/pkg/inventory"
+	"k8sexec/pkg/k8sexec"
+	"k8sexec/pkg/schema"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// CompareReport holds differences between replicas of workloads.
+type CompareReport struct {
+	SchemaVersion int              `json:"schemaVersion"`
+	Metadata      *RunMetadata     `json:"metadata,omitempty"`
+	Namespace     string           `json:"Namespace"`
+	Compare       string           `json:"Compare"`
+	Workloads     []*WorkloadDrift `json:"Workloads"`
+	Errors        []string         `json:"Errors,omitempty"`
+}
+
+// WorkloadDrift lists differences between replicas of a container of
+// a workload, it is empty when all replicas are identical.
+type WorkloadDrift struct {
+	Workload  string          `json:"Workload"`
+	Container string          `json:"Container"`
+	Replicas  int             `json:"Replicas"`
+	Drift     []VariableDrift `json:"Drift,omitempty"`
+}
+
+// VariableDrift lists the values of a variable which differs between
+// replicas and the pods having them, an empty value means unset.
+type VariableDrift struct {
+	Name   string              `json:"Name"`
+	Values map[string][]string `json:"Values"`
+}
+
+var (
+	compareIgnore     string
+	compareShowValues bool
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare",
+	Short: "Compares replicas of workloads",
+}
+
+var compareEnvCmd = &cobra.Command{
+	Use:   "env",
+	Short: "Compares environment variables between replicas of each workload",
+	Long: `Compares environment variables of the main process of each container between replicas
+of the same workload and reports variables which differ. Variables set from fields of the pod
+with the downward API are expected to differ and are ignored.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCompareEnv()
+	},
+}
+
+func runCompareEnv() error {
+	if err := validateOptions(); err != nil {
+		return err
+	}
+	ignore, err := regexp.Compile(compareIgnore)
+	if err != nil {
+		return fmt.Errorf("invalid --ignore: %w", err)
+	}
+
+	k8s := newK8SExec()
+	targets, err := loadTargets(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	if dryRun != "" && !simulate {
+		return printTargets(targets)
+	}
+
+	statuses := k8s.ExecAll(context.TODO(), targets, []string{"sh"}, execOptions([]byte(inventory.EnvironmentScript)))
+
+	report := &CompareReport{SchemaVersion: schema.Version, Namespace: namespace, Compare: "env"}
+	environments := map[string]map[string]map[string]string{}
+	var groups []string
+	for i, status := range statuses {
+		if status.Error != "" {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s/%s: %s", status.Pod, status.Container, status.Error))
+			continue
+		}
+
+		group := workloadOf(lookupPod(targets[i]), targets[i]) + "\x00" + status.Container
+		if environments[group] == nil {
+			environments[group] = map[string]map[string]string{}
+			groups = append(groups, group)
+		}
+		environment := inventory.ParseEnvironment(status.Stdout)
+		for name := range downwardVariables(lookupPod(targets[i]), status.Container) {
+			delete(environment, name)
+		}
+		environments[group][status.Pod] = environment
+	}
+
+	sort.Strings(groups)
+	for _, group := range groups {
+		workload, _container, _ := strings.Cut(group, "\x00")
+		report.Workloads = append(report.Workloads, &WorkloadDrift{
+			Workload:  workload,
+			Container: _container,
+			Replicas:  len(environments[group]),
+			Drift:     environmentDrift(environments[group], ignore),
+		})
+	}
+
+	return printReport(report)
+}
+
+// workloadOf returns the controller of pod as kind/name, or the pod itself
+// when it has none or is not known.
+func workloadOf(_pod *corev1.Pod, target k8sexec.Target) string {
+	if _pod != nil {
+		if owner := metaV1.GetControllerOf(_pod); owner != nil {
+			return owner.Kind + "/" + owner.Name
+		}
+	}
+	return "Pod/" + target.Pod
+}
+
+// downwardVariables returns names of variables of a container set from
+// fields or resources of its pod.
+func downwardVariables(_pod *corev1.Pod, name string) map[string]bool {
+	variables := map[string]bool{}
+	if _pod == nil {
+		return variables
+	}
+	for _, _container := range _pod.Spec.Containers {
+		if _container.Name != name {
+			continue
+		}
+		for _, env := range _container.Env {
+			if env.ValueFrom != nil && (env.ValueFrom.FieldRef != nil || env.ValueFrom.ResourceFieldRef != nil) {
+				variables[env.Name] = true
+			}
+		}
+	}
+	return variables
+}
+
+// environmentDrift returns variables whose values differ between pods.
+func environmentDrift(environments map[string]map[string]string, ignore *regexp.Regexp) []VariableDrift {
+	names := map[string]bool{}
+	for _, environment := range environments {
+		for name := range environment {
+			names[name] = true
+		}
+	}
+
+	var drift []VariableDrift
+	for name := range names {
+		if compareIgnore != "" && ignore.MatchString(name) {
+			continue
+		}
+
+		values := map[string][]string{}
+		for _pod, environment := range environments {
+			value := environment[name]
+			if !compareShowValues && value != "" {
+				value = redactedValue(value)
+			}
+			values[value] = append(values[value], _pod)
+		}
+		if len(values) < 2 {
+			continue
+		}
+		for _, pods := range values {
+			sort.Strings(pods)
+		}
+		drift = append(drift, VariableDrift{Name: name, Values: values})
+	}
+
+	sort.Slice(drift, func(i, j int) bool { return drift[i].Name < drift[j].Name })
+	return drift
+}
+
+// redactedValue redacts value keeping a short digest of it, redacted values
+// of different secrets would look the same otherwise.
+func redactedValue(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return fmt.Sprintf("%s sha256:%x", checks.Redact(value), sum[:4])
+}
+
+// WriteText implements output.TextWriter.
+func (r *CompareReport) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "COMPARE: %s\n", r.Compare)
+	fmt.Fprintf(w, "Namespace: %s\n", r.Namespace)
+	for _, workload := range r.Workloads {
+		if workload.Replicas < 2 {
+			continue
+		}
+		fmt.Fprintf(w, "WORKLOAD: %s container %s, %d replicas, %d variables differ\n", workload.Workload, workload.Container, workload.Replicas, len(workload.Drift))
+		for _, variable := range workload.Drift {
+			fmt.Fprintf(w, "  %s\n", variable.Name)
+			values := make([]string, 0, len(variable.Values))
+			for value := range variable.Values {
+				values = append(values, value)
+			}
+			sort.Strings(values)
+			for _, value := range values {
+				if value == "" {
+					fmt.Fprintf(w, "    (unset): %s\n", strings.Join(variable.Values[value], ", "))
+				} else {
+					fmt.Fprintf(w, "    %q: %s\n", value, strings.Join(variable.Values[value], ", "))
+				}
+			}
+		}
+	}
+	for _, err := range r.Errors {
+		fmt.Fprintf(w, "Error: %s\n", err)
+	}
+	return nil
+}
+
+func init() {
+	compareEnvCmd.Flags().StringVar(&compareIgnore, "ignore", "^(HOSTNAME|HOME|PWD|OLDPWD|SHLVL)$", "regular expression of variable names which are expected to differ")
+	compareEnvCmd.Flags().BoolVar(&compareShowValues, "show-values", false, "show values of variables instead of redacting them")
+	compareCmd.AddCommand(compareEnvCmd)
+	cmd.AddCommand(compareCmd)
+}
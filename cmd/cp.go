@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"k8sexec/pkg/k8sexec"
+	"k8sexec/pkg/schema"
+	"strings"
+)
+
+var cpCmd = &cobra.Command{
+	Use:   "copy pod[/container]:path pod[/container]:path",
+	Short: "Copies a file or directory from one container to another",
+	Long: `Copies a file or directory from one container to another, e.g. of a different pod, streaming
+it as a tar archive between both containers without touching local disk. When the destination
+is an existing directory, the source is copied into it. When no container is given, the
+default container of the pod is used. Both containers need sh and tar.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runCopy(args[0], args[1])
+	},
+}
+
+func runCopy(source, destination string) error {
+	if err := validateOptions(); err != nil {
+		return err
+	}
+
+	k8s := newK8SExec()
+	from, fromPath, err := parseCopyLocation(context.TODO(), source)
+	if err != nil {
+		return err
+	}
+	to, toPath, err := parseCopyLocation(context.TODO(), destination)
+	if err != nil {
+		return err
+	}
+
+	if dryRun != "" && !simulate {
+		return printTargets([]k8sexec.Target{from, to})
+	}
+
+	status, err := k8s.Copy(context.TODO(), from, fromPath, to, toPath)
+	if err != nil {
+		return err
+	}
+	if err := printReport(&PipeReport{SchemaVersion: schema.Version, From: status.From, To: status.To, Bytes: status.Bytes}); err != nil {
+		return err
+	}
+	if status.From.ErrorKind != "" || status.To.ErrorKind != "" {
+		return errors.New("copy failed")
+	}
+	return nil
+}
+
+// parseCopyLocation parses pod[/container]:path.
+func parseCopyLocation(ctx context.Context, location string) (k8sexec.Target, string, error) {
+	container, path, ok := strings.Cut(location, ":")
+	if !ok || container == "" || path == "" {
+		return k8sexec.Target{}, "", fmt.Errorf("invalid location %q, must be pod[/container]:path", location)
+	}
+	target, err := resolveContainer(ctx, container)
+	return target, path, err
+}
+
+func init() {
+	cmd.AddCommand(cpCmd)
+}
@@ -0,0 +1,13 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+)
+
+// debugf prints a debug log to stderr with --debug.
+func debugf(format string, args ...any) {
+	if debug {
+		_, _ = fmt.Fprintf(os.Stderr, "DEBUG: "+format+"\n", args...)
+	}
+}
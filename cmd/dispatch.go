@@ -0,0 +1,407 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"hash/fnv"
+	"io"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8sexec/pkg/k8sexec"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	dispatchImage          string
+	dispatchNamespace      string
+	dispatchServiceAccount string
+	dispatchShards         int
+	dispatchTimeout        time.Duration
+	dispatchKeep           bool
+)
+
+// shard selects the part of targets handled by a pod of a dispatched Job,
+// INDEX/COUNT.
+var shard string
+
+// stdinFile is read instead of stdin, dispatched Jobs mount stdin of the
+// command at it.
+var stdinFile string
+
+// auditUserName is the user named in audit entries written by pods of
+// dispatched Jobs, the one who dispatched them.
+var auditUserName string
+
+// dispatchedFlags are options forwarded to kubex in dispatched Jobs when set.
+// Options referring to local files or to the way the report is printed are
+// not, the --guard rules and --audit-log are shipped to the Job separately.
+var dispatchedFlags = []string{
+	"namespace", "pod", "container", "node", "node-selector", "spread",
+	"parallel", "max-per-node", "read-only", "force", "i-know-what-i-am-doing",
+}
+
+// dispatchConfigDir is where stdin of the command and the guard rules are
+// mounted in pods of dispatched Jobs.
+const dispatchConfigDir = "/var/run/kubex"
+
+// dispatchConfigLabel names the ConfigMap of a dispatched Job on the Job.
+const dispatchConfigLabel = "kubex.io/configmap"
+
+// dispatchAuditLog is where pods of dispatched Jobs write audit entries to,
+// they are appended to the local --audit-log.
+const dispatchAuditLog = "/dev/stderr"
+
+var dispatchCmd = &cobra.Command{
+	Use:   "dispatch --image image [flags] [args]",
+	Short: "Runs a command in the selected containers from a Job in the cluster and prints the aggregated report",
+	Long: `Creates a Job whose pods run kubex in the cluster, close to the API server, to execute a
+command in the selected containers, which is much faster than executing it over a slow link
+for very large sweeps. With --shards the selected containers are split among several pods
+running in parallel. Logs of the pods are followed, their reports are merged and printed as
+if the command was executed locally. The command is checked against the --guard rules and
+--read-only before the Job is created, the pods enforce them too and their audit entries are
+appended to the local --audit-log. The image must contain the kubex binary as its entry
+point and the service account needs to exec into pods, see deploy/dispatch.yaml.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runDispatch(cmd, args)
+	},
+}
+
+func runDispatch(c *cobra.Command, args []string) error {
+	if err := validateOptions(); err != nil {
+		return err
+	}
+	switch {
+	case dispatchImage == "":
+		return errors.New("dispatch requires --image")
+	case dispatchShards < 1:
+		return errors.New("--shards must be at least 1")
+	case replayDir != "" || recordDir != "" || dryRun != "":
+		return errors.New("dispatch cannot be used with --record, --replay or --dry-run")
+	}
+
+	var stdinBuf bytes.Buffer
+	if fi, err := os.Stdin.Stat(); err == nil && (fi.Mode()&os.ModeCharDevice) == 0 {
+		if _, err := io.Copy(&stdinBuf, os.Stdin); err != nil {
+			return fmt.Errorf("failed to read stdin: %w", err)
+		}
+	}
+	if stdinBuf.Len() == 0 && len(args) == 0 {
+		return errors.New("no commands provided either by stdin or arguments")
+	}
+
+	// refused commands are not dispatched at all
+	guard, err := commandGuard()
+	if err != nil {
+		return err
+	}
+	if guard != nil {
+		command := args
+		if len(command) == 0 {
+			command = []string{"sh"}
+		}
+		if err := guard.Check(k8sexec.Command{Args: command}, stdinBuf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	var audit io.Writer
+	if auditLog != "" {
+		file, err := os.OpenFile(auditLog, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		audit = file
+	}
+
+	k8sInit()
+	if dispatchNamespace == "" {
+		dispatchNamespace = namespace
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dispatchTimeout)
+	defer cancel()
+
+	job, err := createDispatchJob(ctx, c, args, stdinBuf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "Dispatched Job %s/%s with %d shards\n", job.Namespace, job.Name, dispatchShards)
+	if !dispatchKeep {
+		defer deleteDispatchJob(job)
+	}
+
+	reports, err := followDispatchJob(ctx, job, audit)
+	if err != nil {
+		return err
+	}
+
+	enumStatus := NewEnumerationStatus(stdinBuf.String(), args, namespace)
+	for _, report := range reports {
+		enumStatus.Statuses = append(enumStatus.Statuses, report.Statuses...)
+		enumStatus.Overrides = append(enumStatus.Overrides, report.Overrides...)
+	}
+	sort.SliceStable(enumStatus.Statuses, func(i, j int) bool {
+		a, b := enumStatus.Statuses[i], enumStatus.Statuses[j]
+		return a.Namespace+"/"+a.Pod+"/"+a.Container < b.Namespace+"/"+b.Pod+"/"+b.Container
+	})
+	return printEnumerationStatus(enumStatus)
+}
+
+// createDispatchJob creates an indexed Job with a pod for each shard running
+// kubex with args, and a ConfigMap holding stdin and the --guard rules when
+// given.
+func createDispatchJob(ctx context.Context, c *cobra.Command, args []string, stdin []byte) (*batchv1.Job, error) {
+	kubexArgs := []string{"--kubeconfig=", "--output=json", "--shard=$(JOB_COMPLETION_INDEX)/" + strconv.Itoa(dispatchShards)}
+	for _, name := range dispatchedFlags {
+		if flag := c.Flags().Lookup(name); flag != nil && flag.Changed {
+			kubexArgs = append(kubexArgs, "--"+flag.Name+"="+flag.Value.String())
+		}
+	}
+	if auditLog != "" {
+		kubexArgs = append(kubexArgs, "--audit-log="+dispatchAuditLog, "--audit-user="+auditUser()+" via Job in "+dispatchNamespace)
+	}
+
+	data := map[string][]byte{}
+	if len(stdin) > 0 {
+		data["stdin"] = stdin
+		kubexArgs = append(kubexArgs, "--stdin-file="+dispatchConfigDir+"/stdin")
+	}
+	if guardPath != "" {
+		rules, err := os.ReadFile(guardPath)
+		if err != nil {
+			return nil, err
+		}
+		data["guard"] = rules
+		kubexArgs = append(kubexArgs, "--guard="+dispatchConfigDir+"/guard")
+	}
+
+	labels := map[string]string{storeManagedByLabel: "kubex", storeKindLabel: "dispatch"}
+	podSpec := corev1.PodSpec{
+		ServiceAccountName: dispatchServiceAccount,
+		RestartPolicy:      corev1.RestartPolicyNever,
+		Containers: []corev1.Container{{
+			Name:  "kubex",
+			Image: dispatchImage,
+		}},
+	}
+
+	if len(data) > 0 {
+		configMap, err := clientset.CoreV1().ConfigMaps(dispatchNamespace).Create(ctx, &corev1.ConfigMap{
+			ObjectMeta: metaV1.ObjectMeta{GenerateName: "kubex-dispatch-", Namespace: dispatchNamespace, Labels: labels},
+			BinaryData: data,
+		}, metaV1.CreateOptions{})
+		if err != nil {
+			return nil, err
+		}
+		podSpec.Volumes = []corev1.Volume{{
+			Name:         "config",
+			VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{LocalObjectReference: corev1.LocalObjectReference{Name: configMap.Name}}},
+		}}
+		podSpec.Containers[0].VolumeMounts = []corev1.VolumeMount{{Name: "config", MountPath: dispatchConfigDir}}
+		labels[dispatchConfigLabel] = configMap.Name
+	}
+	podSpec.Containers[0].Args = append(append(kubexArgs, "--"), args...)
+
+	shards := int32(dispatchShards)
+	backoffLimit := int32(0)
+	completionMode := batchv1.IndexedCompletion
+	job := &batchv1.Job{
+		ObjectMeta: metaV1.ObjectMeta{GenerateName: "kubex-dispatch-", Namespace: dispatchNamespace, Labels: labels},
+		Spec: batchv1.JobSpec{
+			Completions:    &shards,
+			Parallelism:    &shards,
+			CompletionMode: &completionMode,
+			BackoffLimit:   &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metaV1.ObjectMeta{Labels: labels},
+				Spec:       podSpec,
+			},
+		},
+	}
+	created, err := clientset.BatchV1().Jobs(dispatchNamespace).Create(ctx, job, metaV1.CreateOptions{})
+	if err != nil && labels[dispatchConfigLabel] != "" {
+		_ = clientset.CoreV1().ConfigMaps(dispatchNamespace).Delete(ctx, labels[dispatchConfigLabel], metaV1.DeleteOptions{})
+	}
+	return created, err
+}
+
+// deleteDispatchJob deletes job with its pods and its ConfigMap. Failures are
+// reported on stderr.
+func deleteDispatchJob(job *batchv1.Job) {
+	ctx := context.Background()
+	propagation := metaV1.DeletePropagationBackground
+	if err := clientset.BatchV1().Jobs(job.Namespace).Delete(ctx, job.Name, metaV1.DeleteOptions{PropagationPolicy: &propagation}); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Failed to delete Job %s/%s: %v\n", job.Namespace, job.Name, err)
+	}
+	if name := job.Labels[dispatchConfigLabel]; name != "" {
+		if err := clientset.CoreV1().ConfigMaps(job.Namespace).Delete(ctx, name, metaV1.DeleteOptions{}); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Failed to delete ConfigMap %s/%s: %v\n", job.Namespace, name, err)
+		}
+	}
+}
+
+// followDispatchJob follows logs of the pods of all shards of job and returns
+// their reports. Messages the pods print on stderr are passed on prefixed
+// with their shard, their audit entries are appended to audit when not nil.
+func followDispatchJob(ctx context.Context, job *batchv1.Job, audit io.Writer) ([]*EnumerationStatus, error) {
+	if audit != nil {
+		audit = &syncWriter{w: audit}
+	}
+	reports := make([]*EnumerationStatus, dispatchShards)
+	errs := make([]error, dispatchShards)
+	var wg sync.WaitGroup
+	for index := 0; index < dispatchShards; index++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reports[index], errs[index] = followDispatchShard(ctx, job, index, audit)
+			if errs[index] == nil {
+				_, _ = fmt.Fprintf(os.Stderr, "[shard %d] finished %d containers\n", index, len(reports[index].Statuses))
+			}
+		}()
+	}
+	wg.Wait()
+	for index, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("shard %d: %w", index, err)
+		}
+	}
+	return reports, nil
+}
+
+// followDispatchShard waits for the pod of a shard to start and decodes the
+// report it prints.
+func followDispatchShard(ctx context.Context, job *batchv1.Job, index int, audit io.Writer) (*EnumerationStatus, error) {
+	selector := fmt.Sprintf("job-name=%s,batch.kubernetes.io/job-completion-index=%d", job.Name, index)
+	var _pod *corev1.Pod
+	for _pod == nil {
+		pods, err := clientset.CoreV1().Pods(job.Namespace).List(ctx, metaV1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return nil, err
+		}
+		for i := range pods.Items {
+			if phase := pods.Items[i].Status.Phase; phase != corev1.PodPending && phase != "" {
+				_pod = &pods.Items[i]
+			}
+		}
+		if _pod == nil {
+			select {
+			case <-ctx.Done():
+				return nil, fmt.Errorf("pod did not start: %w", ctx.Err())
+			case <-time.After(2 * time.Second):
+			}
+		}
+	}
+
+	logs, err := clientset.CoreV1().Pods(job.Namespace).GetLogs(_pod.Name, &corev1.PodLogOptions{Container: "kubex", Follow: true}).Stream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer logs.Close()
+
+	// stdout and stderr are interleaved in logs, the report starts with a
+	// line holding only {
+	var report bytes.Buffer
+	scanner := bufio.NewScanner(logs)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if report.Len() == 0 && line != "{" {
+			if audit != nil && isAuditEntry(line) {
+				_, _ = io.WriteString(audit, line+"\n")
+				continue
+			}
+			_, _ = fmt.Fprintf(os.Stderr, "[shard %d] %s\n", index, line)
+			continue
+		}
+		report.WriteString(line)
+		report.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if report.Len() == 0 {
+		return nil, fmt.Errorf("pod %s printed no report", _pod.Name)
+	}
+
+	var enumStatus EnumerationStatus
+	if err := json.NewDecoder(&report).Decode(&enumStatus); err != nil {
+		return nil, fmt.Errorf("failed to decode the report of pod %s: %w", _pod.Name, err)
+	}
+	return &enumStatus, nil
+}
+
+// isAuditEntry reports whether a line logged by a pod of a dispatched Job is
+// an audit entry.
+func isAuditEntry(line string) bool {
+	var entry k8sexec.AuditEntry
+	return json.Unmarshal([]byte(line), &entry) == nil && !entry.Time.IsZero() && entry.User != ""
+}
+
+// syncWriter serializes writes of shards to a writer.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// parseShard parses a shard selected with --shard.
+func parseShard(value string) (int, int, error) {
+	indexStr, countStr, ok := strings.Cut(value, "/")
+	index, err1 := strconv.Atoi(indexStr)
+	count, err2 := strconv.Atoi(countStr)
+	if !ok || err1 != nil || err2 != nil || count < 1 || index < 0 || index >= count {
+		return 0, 0, fmt.Errorf("invalid --shard value %q, must be INDEX/COUNT", value)
+	}
+	return index, count, nil
+}
+
+// shardTargets keeps only targets of the shard selected with --shard. Pods
+// are assigned to shards by hashes of their names, so that each pod is
+// handled by a single shard.
+func shardTargets(targets []k8sexec.Target) []k8sexec.Target {
+	index, count, err := parseShard(shard)
+	if err != nil {
+		return targets
+	}
+	var selected []k8sexec.Target
+	for _, target := range targets {
+		hash := fnv.New32a()
+		_, _ = hash.Write([]byte(target.Namespace + "/" + target.Pod))
+		if int(hash.Sum32()%uint32(count)) == index {
+			selected = append(selected, target)
+		}
+	}
+	return selected
+}
+
+func init() {
+	dispatchCmd.Flags().StringVar(&dispatchImage, "image", "", "image of the Job, containing the kubex binary as its entry point")
+	dispatchCmd.Flags().StringVar(&dispatchNamespace, "dispatch-namespace", "", "namespace the Job is created in, defaults to --namespace")
+	dispatchCmd.Flags().StringVar(&dispatchServiceAccount, "service-account", "kubex-dispatch", "service account of the Job, allowed to exec into the selected pods")
+	dispatchCmd.Flags().IntVar(&dispatchShards, "shards", 1, "number of pods the selected containers are split among")
+	dispatchCmd.Flags().DurationVar(&dispatchTimeout, "timeout", time.Hour, "time to wait for the Job to finish")
+	dispatchCmd.Flags().BoolVar(&dispatchKeep, "keep", false, "do not delete the Job and its pods when finished")
+	// support for '--'
+	dispatchCmd.Flags().SetInterspersed(false)
+	cmd.AddCommand(dispatchCmd)
+}
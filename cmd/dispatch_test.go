@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8sexec/pkg/k8sexec"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// useDispatchOptions sets options of dispatch for the test, with the --guard
+// rules in a file.
+func useDispatchOptions(t *testing.T, rules string) {
+	t.Helper()
+	previousGuard, previousAudit, previousImage, previousNamespace := guardPath, auditLog, dispatchImage, dispatchNamespace
+	t.Cleanup(func() {
+		guardPath, auditLog, dispatchImage, dispatchNamespace = previousGuard, previousAudit, previousImage, previousNamespace
+	})
+	guardPath = filepath.Join(t.TempDir(), "guard.yaml")
+	if err := os.WriteFile(guardPath, []byte(rules), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	auditLog = filepath.Join(t.TempDir(), "audit.log")
+	dispatchImage = "kubex"
+	dispatchNamespace = "ns"
+}
+
+func TestDispatchRefusesGuardedCommands(t *testing.T) {
+	fakeClientset := useFakeClientset(t)
+	useDispatchOptions(t, "deny:\n  - '\\brm\\b'\n")
+	stdin, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	previousStdin := os.Stdin
+	os.Stdin = stdin
+	defer func() { os.Stdin = previousStdin }()
+
+	err = runDispatch(dispatchCmd, []string{"rm", "-rf", "/data"})
+	if !errors.Is(err, k8sexec.ErrDenied) {
+		t.Errorf("dispatching a denied command = %v, want ErrDenied", err)
+	}
+	jobs, err := fakeClientset.BatchV1().Jobs("ns").List(context.Background(), metaV1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs.Items) != 0 {
+		t.Errorf("Jobs were created for a denied command: %v", jobs.Items)
+	}
+}
+
+func TestDispatchShipsGuardAndAudit(t *testing.T) {
+	fakeClientset := useFakeClientset(t)
+	rules := "allow:\n  - '^id$'\n"
+	useDispatchOptions(t, rules)
+	ctx := context.Background()
+
+	job, err := createDispatchJob(ctx, dispatchCmd, []string{"id"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := job.Spec.Template.Spec.Containers[0].Args
+	for _, want := range []string{"--guard=" + dispatchConfigDir + "/guard", "--audit-log=" + dispatchAuditLog} {
+		if !slices.Contains(args, want) {
+			t.Errorf("args %q do not contain %q", args, want)
+		}
+	}
+	configMap, err := fakeClientset.CoreV1().ConfigMaps("ns").Get(ctx, job.Labels[dispatchConfigLabel], metaV1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(configMap.BinaryData["guard"]); got != rules {
+		t.Errorf("guard rules in the ConfigMap %q, want %q", got, rules)
+	}
+}
+
+func TestIsAuditEntry(t *testing.T) {
+	for line, want := range map[string]bool{
+		`{"Time":"2026-01-02T03:04:05Z","User":"alice via Job in ns","Target":{"Namespace":"ns","Pod":"web-0","Container":"nginx"},"Args":["id"],"ExitCode":0,"Duration":1000}`: true,
+		`Failed to list pods: forbidden`: false,
+		`{"Error": "no such container"}`: false,
+	} {
+		if got := isAuditEntry(line); got != want {
+			t.Errorf("isAuditEntry(%q) = %t, want %t", line, got, want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"k8sexec/pkg/k8sexec"
+	"os"
+)
+
+// attachEvents sets events of pods of statuses, e.g. explaining crash-loops
+// or image pull problems. Failures are reported once on stderr.
+func attachEvents(k8s *k8sexec.K8SExec, statuses []*k8sexec.ExecutionStatus) {
+	pods := map[string][]k8sexec.Event{}
+	var failed bool
+	for _, status := range statuses {
+		key := status.Namespace + "/" + status.Pod
+		events, ok := pods[key]
+		if !ok {
+			var err error
+			events, err = k8s.PodEvents(context.TODO(), k8sexec.Target{Namespace: status.Namespace, Pod: status.Pod})
+			if err != nil {
+				recordIssue("list events", key, err)
+			}
+			if err != nil && !failed {
+				failed = true
+				_, _ = fmt.Fprintf(os.Stderr, "Failed to list events: %v\n", err)
+			}
+			pods[key] = events
+		}
+		status.Events = events
+	}
+}
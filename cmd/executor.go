@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"k8sexec/pkg/k8sexec"
+	"k8sexec/pkg/schema"
+	"os"
+	"os/user"
+	"sync"
+)
+
+// newK8SExec creates the K8SExec commands are executed with. It runs them in
+// the cluster, serves them from recordings when --replay is used or only
+// pretends to run them when --simulate is used, and records them when
+// --record is used. Commands not allowed by the --guard rules, or mutating
+// ones with --read-only, are never executed.
+func newK8SExec() *k8sexec.K8SExec {
+	guard, err := commandGuard()
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	var executor k8sexec.Executor
+	switch {
+	case replayDir != "":
+		executor = k8sexec.NewReplayExecutor(replayDir)
+	case simulate:
+		k8sInit()
+		executor = k8sexec.StubExecutor{}
+	default:
+		k8sInit()
+		spdyExecutor := k8sexec.NewSPDYExecutor(config, clientset)
+		spdyExecutor.PingPeriod = keepalive
+		spdyExecutor.DialTimeout = dialTimeout
+		executor = k8sexec.NewArchExecutor(spdyExecutor, targetArch)
+		if injectToolbox {
+			toolboxExecutor = k8sexec.NewToolboxExecutor(executor, toolboxBinary)
+			executor = toolboxExecutor
+		}
+		if remoteTimeout > 0 {
+			executor = k8sexec.NewTimeoutExecutor(executor, remoteTimeout)
+		}
+		if workdir != "" {
+			var err error
+			if executor, err = k8sexec.NewWorkdirExecutor(executor, workdir); err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+		}
+		// values are not part of commands in recordings, reports and audit
+		// logs
+		if len(envVars) > 0 {
+			var err error
+			if executor, err = k8sexec.NewEnvExecutor(executor, envVars); err != nil {
+				fmt.Println(err.Error())
+				os.Exit(1)
+			}
+		}
+		if resolveCommands {
+			executor = k8sexec.NewResolveExecutor(executor)
+		}
+	}
+
+	if recordDir != "" {
+		executor = k8sexec.NewRecordingExecutor(executor, recordDir)
+	}
+
+	if guard != nil {
+		executor = k8sexec.NewGuardExecutor(executor, guard)
+	}
+
+	// refused commands are audited as well
+	if auditLog != "" {
+		file, err := os.OpenFile(auditLog, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			fmt.Println(err.Error())
+			os.Exit(1)
+		}
+		audit := k8sexec.NewAuditExecutor(executor, file, auditUser())
+		if guard != nil {
+			guard.OnOverride = func(target k8sexec.Target, cmd k8sexec.Command, reason string) {
+				audit.Override(target, cmd, reason)
+				recordOverride(target, cmd, reason)
+			}
+		}
+		executor = audit
+	}
+
+	return k8sexec.NewK8SExecWithExecutor(clientset, executor, namespace)
+}
+
+// commandGuard returns the guard restricting commands by the --guard rules,
+// --read-only, --force and --i-know-what-i-am-doing, or nil when commands are
+// not restricted.
+func commandGuard() (*k8sexec.Guard, error) {
+	var guard *k8sexec.Guard
+	if guardPath != "" {
+		var err error
+		if guard, err = k8sexec.LoadGuard(guardPath); err != nil {
+			return nil, err
+		}
+	}
+	if readOnly {
+		if guard == nil {
+			guard = &k8sexec.Guard{}
+		}
+		guard.ReadOnly = true
+	}
+	if guard != nil {
+		guard.OverrideReadOnly = force
+		guard.OverrideAllow = iKnowWhatIAmDoing
+		guard.OnOverride = recordOverride
+	}
+	return guard, nil
+}
+
+// Override is a guard rule overridden for a command with --force or
+// --i-know-what-i-am-doing.
+type Override struct {
+	Namespace string   `json:"Namespace"`
+	Pod       string   `json:"Pod"`
+	Container string   `json:"Container"`
+	Command   []string `json:"Command"`
+	Reason    string   `json:"Reason"`
+}
+
+var overrides struct {
+	sync.Mutex
+	list []Override
+}
+
+func recordOverride(target k8sexec.Target, cmd k8sexec.Command, reason string) {
+	overrides.Lock()
+	defer overrides.Unlock()
+	overrides.list = append(overrides.list, Override{Namespace: target.Namespace, Pod: target.Pod, Container: target.Container, Command: cmd.Args, Reason: reason})
+}
+
+// recordedOverrides returns overrides recorded so far.
+func recordedOverrides() []Override {
+	overrides.Lock()
+	defer overrides.Unlock()
+	return append([]Override(nil), overrides.list...)
+}
+
+// auditUser identifies who runs kubex in the audit log, pods of dispatched
+// Jobs are given who dispatched them with --audit-user.
+func auditUser() string {
+	if auditUserName != "" {
+		return auditUserName
+	}
+	name := "unknown"
+	if current, err := user.Current(); err == nil {
+		name = current.Username
+	}
+	if config != nil && config.Impersonate.UserName != "" {
+		name += " as " + config.Impersonate.UserName
+	}
+	return name
+}
+
+// execOptions returns options commands are executed in multiple containers
+// with, limited by --parallel and --max-per-node.
+func execOptions(stdin []byte) k8sexec.ExecOptions {
+	return k8sexec.ExecOptions{
+		Stdin:      stdin,
+		Parallel:   parallel,
+		MaxPerNode: maxPerNode,
+		Node:       targetNode,
+		Image:      targetImageID,
+		Retries:    retries,
+	}
+}
+
+// loadTargets returns the containers a command is executed in.
+func loadTargets(ctx context.Context) ([]k8sexec.Target, error) {
+	resolver, err := podResolver()
+	if err != nil {
+		return nil, err
+	}
+	targets, err := resolver.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if spread == spreadNode {
+		targets = spreadNodeTargets(targets)
+	}
+
+	if uniqueImages {
+		targets = uniqueImageTargets(targets)
+	}
+
+	if maxPods > 0 || samplePods != "" {
+		targets = sampleTargets(targets)
+	}
+
+	if shard != "" {
+		targets = shardTargets(targets)
+	}
+
+	if recordDir != "" {
+		if err := k8sexec.SaveTargets(recordDir, targets); err != nil {
+			return nil, err
+		}
+	}
+
+	return targets, nil
+}
+
+// TargetsReport holds targets selected for a dry run.
+type TargetsReport struct {
+	SchemaVersion int              `json:"schemaVersion"`
+	Metadata      *RunMetadata     `json:"metadata,omitempty"`
+	Targets       []k8sexec.Target `json:"Targets"`
+}
+
+// printTargets prints targets selected for a dry run.
+func printTargets(targets []k8sexec.Target) error {
+	return printReport(&TargetsReport{SchemaVersion: schema.Version, Targets: targets})
+}
+
+// WriteText implements output.TextWriter.
+func (r *TargetsReport) WriteText(w io.Writer) error {
+	for _, target := range r.Targets {
+		fmt.Fprintf(w, "%s/%s/%s\n", target.Namespace, target.Pod, target.Container)
+	}
+	return nil
+}
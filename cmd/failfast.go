@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"context"
+	"k8sexec/pkg/k8sexec"
+	"sync"
+)
+
+// Failures aborting a sweep with --fail-fast.
+const (
+	// failFastAny aborts on errors and non-zero exit codes.
+	failFastAny = "any"
+	// failFastError aborts on errors executing the command only.
+	failFastError = "error"
+)
+
+var failFast string
+
+// failFastWatch cancels a sweep on the first failure selected by
+// --fail-fast.
+type failFastWatch struct {
+	cancel context.CancelFunc
+	once   sync.Once
+	// first is the status of the first failure, read once the sweep
+	// finished.
+	first *k8sexec.ExecutionStatus
+}
+
+// watchFailures returns a context canceled by the returned watch on the first
+// failure, a nil watch without --fail-fast.
+func watchFailures(ctx context.Context) (context.Context, *failFastWatch) {
+	if failFast == "" {
+		return ctx, nil
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return ctx, &failFastWatch{cancel: cancel}
+}
+
+// Record cancels the sweep when status is a failure. Containers canceled by
+// the sweep being canceled are not failures.
+func (w *failFastWatch) Record(status *k8sexec.ExecutionStatus) {
+	switch status.ErrorKind {
+	case "", k8sexec.KindCanceled:
+		return
+	case k8sexec.KindNonZeroExit:
+		if failFast == failFastError {
+			return
+		}
+	}
+	w.once.Do(func() {
+		w.first = status
+		w.cancel()
+	})
+}
+
+// abortedRun returns the marker of a sweep aborted by the first failure, nil
+// when none occurred.
+func (w *failFastWatch) abortedRun(statuses []*k8sexec.ExecutionStatus) *RunAborted {
+	if w == nil || w.first == nil {
+		return nil
+	}
+	return countAborted(&RunAborted{Failed: w.first.Namespace + "/" + w.first.Pod + "/" + w.first.Container}, statuses)
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"k8sexec/pkg/k8sexec"
+	"sort"
+	"strconv"
+)
+
+// Keys results are aggregated by with --aggregate.
+const (
+	aggregateExitCode = "exit-code"
+	aggregateImage    = "image"
+	aggregateWorkload = "workload"
+)
+
+// Aggregation counts results sharing a key, e.g. an exit code.
+type Aggregation struct {
+	By     string              `json:"By"`
+	Groups []*AggregationGroup `json:"Groups"`
+}
+
+// AggregationGroup counts containers whose results share Key.
+type AggregationGroup struct {
+	Key string `json:"Key"`
+	// Description explains exit codes.
+	Description string `json:"Description,omitempty"`
+	Containers  int    `json:"Containers"`
+	Failed      int    `json:"Failed"`
+}
+
+// ImageGroup holds results of containers running the same image build.
+type ImageGroup struct {
+	Image   string `json:"Image"`
+	ImageID string `json:"ImageID"`
+	// Containers are the containers of the group as pod/container.
+	Containers []string `json:"Containers"`
+	Failed     int      `json:"Failed"`
+
+	Statuses []*k8sexec.ExecutionStatus `json:"-"`
+}
+
+// groupByImages groups statuses by image references and image IDs of their
+// containers, in the order of first occurrence.
+func groupByImages(statuses []*k8sexec.ExecutionStatus) []*ImageGroup {
+	index := map[string]int{}
+	var groups []*ImageGroup
+	for _, status := range statuses {
+		key := status.Image + "\x00" + status.ImageID
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, &ImageGroup{Image: status.Image, ImageID: status.ImageID})
+		}
+
+		group := groups[i]
+		group.Containers = append(group.Containers, status.Pod+"/"+status.Container)
+		group.Statuses = append(group.Statuses, status)
+		if status.ErrorKind != "" {
+			group.Failed++
+		}
+	}
+	return groups
+}
+
+// aggregate counts statuses by the key by, largest groups first.
+func aggregate(statuses []*k8sexec.ExecutionStatus, by string) *Aggregation {
+	aggregation := &Aggregation{By: by}
+	if by == aggregateWorkload {
+		targets := make([]k8sexec.Target, len(statuses))
+		for i, status := range statuses {
+			targets[i] = k8sexec.Target{Namespace: status.Namespace, Pod: status.Pod, Container: status.Container}
+		}
+		resolveWorkloads(context.TODO(), targets)
+	}
+	index := map[string]*AggregationGroup{}
+	for _, status := range statuses {
+		var group AggregationGroup
+		switch by {
+		case aggregateExitCode:
+			group.Key, group.Description = strconv.Itoa(status.RetCode), k8sexec.GetExitCodeDescription(status.RetCode)
+		case aggregateImage:
+			group.Key = status.Image
+		case aggregateWorkload:
+			target := k8sexec.Target{Namespace: status.Namespace, Pod: status.Pod, Container: status.Container}
+			group.Key = Workload{Namespace: status.Namespace, Kind: "Pod", Name: status.Pod}.String()
+			if _pod := lookupPod(target); _pod != nil {
+				group.Key = podWorkload(context.TODO(), _pod).String()
+			}
+		}
+		if group.Key == "" {
+			group.Key = "unknown"
+		}
+
+		existing, ok := index[group.Key]
+		if !ok {
+			existing = &group
+			index[group.Key] = existing
+			aggregation.Groups = append(aggregation.Groups, existing)
+		}
+		existing.Containers++
+		if status.ErrorKind != "" {
+			existing.Failed++
+		}
+	}
+	sort.SliceStable(aggregation.Groups, func(i, j int) bool {
+		return aggregation.Groups[i].Containers > aggregation.Groups[j].Containers
+	})
+	return aggregation
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"k8sexec/pkg/k8sexec"
+)
+
+// cacheByImage executes commands in only one container of each image digest
+// and reuses its result for the others.
+var cacheByImage bool
+
+// execAllByImage executes cmd in targets like ExecOnTargets. With --cache-by-image
+// it is executed in the first container of each image digest only, other
+// containers of the image report a copy of its result referencing it in
+// CachedFrom. Containers whose first container of the image failed to
+// execute the command at all execute it themselves.
+func execAllByImage(ctx context.Context, k8s *k8sexec.K8SExec, targets []k8sexec.Target, cmd []string, opts k8sexec.ExecOptions) []*k8sexec.ExecutionStatus {
+	if !cacheByImage {
+		return k8s.ExecOnTargets(ctx, targets, cmd, opts)
+	}
+
+	// source holds the index of the executed target each target reuses
+	first := map[string]int{}
+	source := make([]int, len(targets))
+	var executed []k8sexec.Target
+	for i, target := range targets {
+		_, imageID := targetImageID(target)
+		j, ok := first[imageID]
+		if !ok || imageID == "" {
+			j = len(executed)
+			executed = append(executed, target)
+			if imageID != "" {
+				first[imageID] = j
+			}
+		}
+		source[i] = j
+	}
+	executedStatuses := k8s.ExecAll(ctx, executed, cmd, opts)
+
+	statuses := make([]*k8sexec.ExecutionStatus, len(targets))
+	var retried []int
+	for i, target := range targets {
+		status := executedStatuses[source[i]]
+		switch {
+		case executed[source[i]] == target:
+			statuses[i] = status
+		case status.ErrorKind != "" && status.ErrorKind != k8sexec.KindNonZeroExit:
+			retried = append(retried, i)
+		default:
+			reused := *status
+			reused.Namespace, reused.Pod, reused.Container = cmp.Or(target.Namespace, status.Namespace), target.Pod, target.Container
+			reused.Node = targetNode(target)
+			reused.Image, reused.ImageID = targetImageID(target)
+			reused.CachedFrom = status.Namespace + "/" + status.Pod + "/" + status.Container
+			statuses[i] = &reused
+			if opts.Done != nil {
+				opts.Done(&reused)
+			}
+		}
+	}
+
+	if len(retried) > 0 {
+		retriedTargets := make([]k8sexec.Target, len(retried))
+		for j, i := range retried {
+			retriedTargets[j] = targets[i]
+		}
+		for j, status := range k8s.ExecAll(ctx, retriedTargets, cmd, opts) {
+			statuses[retried[j]] = status
+		}
+	}
+	return statuses
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"k8sexec/pkg/checks"
+	"k8sexec/pkg/imagesig"
+	"k8sexec/pkg/k8sexec"
+)
+
+var (
+	verifyImages bool
+	verifier     imagesig.Verifier
+)
+
+// verifyImageSignatures verifies signatures of each unique image of targets
+// and reports images failing verification as findings of the first
+// container running them.
+func verifyImageSignatures(targets []k8sexec.Target) []checks.Finding {
+	var findings []checks.Finding
+	seen := map[string]bool{}
+	for _, target := range targets {
+		ref := imagesig.Reference(targetImageID(target))
+		if ref == "" || seen[ref] {
+			continue
+		}
+		seen[ref] = true
+
+		err := verifier.Verify(context.TODO(), ref)
+		if err == nil {
+			continue
+		}
+		finding := checks.Finding{
+			Check:     "image-signature",
+			ID:        "unsigned-image",
+			Severity:  checks.SeverityHigh,
+			Namespace: target.Namespace,
+			Pod:       target.Pod,
+			Container: target.Container,
+			Title:     "image " + ref + " has no valid signature",
+			Detail:    err.Error(),
+		}
+		if !errors.Is(err, imagesig.ErrUnsigned) {
+			finding.ID = "image-verification-failed"
+			finding.Severity = checks.SeverityInfo
+			finding.Title = fmt.Sprintf("signature of image %s could not be verified", ref)
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
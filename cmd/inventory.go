@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"k8sexec/pkg/inventory"
+	"k8sexec/pkg/k8sexec"
+	"k8sexec/pkg/schema"
+	"os"
+	"strings"
+)
+
+// InventoryReport holds an inventory collected in all selected containers.
+type InventoryReport struct {
+	SchemaVersion int                   `json:"schemaVersion"`
+	Metadata      *RunMetadata          `json:"metadata,omitempty"`
+	Namespace     string                `json:"Namespace"`
+	Inventory     string                `json:"Inventory"`
+	Containers    []*ContainerInventory `json:"Containers"`
+}
+
+// ContainerInventory is an inventory of a single container.
+type ContainerInventory struct {
+	Namespace string `json:"Namespace"`
+	Pod       string `json:"Pod"`
+	Container string `json:"Container"`
+	Image     string `json:"Image,omitempty"`
+	// CachedFrom is the namespace/pod/container of the same image whose
+	// inventory is reported with --cache-by-image.
+	CachedFrom  string                 `json:"CachedFrom,omitempty"`
+	Packages    *inventory.Packages    `json:"Packages,omitempty"`
+	Processes   []inventory.Process    `json:"Processes,omitempty"`
+	Fingerprint *inventory.Fingerprint `json:"Fingerprint,omitempty"`
+	// Unexpected are processes not expected to run in a container.
+	Unexpected []inventory.UnexpectedProcess `json:"Unexpected,omitempty"`
+	// Vulnerabilities of packages, only set with --vuln-db.
+	Vulnerabilities []inventory.Vulnerability `json:"Vulnerabilities,omitempty"`
+	Error           string                    `json:"Error,omitempty"`
+}
+
+// vulnDBPath is the offline OSV database packages are correlated with.
+var vulnDBPath string
+
+var inventoryCmd = &cobra.Command{
+	Use:   "inventory",
+	Short: "Collects software inventories of all selected containers",
+}
+
+var inventoryPackagesCmd = &cobra.Command{
+	Use:   "packages",
+	Short: "Collects packages installed by apk, dpkg, rpm or pacman",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var vulnDB *inventory.VulnDB
+		if vulnDBPath != "" {
+			var err error
+			if vulnDB, err = inventory.LoadVulnDB(vulnDBPath); err != nil {
+				return err
+			}
+		}
+
+		report, err := collectInventory("packages", inventory.PackagesScript, false, func(item *ContainerInventory, status *k8sexec.ExecutionStatus) {
+			item.Packages = inventory.ParsePackages(status.Stdout)
+			if vulnDB != nil {
+				item.Vulnerabilities = vulnDB.Match(item.Packages)
+			}
+		})
+		if err != nil || report == nil {
+			return err
+		}
+		return printReport(report)
+	},
+}
+
+var inventoryProcessesCmd = &cobra.Command{
+	Use:   "processes",
+	Short: "Collects running processes and flags unexpected daemons",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		report, err := collectInventory("processes", inventory.ProcessesScript, false, func(item *ContainerInventory, status *k8sexec.ExecutionStatus) {
+			item.Processes = inventory.ParseProcesses(status.Stdout)
+			item.Unexpected = inventory.UnexpectedProcesses(item.Processes)
+		})
+		if err != nil || report == nil {
+			return err
+		}
+		return printReport(report)
+	},
+}
+
+var inventoryFingerprintCmd = &cobra.Command{
+	Use:   "fingerprint",
+	Short: "Collects the OS, kernel, C library and container runtime once per image",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		report, err := collectInventory("fingerprint", inventory.FingerprintScript, true, parseFingerprint)
+		if err != nil || report == nil {
+			return err
+		}
+		return printReport(report)
+	},
+}
+
+func parseFingerprint(item *ContainerInventory, status *k8sexec.ExecutionStatus) {
+	item.Fingerprint = inventory.ParseFingerprint(status.Stdout)
+}
+
+// collectInventory executes script in all selected containers, or in one
+// container of each image when perImage is set, and lets parse fill the
+// inventory of each container in which it succeeded. It returns a nil report
+// for dry runs.
+func collectInventory(name string, script string, perImage bool, parse func(*ContainerInventory, *k8sexec.ExecutionStatus)) (*InventoryReport, error) {
+	if err := validateOptions(); err != nil {
+		return nil, err
+	}
+
+	k8s := newK8SExec()
+	targets, err := loadTargets(context.TODO())
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	if perImage {
+		targets = uniqueImageTargets(targets)
+	}
+
+	if dryRun != "" && !simulate {
+		return nil, printTargets(targets)
+	}
+
+	report := &InventoryReport{SchemaVersion: schema.Version, Namespace: namespace, Inventory: name}
+	report.Containers = containerInventories(k8s, targets, script, parse)
+	return report, nil
+}
+
+// containerInventories executes script in targets and lets parse fill the
+// inventory of each container in which it succeeded.
+func containerInventories(k8s *k8sexec.K8SExec, targets []k8sexec.Target, script string, parse func(*ContainerInventory, *k8sexec.ExecutionStatus)) []*ContainerInventory {
+	statuses := execAllByImage(context.TODO(), k8s, targets, []string{"sh"}, execOptions([]byte(script)))
+
+	items := make([]*ContainerInventory, 0, len(statuses))
+	for i, status := range statuses {
+		item := &ContainerInventory{Namespace: status.Namespace, Pod: status.Pod, Container: status.Container, Image: targetImage(targets[i]), CachedFrom: status.CachedFrom}
+
+		switch {
+		case status.Error != "":
+			item.Error = status.Error
+		case status.RetCode != 0:
+			item.Error = fmt.Sprintf("exit code %d: %s", status.RetCode, status.Stderr)
+		default:
+			parse(item, status)
+		}
+		items = append(items, item)
+	}
+
+	return items
+}
+
+// WriteText implements output.TextWriter.
+func (r *InventoryReport) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "INVENTORY: %s\n", r.Inventory)
+	fmt.Fprintf(w, "Namespace: %s\n", r.Namespace)
+	for _, item := range r.Containers {
+		fmt.Fprintf(w, "CONTAINER: %s/%s %s\n", item.Pod, item.Container, item.Image)
+		if item.CachedFrom != "" {
+			fmt.Fprintf(w, "Cached from: %s\n", item.CachedFrom)
+		}
+		if item.Error != "" {
+			fmt.Fprintf(w, "Error: %s\n\n", item.Error)
+			continue
+		}
+		if item.Packages != nil {
+			if item.Packages.Manager == "" {
+				fmt.Fprintln(w, "No package manager detected")
+			} else {
+				fmt.Fprintf(w, "Package manager: %s, %d packages\n", item.Packages.Manager, len(item.Packages.Packages))
+			}
+			for _, p := range item.Packages.Packages {
+				fmt.Fprintf(w, "  %s %s %s\n", p.Name, p.Version, p.Arch)
+			}
+		}
+		if item.Fingerprint != nil {
+			printFingerprint(w, item.Fingerprint)
+		}
+		if item.Processes != nil {
+			fmt.Fprintf(w, "Processes: %d\n", len(item.Processes))
+			for _, p := range item.Processes {
+				fmt.Fprintf(w, "  %6d %6d %-8s %s\n", p.PID, p.PPID, p.User, p.Cmdline)
+			}
+			for _, p := range item.Unexpected {
+				fmt.Fprintf(w, "Unexpected process %d %s: %s\n", p.PID, p.Name, p.Reason)
+			}
+		}
+		if vulnDBPath != "" {
+			fmt.Fprintf(w, "Known vulnerabilities: %d\n", len(item.Vulnerabilities))
+			for _, v := range item.Vulnerabilities {
+				fmt.Fprintf(w, "  %s %s %s fixed in %q [%s] %s\n", v.ID, v.Package, v.Version, v.Fixed, v.Severity, v.Summary)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+func printFingerprint(w io.Writer, fingerprint *inventory.Fingerprint) {
+	if fingerprint.OSName != "" {
+		fmt.Fprintf(w, "OS: %s\n", fingerprint.OSName)
+	}
+	fmt.Fprintf(w, "Kernel: %s %s\n", fingerprint.Kernel, fingerprint.Arch)
+	if fingerprint.Libc != "" {
+		fmt.Fprintf(w, "C library: %s %s\n", fingerprint.Libc, fingerprint.LibcVersion)
+	}
+	if len(fingerprint.Runtime) > 0 {
+		fmt.Fprintf(w, "Runtime: %s\n", strings.Join(fingerprint.Runtime, ", "))
+	}
+	if fingerprint.Cgroup != "" {
+		fmt.Fprintf(w, "Cgroup: %s\n", fingerprint.Cgroup)
+	}
+}
+
+func init() {
+	inventoryPackagesCmd.Flags().StringVar(&vulnDBPath, "vuln-db", "", "offline OSV database (directory or zip archive of OSV JSON files) to correlate packages with")
+	inventoryCmd.AddCommand(inventoryPackagesCmd)
+	inventoryCmd.AddCommand(inventoryProcessesCmd)
+	inventoryCmd.AddCommand(inventoryFingerprintCmd)
+	cmd.AddCommand(inventoryCmd)
+}
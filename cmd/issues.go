@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// strict fails runs which encountered issues.
+var strict bool
+
+// ErrIssues is returned with --strict by runs which encountered issues.
+var ErrIssues = errors.New("issues encountered")
+
+// Issue is a step of a run which failed without failing the run, e.g. a
+// listing of pods or a query of the metrics API.
+type Issue struct {
+	Step string `json:"Step"`
+	// Subject is what the step failed for, e.g. a namespace or pod.
+	Subject string `json:"Subject,omitempty"`
+	Error   string `json:"Error"`
+}
+
+var issues struct {
+	sync.Mutex
+	list []Issue
+}
+
+func recordIssue(step, subject string, err error) {
+	issues.Lock()
+	defer issues.Unlock()
+	issues.list = append(issues.list, Issue{Step: step, Subject: subject, Error: err.Error()})
+}
+
+// recordedIssues returns issues encountered so far.
+func recordedIssues() []Issue {
+	issues.Lock()
+	defer issues.Unlock()
+	return append([]Issue(nil), issues.list...)
+}
+
+// printIssues prints the issues section of text reports.
+func printIssues(w io.Writer, list []Issue) {
+	if len(list) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "ISSUES ENCOUNTERED:")
+	for _, issue := range list {
+		if issue.Subject != "" {
+			fmt.Fprintf(w, "  %s %s: %s\n", issue.Step, issue.Subject, issue.Error)
+		} else {
+			fmt.Fprintf(w, "  %s: %s\n", issue.Step, issue.Error)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// checkIssues returns ErrIssues with --strict when issues were encountered.
+func checkIssues() error {
+	if n := len(recordedIssues()); strict && n > 0 {
+		_, _ = fmt.Fprintf(os.Stderr, "Error: %d issues encountered\n", n)
+		return ErrIssues
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"os"
+	"strings"
+)
+
+// clientConfig is the kubeconfig loaded by k8sInit.
+var clientConfig clientcmd.ClientConfig
+
+// loadClientConfig loads the kubeconfig of --kubeconfig or, without it, the
+// files listed in KUBECONFIG merged as kubectl does, or ~/.kube/config. The
+// service account of the pod is used in a cluster when there is none.
+func loadClientConfig() clientcmd.ClientConfig {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{})
+
+	if debug {
+		// only files that exist are loaded
+		var files []string
+		for _, file := range rules.GetLoadingPrecedence() {
+			if _, err := os.Stat(file); err == nil {
+				files = append(files, file)
+			}
+		}
+		if len(files) == 0 {
+			files = []string{"none"}
+		}
+		debugf("kubeconfig files: %s, context: %s", strings.Join(files, ", "), currentContext(loader))
+	}
+	return loader
+}
+
+// currentContext returns the context of clientConfig, "in-cluster" when the
+// service account of the pod is used.
+func currentContext(clientConfig clientcmd.ClientConfig) string {
+	if inCluster(clientConfig) {
+		return "in-cluster"
+	}
+	if rawConfig, err := clientConfig.RawConfig(); err == nil {
+		return rawConfig.CurrentContext
+	}
+	return ""
+}
+
+// inCluster reports whether clientConfig falls back to the service account
+// of the pod, as it does in a cluster when the kubeconfig does not configure
+// one.
+func inCluster(clientConfig clientcmd.ClientConfig) bool {
+	if _, err := rest.InClusterConfig(); err != nil {
+		return false
+	}
+	rawConfig, err := clientConfig.RawConfig()
+	if err != nil {
+		return false
+	}
+	merged, err := clientcmd.NewDefaultClientConfig(rawConfig, &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return clientcmd.IsEmptyConfig(err)
+	}
+	rules, ok := clientConfig.ConfigAccess().(*clientcmd.ClientConfigLoadingRules)
+	return ok && rules.IsDefaultConfig(merged)
+}
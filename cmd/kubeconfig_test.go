@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCurrentContext(t *testing.T) {
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	previous := kubeconfig
+	t.Cleanup(func() { kubeconfig = previous })
+
+	kubeconfig = filepath.Join(t.TempDir(), "config")
+	data := []byte(`apiVersion: v1
+kind: Config
+clusters:
+- name: cluster
+  cluster:
+    server: https://cluster
+users:
+- name: user
+  user:
+    token: secret
+contexts:
+- name: admin@cluster
+  context:
+    cluster: cluster
+    user: user
+current-context: admin@cluster
+`)
+	if err := os.WriteFile(kubeconfig, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := currentContext(loadClientConfig()); got != "admin@cluster" {
+		t.Errorf("currentContext = %q, want admin@cluster", got)
+	}
+
+	// outside of a cluster an empty kubeconfig is not reported as in-cluster
+	if err := os.WriteFile(kubeconfig, []byte("apiVersion: v1\nkind: Config\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := currentContext(loadClientConfig()); got != "" {
+		t.Errorf("currentContext of an empty kubeconfig = %q, want none", got)
+	}
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"k8sexec/pkg/k8sexec"
+	"k8sexec/pkg/schema"
+	"text/tabwriter"
+)
+
+// ListReport lists pods, containers or workloads selected for commands.
+type ListReport struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	Metadata      *RunMetadata `json:"metadata,omitempty"`
+	Namespace     string       `json:"Namespace"`
+	// Kind is pods, containers or workloads.
+	Kind       string           `json:"Kind"`
+	Pods       []*PodEntry      `json:"Pods,omitempty"`
+	Containers []*ContainerItem `json:"Containers,omitempty"`
+	Workloads  []*WorkloadEntry `json:"Workloads,omitempty"`
+}
+
+// PodEntry is a selected pod.
+type PodEntry struct {
+	Namespace  string   `json:"Namespace"`
+	Name       string   `json:"Name"`
+	Node       string   `json:"Node,omitempty"`
+	IP         string   `json:"IP,omitempty"`
+	Workload   string   `json:"Workload,omitempty"`
+	Containers []string `json:"Containers"`
+}
+
+// ContainerItem is a selected container.
+type ContainerItem struct {
+	Namespace string `json:"Namespace"`
+	Pod       string `json:"Pod"`
+	Container string `json:"Container"`
+	Image     string `json:"Image,omitempty"`
+	Node      string `json:"Node,omitempty"`
+}
+
+// WorkloadEntry is a workload with selected pods.
+type WorkloadEntry struct {
+	Workload
+	Pods       int `json:"Pods"`
+	Containers int `json:"Containers"`
+}
+
+var listCmd = &cobra.Command{
+	Use:   "list pods|containers|workloads",
+	Short: "Lists pods, containers or workloads selected by the selector options",
+	Long: `Lists running pods, their containers or the workloads controlling them, e.g. Deployments,
+selected by the same options as containers commands are executed in, so that targets of
+sweeps can be discovered and scripted with kubex alone.`,
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: []string{"pods", "containers", "workloads"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runList(args[0])
+	},
+}
+
+func runList(kind string) error {
+	if err := validateOptions(); err != nil {
+		return err
+	}
+	if replayDir == "" {
+		k8sInit()
+	}
+	targets, err := loadTargets(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	report := &ListReport{SchemaVersion: schema.Version, Namespace: namespace, Kind: kind}
+	switch kind {
+	case "pods":
+		report.Pods = listPods(targets)
+	case "containers":
+		for _, target := range targets {
+			report.Containers = append(report.Containers, &ContainerItem{
+				Namespace: target.Namespace,
+				Pod:       target.Pod,
+				Container: target.Container,
+				Image:     targetImage(target),
+				Node:      targetNode(target),
+			})
+		}
+	case "workloads":
+		report.Workloads = listWorkloads(targets)
+	}
+	return printReport(report)
+}
+
+func listPods(targets []k8sexec.Target) []*PodEntry {
+	resolveWorkloads(context.TODO(), targets)
+	var pods []*PodEntry
+	index := map[string]*PodEntry{}
+	for _, target := range targets {
+		key := target.Namespace + "/" + target.Pod
+		entry, ok := index[key]
+		if !ok {
+			entry = &PodEntry{Namespace: target.Namespace, Name: target.Pod}
+			if _pod := lookupPod(target); _pod != nil {
+				entry.Node, entry.IP = _pod.Spec.NodeName, _pod.Status.PodIP
+				entry.Workload = podWorkload(context.TODO(), _pod).String()
+			}
+			index[key] = entry
+			pods = append(pods, entry)
+		}
+		entry.Containers = append(entry.Containers, target.Container)
+	}
+	return pods
+}
+
+func listWorkloads(targets []k8sexec.Target) []*WorkloadEntry {
+	resolveWorkloads(context.TODO(), targets)
+	var workloads []*WorkloadEntry
+	index := map[Workload]*WorkloadEntry{}
+	pods := map[string]bool{}
+	for _, target := range targets {
+		workload := Workload{Namespace: target.Namespace, Kind: "Pod", Name: target.Pod}
+		if _pod := lookupPod(target); _pod != nil {
+			workload = podWorkload(context.TODO(), _pod)
+		}
+		entry, ok := index[workload]
+		if !ok {
+			entry = &WorkloadEntry{Workload: workload}
+			index[workload] = entry
+			workloads = append(workloads, entry)
+		}
+		entry.Containers++
+		if key := target.Namespace + "/" + target.Pod; !pods[key] {
+			pods[key] = true
+			entry.Pods++
+		}
+	}
+	return workloads
+}
+
+// WriteText implements output.TextWriter.
+func (r *ListReport) WriteText(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	switch r.Kind {
+	case "pods":
+		fmt.Fprintln(table, "NAMESPACE\tPOD\tNODE\tIP\tWORKLOAD\tCONTAINERS")
+		for _, entry := range r.Pods {
+			fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%d\n", entry.Namespace, entry.Name, entry.Node, entry.IP, entry.Workload, len(entry.Containers))
+		}
+	case "containers":
+		fmt.Fprintln(table, "NAMESPACE\tPOD\tCONTAINER\tIMAGE\tNODE")
+		for _, entry := range r.Containers {
+			fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\n", entry.Namespace, entry.Pod, entry.Container, entry.Image, entry.Node)
+		}
+	case "workloads":
+		fmt.Fprintln(table, "NAMESPACE\tKIND\tNAME\tPODS\tCONTAINERS")
+		for _, entry := range r.Workloads {
+			fmt.Fprintf(table, "%s\t%s\t%s\t%d\t%d\n", entry.Namespace, entry.Kind, entry.Name, entry.Pods, entry.Containers)
+		}
+	}
+	return table.Flush()
+}
+
+func init() {
+	cmd.AddCommand(listCmd)
+}
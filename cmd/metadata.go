@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"io"
+	authenticationV1 "k8s.io/api/authentication/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// RunMetadata identifies the run a report was produced by, for traceability
+// of collected evidence.
+type RunMetadata struct {
+	RunID      string    `json:"RunID"`
+	StartedAt  time.Time `json:"StartedAt"`
+	FinishedAt time.Time `json:"FinishedAt"`
+	Version    string    `json:"Version"`
+	// Command is the invoked command, e.g. "kubex list".
+	Command string `json:"Command"`
+	// ServerVersion, Context and User describe the cluster and who ran
+	// kubex, they are empty when not known, e.g. with --replay.
+	ServerVersion string   `json:"ServerVersion,omitempty"`
+	Context       string   `json:"Context,omitempty"`
+	User          string   `json:"User,omitempty"`
+	Groups        []string `json:"Groups,omitempty"`
+	// Flags are flags set on the command line, values of --env are
+	// omitted.
+	Flags map[string]string `json:"Flags,omitempty"`
+}
+
+// runMetadata is completed when reports are printed.
+var runMetadata = &RunMetadata{}
+
+// startRun starts the metadata of the run of c.
+func startRun(c *cobra.Command) {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	runMetadata.RunID = hex.EncodeToString(id)
+	runMetadata.StartedAt = time.Now().UTC()
+	runMetadata.Version = appVersion
+	runMetadata.Command = c.CommandPath()
+	c.Flags().Visit(func(flag *pflag.Flag) {
+		if runMetadata.Flags == nil {
+			runMetadata.Flags = map[string]string{}
+		}
+		value := flag.Value.String()
+		if flag.Name == "env" {
+			var names []string
+			for _, env := range envVars {
+				name, _, _ := strings.Cut(env, "=")
+				names = append(names, name)
+			}
+			value = strings.Join(names, ",")
+		}
+		runMetadata.Flags[flag.Name] = value
+	})
+}
+
+// finishRun completes the metadata with the finish time and, once, with
+// details of the cluster.
+func finishRun() *RunMetadata {
+	runMetadata.FinishedAt = time.Now().UTC()
+	if clientset == nil || runMetadata.Context != "" {
+		return runMetadata
+	}
+
+	runMetadata.Context = currentContext(clientConfig)
+	if info, err := clientset.Discovery().ServerVersion(); err == nil {
+		runMetadata.ServerVersion = info.GitVersion
+	}
+	review, err := clientset.AuthenticationV1().SelfSubjectReviews().Create(context.TODO(), &authenticationV1.SelfSubjectReview{}, metaV1.CreateOptions{})
+	if err == nil {
+		runMetadata.User = review.Status.UserInfo.Username
+		runMetadata.Groups = review.Status.UserInfo.Groups
+	}
+	return runMetadata
+}
+
+// attachRunMetadata sets the Metadata field of report and returns it, nil
+// for reports without one.
+func attachRunMetadata(report any) *RunMetadata {
+	value := reflect.ValueOf(report)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	field := value.Elem().FieldByName("Metadata")
+	if !field.IsValid() || field.Type() != reflect.TypeOf(runMetadata) {
+		return nil
+	}
+	metadata := finishRun()
+	field.Set(reflect.ValueOf(metadata))
+	return metadata
+}
+
+// writeRunMetadata writes the metadata header of text reports.
+func writeRunMetadata(w io.Writer, metadata *RunMetadata) {
+	fmt.Fprintf(w, "RUN: %s (%s %s) %s - %s\n", metadata.RunID, metadata.Command, metadata.Version,
+		metadata.StartedAt.Format(time.RFC3339), metadata.FinishedAt.Format(time.RFC3339))
+	if metadata.Context != "" {
+		fmt.Fprintf(w, "CLUSTER: %s (%s) as %s\n", metadata.Context, metadata.ServerVersion, metadata.User)
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"k8sexec/pkg/k8sexec"
+	"os"
+)
+
+// attachUsage sets the current resource usage of containers of statuses as
+// reported by the metrics API. Pods without metrics are skipped, failures are
+// reported once on stderr since they usually mean that no metrics-server is
+// installed.
+func attachUsage(k8s *k8sexec.K8SExec, statuses []*k8sexec.ExecutionStatus) {
+	pods := map[string]map[string]*k8sexec.Usage{}
+	var failed bool
+	for _, status := range statuses {
+		key := status.Namespace + "/" + status.Pod
+		usage, ok := pods[key]
+		if !ok {
+			var err error
+			usage, err = k8s.PodUsage(context.TODO(), k8sexec.Target{Namespace: status.Namespace, Pod: status.Pod})
+			if err != nil {
+				recordIssue("query the metrics API", key, err)
+			}
+			if err != nil && !failed {
+				failed = true
+				_, _ = fmt.Fprintf(os.Stderr, "Failed to query the metrics API: %v\n", err)
+			}
+			pods[key] = usage
+		}
+		status.Usage = usage[status.Container]
+	}
+}
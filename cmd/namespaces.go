@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8sexec/pkg/k8sexec"
+)
+
+// allNamespaces selects pods of all namespaces instead of --namespace.
+var allNamespaces bool
+
+// selectAllNamespaces returns containers selected by selector in every
+// namespace. Namespaces whose pods cannot be listed or exec'ed into are
+// recorded as skipped instead of failing the run.
+func selectAllNamespaces(ctx context.Context, selector TargetSelector) ([]k8sexec.Target, error) {
+	namespaces, err := cached("namespaces", func() (*corev1.NamespaceList, error) {
+		return clientset.CoreV1().Namespaces().List(ctx, metaV1.ListOptions{})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	var targets []k8sexec.Target
+	for _, _namespace := range namespaces.Items {
+		selector.Namespace = _namespace.Name
+		if reason := execDenied(ctx, _namespace.Name); reason != "" {
+			recordSkipped(_namespace.Name, "", reason)
+			continue
+		}
+
+		namespaceTargets, err := selectTargets(ctx, selector)
+		if apierrors.IsForbidden(err) || apierrors.IsUnauthorized(err) {
+			recordSkipped(_namespace.Name, "", "cannot list pods: "+err.Error())
+			recordIssue("list pods", _namespace.Name, err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, namespaceTargets...)
+	}
+	return targets, nil
+}
+
+// execDenied returns why commands cannot be executed in pods of namespace,
+// an empty string when they can or it is not known.
+func execDenied(ctx context.Context, namespace string) string {
+	review, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &authorizationv1.ResourceAttributes{
+			Namespace:   namespace,
+			Verb:        "create",
+			Resource:    "pods",
+			Subresource: "exec",
+		}},
+	}, metaV1.CreateOptions{})
+	if err != nil {
+		recordIssue("review exec access", namespace, err)
+		return ""
+	}
+	if review.Status.Allowed {
+		return ""
+	}
+	reason := "not allowed to exec into pods"
+	if review.Status.Reason != "" {
+		reason += ": " + review.Status.Reason
+	}
+	return reason
+}
@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	corev1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8sexec/pkg/k8sexec"
+	"k8sexec/pkg/netcheck"
+	"k8sexec/pkg/schema"
+	"net"
+	"strconv"
+	"text/tabwriter"
+)
+
+// NetcheckReport is a reachability matrix of destinations from pods.
+type NetcheckReport struct {
+	SchemaVersion int                   `json:"schemaVersion"`
+	Metadata      *RunMetadata          `json:"metadata,omitempty"`
+	Namespace     string                `json:"Namespace"`
+	Sources       []string              `json:"Sources"`
+	Destinations  []NetcheckDestination `json:"Destinations"`
+	Probes        []NetcheckProbe       `json:"Probes"`
+}
+
+// NetcheckDestination is a service or pod port, or an address given as an
+// argument.
+type NetcheckDestination struct {
+	Name    string `json:"Name"`
+	Address string `json:"Address"`
+}
+
+// NetcheckProbe is a probe of a destination from a source pod.
+type NetcheckProbe struct {
+	Source      string `json:"Source"`
+	Destination string `json:"Destination"`
+	Result      string `json:"Result"`
+	Tool        string `json:"Tool,omitempty"`
+	Error       string `json:"Error,omitempty"`
+}
+
+var (
+	netcheckServices       bool
+	netcheckPods           bool
+	netcheckConnectTimeout int
+)
+
+var netcheckCmd = &cobra.Command{
+	Use:   "netcheck [host:port...]",
+	Short: "Probes reachability of services and pods from the selected pods",
+	Long: `Probes TCP reachability of destinations from every selected pod with nc, curl or bash
+and prints a reachability matrix, e.g. to verify NetworkPolicies. Destinations are the given
+host:port addresses, or ports of services and pods of the namespace when none are given.
+Since containers of a pod share their network, probes run in one container of each pod.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runNetcheck(args)
+	},
+}
+
+func runNetcheck(args []string) error {
+	if err := validateOptions(); err != nil {
+		return err
+	}
+
+	k8s := newK8SExec()
+	targets, err := loadTargets(context.TODO())
+	if err != nil {
+		return err
+	}
+	targets = uniquePodTargets(targets)
+
+	destinations, err := netcheckDestinations(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	if len(destinations) == 0 {
+		return errors.New("no destinations to probe")
+	}
+
+	if dryRun != "" && !simulate {
+		return printTargets(targets)
+	}
+
+	command := []string{"sh", "-s", "--", strconv.Itoa(netcheckConnectTimeout)}
+	for _, destination := range destinations {
+		command = append(command, destination.Address)
+	}
+	statuses := k8s.ExecAll(context.TODO(), targets, command, execOptions([]byte(netcheck.Script)))
+
+	report := &NetcheckReport{SchemaVersion: schema.Version, Namespace: namespace, Destinations: destinations}
+	for _, status := range statuses {
+		source := status.Namespace + "/" + status.Pod
+		report.Sources = append(report.Sources, source)
+
+		probes := netcheck.ParseProbes(status.Stdout)
+		for _, destination := range destinations {
+			probe := NetcheckProbe{Source: source, Destination: destination.Name}
+			if status.Error != "" {
+				probe.Result, probe.Error = "error", status.Error
+			} else if p, ok := probes[destination.Address]; ok {
+				probe.Result, probe.Tool = p.Result, p.Tool
+			} else {
+				probe.Result = netcheck.ResultUntested
+			}
+			report.Probes = append(report.Probes, probe)
+		}
+	}
+
+	return printReport(report)
+}
+
+// uniquePodTargets keeps only the first container of each pod.
+func uniquePodTargets(targets []k8sexec.Target) []k8sexec.Target {
+	seen := map[string]bool{}
+	var unique []k8sexec.Target
+	for _, target := range targets {
+		key := target.Namespace + "/" + target.Pod
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, target)
+		}
+	}
+	return unique
+}
+
+// netcheckDestinations returns addresses given as arguments, or TCP ports of
+// services and running pods of the namespace when there are none.
+func netcheckDestinations(ctx context.Context, args []string) ([]NetcheckDestination, error) {
+	var destinations []NetcheckDestination
+	for _, arg := range args {
+		if _, _, err := net.SplitHostPort(arg); err != nil {
+			return nil, fmt.Errorf("invalid destination %q: %w", arg, err)
+		}
+		destinations = append(destinations, NetcheckDestination{Name: arg, Address: arg})
+	}
+	if len(args) > 0 {
+		return destinations, nil
+	}
+
+	if clientset == nil {
+		return nil, errors.New("destinations must be given as arguments when replaying")
+	}
+
+	services, pods := netcheckServices, netcheckPods
+	if !services && !pods {
+		services, pods = true, true
+	}
+
+	if services {
+		list, err := clientset.CoreV1().Services(namespace).List(ctx, metaV1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, service := range list.Items {
+			for _, port := range service.Spec.Ports {
+				if port.Protocol != "" && port.Protocol != corev1.ProtocolTCP {
+					continue
+				}
+				host := fmt.Sprintf("%s.%s.svc", service.Name, service.Namespace)
+				destinations = append(destinations, NetcheckDestination{
+					Name:    fmt.Sprintf("svc/%s:%d", service.Name, port.Port),
+					Address: net.JoinHostPort(host, strconv.Itoa(int(port.Port))),
+				})
+			}
+		}
+	}
+
+	if pods {
+		list, err := clientset.CoreV1().Pods(namespace).List(ctx, metaV1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		for _, _pod := range list.Items {
+			if _pod.Status.Phase != corev1.PodRunning || _pod.Status.PodIP == "" {
+				continue
+			}
+			for _, _container := range _pod.Spec.Containers {
+				for _, port := range _container.Ports {
+					if port.Protocol != "" && port.Protocol != corev1.ProtocolTCP {
+						continue
+					}
+					destinations = append(destinations, NetcheckDestination{
+						Name:    fmt.Sprintf("pod/%s:%d", _pod.Name, port.ContainerPort),
+						Address: net.JoinHostPort(_pod.Status.PodIP, strconv.Itoa(int(port.ContainerPort))),
+					})
+				}
+			}
+		}
+	}
+
+	return destinations, nil
+}
+
+// WriteText implements output.TextWriter.
+func (r *NetcheckReport) WriteText(w io.Writer) error {
+	fmt.Fprintln(w, "NETCHECK")
+	fmt.Fprintf(w, "Namespace: %s\n", r.Namespace)
+	for i, destination := range r.Destinations {
+		fmt.Fprintf(w, "D%d: %s (%s)\n", i+1, destination.Name, destination.Address)
+	}
+	fmt.Fprintln(w)
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprint(table, "SOURCE")
+	for i := range r.Destinations {
+		fmt.Fprintf(table, "\tD%d", i+1)
+	}
+	fmt.Fprintln(table)
+	for i, probe := range r.Probes {
+		if i%len(r.Destinations) == 0 {
+			fmt.Fprint(table, probe.Source)
+		}
+		fmt.Fprintf(table, "\t%s", probe.Result)
+		if (i+1)%len(r.Destinations) == 0 {
+			fmt.Fprintln(table)
+		}
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	for i := 0; i < len(r.Probes); i += len(r.Destinations) {
+		if probe := r.Probes[i]; probe.Error != "" {
+			fmt.Fprintf(w, "\nProbes from %s failed: %s\n", probe.Source, probe.Error)
+		}
+	}
+	return nil
+}
+
+func init() {
+	netcheckCmd.Flags().BoolVar(&netcheckServices, "services", false, "probe ports of services of the namespace (default when no destinations are given)")
+	netcheckCmd.Flags().BoolVar(&netcheckPods, "pods", false, "probe container ports of pods of the namespace (default when no destinations are given)")
+	netcheckCmd.Flags().IntVar(&netcheckConnectTimeout, "connect-timeout", 2, "seconds to wait for a connection to be established")
+	cmd.AddCommand(netcheckCmd)
+}
@@ -0,0 +1,365 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/util/retry"
+	"k8sexec/pkg/cron"
+	"k8sexec/pkg/k8sexec"
+	"log"
+	"sync"
+	"time"
+)
+
+// execTaskResource is the resource of ExecTask custom resources, see
+// deploy/exectask.yaml for their definition.
+var execTaskResource = k8sschema.GroupVersionResource{Group: "kubex.io", Version: "v1alpha1", Resource: "exectasks"}
+
+// ExecTaskSpec is the desired state of an ExecTask: a command executed in
+// selected containers of its namespace, once or on a schedule.
+type ExecTaskSpec struct {
+	Selector ExecTaskSelector `json:"selector,omitempty"`
+	Command  []string         `json:"command,omitempty"`
+	Stdin    string           `json:"stdin,omitempty"`
+	Parallel int              `json:"parallel,omitempty"`
+	// Schedule is a crontab schedule, a macro like @daily or @every
+	// followed by a duration. Tasks without it run once for each change of
+	// their spec.
+	Schedule string `json:"schedule,omitempty"`
+	Suspend  bool   `json:"suspend,omitempty"`
+	// Output names a ConfigMap or Secret in the namespace of the task the
+	// full report of the last run is written to.
+	Output *ExecTaskOutput `json:"output,omitempty"`
+}
+
+// ExecTaskSelector selects containers in the namespace of an ExecTask.
+type ExecTaskSelector struct {
+	Pod           string `json:"pod,omitempty"`
+	Container     string `json:"container,omitempty"`
+	LabelSelector string `json:"labelSelector,omitempty"`
+	Node          string `json:"node,omitempty"`
+	NodeSelector  string `json:"nodeSelector,omitempty"`
+}
+
+// ExecTaskOutput is where the report of an ExecTask is written to.
+type ExecTaskOutput struct {
+	ConfigMap string `json:"configMap,omitempty"`
+	Secret    string `json:"secret,omitempty"`
+}
+
+// ExecTaskStatus is the outcome of the last run of an ExecTask.
+type ExecTaskStatus struct {
+	Phase              string          `json:"phase,omitempty"`
+	ObservedGeneration int64           `json:"observedGeneration,omitempty"`
+	LastRunTime        *metaV1.Time    `json:"lastRunTime,omitempty"`
+	NextRunTime        *metaV1.Time    `json:"nextRunTime,omitempty"`
+	Targets            int             `json:"targets"`
+	Failed             int             `json:"failed"`
+	Message            string          `json:"message,omitempty"`
+	Failures           []string        `json:"failures,omitempty"`
+	Output             *ExecTaskOutput `json:"output,omitempty"`
+}
+
+// Phases of ExecTasks.
+const (
+	TaskRunning   = "Running"
+	TaskSucceeded = "Succeeded"
+	TaskFailed    = "Failed"
+	TaskInvalid   = "Invalid"
+)
+
+// reportKey is the key of the report in ConfigMaps and Secrets.
+const reportKey = "report.json"
+
+// maxStatusFailures limits containers listed in the status of a task.
+const maxStatusFailures = 20
+
+var operatorResync time.Duration
+
+var operatorCmd = &cobra.Command{
+	Use:   "operator",
+	Short: "Runs sweeps declared by ExecTask custom resources, e.g. in a cluster",
+	Long: `Runs as a controller executing commands declared by ExecTask custom resources in
+containers of their namespaces, once or on a schedule, and writes the outcome into their
+status and the full report into a ConfigMap or Secret. Use --namespace to watch a single
+namespace, or --all-namespaces. When running in a cluster without a kubeconfig, the service
+account of the pod is used. See deploy/exectask.yaml for the resource definition.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runOperator()
+	},
+}
+
+// operator reconciles ExecTasks.
+type operator struct {
+	k8s     *k8sexec.K8SExec
+	client  dynamic.NamespaceableResourceInterface
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+func runOperator() error {
+	if err := validateOptions(); err != nil {
+		return err
+	}
+	if replayDir != "" || dryRun != "" {
+		return errors.New("operator cannot be used with --replay or --dry-run")
+	}
+
+	k8s := newK8SExec()
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	o := &operator{k8s: k8s, client: client.Resource(execTaskResource), running: map[string]bool{}}
+
+	watched := namespace
+	if allNamespaces {
+		watched = metaV1.NamespaceAll
+	}
+	log.Printf("Reconciling ExecTasks every %s", operatorResync)
+	for {
+		o.reconcileAll(context.Background(), watched)
+		time.Sleep(operatorResync)
+	}
+}
+
+func (o *operator) reconcileAll(ctx context.Context, namespace string) {
+	list, err := o.client.Namespace(namespace).List(ctx, metaV1.ListOptions{})
+	if err != nil {
+		log.Printf("Failed to list ExecTasks: %v", err)
+		return
+	}
+	for i := range list.Items {
+		o.reconcile(ctx, &list.Items[i])
+	}
+}
+
+// reconcile starts a run of task when it is due and not running yet.
+func (o *operator) reconcile(ctx context.Context, task *unstructured.Unstructured) {
+	key := task.GetNamespace() + "/" + task.GetName()
+	var spec ExecTaskSpec
+	var status ExecTaskStatus
+	if err := fromUnstructured(task.Object["spec"], &spec); err != nil {
+		o.setStatus(ctx, task, &ExecTaskStatus{Phase: TaskInvalid, ObservedGeneration: task.GetGeneration(), Message: err.Error()})
+		return
+	}
+	_ = fromUnstructured(task.Object["status"], &status)
+
+	if spec.Suspend || (len(spec.Command) == 0 && spec.Stdin == "") {
+		return
+	}
+	var schedule cron.Schedule
+	if spec.Schedule != "" {
+		var err error
+		if schedule, err = cron.Parse(spec.Schedule); err != nil {
+			if status.Phase != TaskInvalid || status.ObservedGeneration != task.GetGeneration() {
+				o.setStatus(ctx, task, &ExecTaskStatus{Phase: TaskInvalid, ObservedGeneration: task.GetGeneration(), Message: err.Error()})
+			}
+			return
+		}
+	}
+
+	changed := status.ObservedGeneration != task.GetGeneration()
+	due := schedule != nil && status.NextRunTime != nil && !time.Now().Before(status.NextRunTime.Time)
+	if !changed && !due {
+		return
+	}
+
+	o.mu.Lock()
+	if o.running[key] {
+		o.mu.Unlock()
+		return
+	}
+	o.running[key] = true
+	o.mu.Unlock()
+
+	go func() {
+		defer func() {
+			o.mu.Lock()
+			delete(o.running, key)
+			o.mu.Unlock()
+		}()
+		o.run(ctx, task, &spec, schedule)
+	}()
+}
+
+// run executes the command of task and records its outcome.
+func (o *operator) run(ctx context.Context, task *unstructured.Unstructured, spec *ExecTaskSpec, schedule cron.Schedule) {
+	started := metaV1.Now()
+	status := &ExecTaskStatus{Phase: TaskRunning, ObservedGeneration: task.GetGeneration(), LastRunTime: &started, Output: spec.Output}
+	o.setStatus(ctx, task, status)
+
+	command := spec.Command
+	if len(command) == 0 {
+		command = []string{"sh"}
+	}
+	// tasks only ever select containers of their own namespace, so that
+	// creating a task does not grant access to other namespaces
+	targets, err := selectTargets(ctx, TargetSelector{
+		Namespace:     task.GetNamespace(),
+		Pod:           spec.Selector.Pod,
+		Container:     spec.Selector.Container,
+		LabelSelector: spec.Selector.LabelSelector,
+		Node:          spec.Selector.Node,
+		NodeSelector:  spec.Selector.NodeSelector,
+	})
+	if err == nil {
+		opts := execOptions([]byte(spec.Stdin))
+		if spec.Parallel > 0 {
+			opts.Parallel = spec.Parallel
+		}
+		report := NewEnumerationStatus(spec.Stdin, command, task.GetNamespace())
+		report.Statuses = o.k8s.ExecAll(ctx, targets, command, opts)
+
+		status.Targets = len(report.Statuses)
+		for _, result := range report.Statuses {
+			if result.ErrorKind == "" {
+				continue
+			}
+			status.Failed++
+			if len(status.Failures) < maxStatusFailures {
+				status.Failures = append(status.Failures, fmt.Sprintf("%s/%s: %s", result.Pod, result.Container, result.ErrorKind))
+			}
+		}
+		err = o.writeOutput(ctx, task, spec.Output, report)
+	}
+
+	status.Phase = TaskSucceeded
+	status.Message = fmt.Sprintf("executed in %d containers, %d failed", status.Targets, status.Failed)
+	if err != nil {
+		status.Phase = TaskFailed
+		status.Message = err.Error()
+	}
+	if schedule != nil {
+		next := metaV1.NewTime(schedule.Next(time.Now()))
+		status.NextRunTime = &next
+	}
+	o.setStatus(ctx, task, status)
+}
+
+// writeOutput writes report into the ConfigMap or Secret of output, owned by
+// task so that it is deleted together with it. Existing objects not owned by
+// task are refused, so that tasks cannot overwrite and take over ConfigMaps
+// and Secrets of others.
+func (o *operator) writeOutput(ctx context.Context, task *unstructured.Unstructured, output *ExecTaskOutput, report *EnumerationStatus) error {
+	if output == nil || (output.ConfigMap == "" && output.Secret == "") {
+		return nil
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	if output.ConfigMap != "" {
+		configMaps := clientset.CoreV1().ConfigMaps(task.GetNamespace())
+		configMap, err := configMaps.Get(ctx, output.ConfigMap, metaV1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err):
+			configMap = &corev1.ConfigMap{ObjectMeta: outputMeta(task, output.ConfigMap), Data: map[string]string{reportKey: string(data)}}
+			_, err = configMaps.Create(ctx, configMap, metaV1.CreateOptions{})
+		case err == nil && !ownedBy(configMap, task):
+			err = errNotOwned
+		case err == nil:
+			configMap.Data = map[string]string{reportKey: string(data)}
+			_, err = configMaps.Update(ctx, configMap, metaV1.UpdateOptions{})
+		}
+		if err != nil {
+			return fmt.Errorf("writing report to ConfigMap %s: %w", output.ConfigMap, err)
+		}
+	}
+	if output.Secret != "" {
+		secrets := clientset.CoreV1().Secrets(task.GetNamespace())
+		secret, err := secrets.Get(ctx, output.Secret, metaV1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err):
+			secret = &corev1.Secret{ObjectMeta: outputMeta(task, output.Secret), Data: map[string][]byte{reportKey: data}}
+			_, err = secrets.Create(ctx, secret, metaV1.CreateOptions{})
+		case err == nil && !ownedBy(secret, task):
+			err = errNotOwned
+		case err == nil:
+			secret.Data = map[string][]byte{reportKey: data}
+			_, err = secrets.Update(ctx, secret, metaV1.UpdateOptions{})
+		}
+		if err != nil {
+			return fmt.Errorf("writing report to Secret %s: %w", output.Secret, err)
+		}
+	}
+	return nil
+}
+
+// errNotOwned is returned for outputs of tasks which exist but were not
+// created by them.
+var errNotOwned = errors.New("it exists and is not owned by the ExecTask")
+
+// outputMeta returns the metadata of the output name of task.
+func outputMeta(task *unstructured.Unstructured, name string) metaV1.ObjectMeta {
+	return metaV1.ObjectMeta{
+		Namespace: task.GetNamespace(),
+		Name:      name,
+		Labels:    map[string]string{storeManagedByLabel: "kubex"},
+		OwnerReferences: []metaV1.OwnerReference{{
+			APIVersion: task.GetAPIVersion(),
+			Kind:       task.GetKind(),
+			Name:       task.GetName(),
+			UID:        task.GetUID(),
+		}},
+	}
+}
+
+// ownedBy reports whether object is owned by task.
+func ownedBy(object metaV1.Object, task *unstructured.Unstructured) bool {
+	for _, owner := range object.GetOwnerReferences() {
+		if owner.UID == task.GetUID() {
+			return true
+		}
+	}
+	return false
+}
+
+// setStatus replaces the status of task, retrying on conflicts with its
+// latest version.
+func (o *operator) setStatus(ctx context.Context, task *unstructured.Unstructured, status *ExecTaskStatus) {
+	object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(status)
+	if err != nil {
+		log.Printf("Failed to encode status of ExecTask %s/%s: %v", task.GetNamespace(), task.GetName(), err)
+		return
+	}
+
+	client := o.client.Namespace(task.GetNamespace())
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := client.Get(ctx, task.GetName(), metaV1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		latest.Object["status"] = object
+		_, err = client.UpdateStatus(ctx, latest, metaV1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		log.Printf("Failed to update status of ExecTask %s/%s: %v", task.GetNamespace(), task.GetName(), err)
+	}
+}
+
+func fromUnstructured(object any, into any) error {
+	fields, ok := object.(map[string]any)
+	if !ok {
+		return nil
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(fields, into)
+}
+
+func init() {
+	operatorCmd.Flags().DurationVar(&operatorResync, "resync", 30*time.Second, "interval in which ExecTasks are checked for changes and due runs")
+	cmd.AddCommand(operatorCmd)
+}
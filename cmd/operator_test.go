@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	corev1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"testing"
+)
+
+func TestWriteOutputOwnership(t *testing.T) {
+	foreign := &corev1.Secret{
+		ObjectMeta: metaV1.ObjectMeta{Namespace: "ns", Name: "credentials"},
+		Data:       map[string][]byte{"password": []byte("secret")},
+	}
+	fakeClientset := useFakeClientset(t, foreign)
+	task := &unstructured.Unstructured{}
+	task.SetAPIVersion("kubex.io/v1alpha1")
+	task.SetKind("ExecTask")
+	task.SetNamespace("ns")
+	task.SetName("audit")
+	task.SetUID("task-uid")
+	o := &operator{}
+	ctx := context.Background()
+
+	// created owned by the task, then updated
+	for range 2 {
+		if err := o.writeOutput(ctx, task, &ExecTaskOutput{ConfigMap: "report"}, &EnumerationStatus{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	configMap, err := fakeClientset.CoreV1().ConfigMaps("ns").Get(ctx, "report", metaV1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ownedBy(configMap, task) || configMap.Data[reportKey] == "" {
+		t.Errorf("report ConfigMap %+v, want one owned by the task with the report", configMap)
+	}
+
+	err = o.writeOutput(ctx, task, &ExecTaskOutput{Secret: "credentials"}, &EnumerationStatus{})
+	if !errors.Is(err, errNotOwned) {
+		t.Errorf("writing to a Secret not owned by the task = %v, want errNotOwned", err)
+	}
+	secret, err := fakeClientset.CoreV1().Secrets("ns").Get(ctx, "credentials", metaV1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secret.OwnerReferences) != 0 || string(secret.Data["password"]) != "secret" || secret.Data[reportKey] != nil {
+		t.Errorf("Secret not owned by the task was changed: %+v", secret)
+	}
+}
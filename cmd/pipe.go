@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8sexec/pkg/k8sexec"
+	"k8sexec/pkg/schema"
+	"strings"
+)
+
+// PipeReport holds the outcome of commands connected by the pipe command.
+type PipeReport struct {
+	SchemaVersion int                      `json:"schemaVersion"`
+	Metadata      *RunMetadata             `json:"metadata,omitempty"`
+	From          *k8sexec.ExecutionStatus `json:"From"`
+	To            *k8sexec.ExecutionStatus `json:"To"`
+	Bytes         int64                    `json:"Bytes"`
+}
+
+// defaultContainerAnnotation selects the container kubectl uses when none is
+// given.
+const defaultContainerAnnotation = "kubectl.kubernetes.io/default-container"
+
+var (
+	pipeFrom string
+	pipeTo   string
+)
+
+var pipeCmd = &cobra.Command{
+	Use:   "pipe --from pod[/container]:command --to pod[/container]:command",
+	Short: "Streams the output of a command in one container into a command in another container",
+	Long: `Executes commands in two containers concurrently and streams the standard output of the
+--from command into the standard input of the --to command without passing it through local
+disk, e.g. to copy a database with pg_dump and psql. Commands are executed with sh -c. When
+no container is given, the default container of the pod is used. The --from command is
+canceled when the --to command finishes early, and the --to command is canceled when the
+--from command fails.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runPipe()
+	},
+}
+
+func runPipe() error {
+	if err := validateOptions(); err != nil {
+		return err
+	}
+	if pipeFrom == "" || pipeTo == "" {
+		return errors.New("pipe requires --from and --to")
+	}
+
+	k8s := newK8SExec()
+	from, fromCmd, err := parsePipeEndpoint(context.TODO(), pipeFrom)
+	if err != nil {
+		return err
+	}
+	to, toCmd, err := parsePipeEndpoint(context.TODO(), pipeTo)
+	if err != nil {
+		return err
+	}
+
+	if dryRun != "" && !simulate {
+		return printTargets([]k8sexec.Target{from, to})
+	}
+
+	status := k8s.Pipe(context.TODO(), from, []string{"sh", "-c", fromCmd}, to, []string{"sh", "-c", toCmd})
+	if err := printReport(&PipeReport{SchemaVersion: schema.Version, From: status.From, To: status.To, Bytes: status.Bytes}); err != nil {
+		return err
+	}
+	if status.From.ErrorKind != "" || status.To.ErrorKind != "" {
+		return errors.New("pipe failed")
+	}
+	return nil
+}
+
+// parsePipeEndpoint parses pod[/container]:command.
+func parsePipeEndpoint(ctx context.Context, endpoint string) (k8sexec.Target, string, error) {
+	location, command, ok := strings.Cut(endpoint, ":")
+	if !ok || location == "" || command == "" {
+		return k8sexec.Target{}, "", fmt.Errorf("invalid endpoint %q, must be pod[/container]:command", endpoint)
+	}
+	target, err := resolveContainer(ctx, location)
+	return target, command, err
+}
+
+// resolveContainer returns the container of pod[/container] in --namespace,
+// the default container of the pod when none is given.
+func resolveContainer(ctx context.Context, location string) (k8sexec.Target, error) {
+	podName, containerName, _ := strings.Cut(location, "/")
+	target := k8sexec.Target{Namespace: namespace, Pod: podName, Container: containerName}
+	if containerName != "" {
+		return target, nil
+	}
+	if replayDir != "" {
+		return target, fmt.Errorf("a container of pod %s must be given with --replay", podName)
+	}
+
+	_pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, podName, metaV1.GetOptions{})
+	if err != nil {
+		return target, err
+	}
+	rememberPod(_pod)
+	if name := _pod.Annotations[defaultContainerAnnotation]; name != "" {
+		target.Container = name
+	} else if len(_pod.Spec.Containers) > 0 {
+		target.Container = _pod.Spec.Containers[0].Name
+	}
+	return target, nil
+}
+
+// WriteText implements output.TextWriter.
+func (r *PipeReport) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "Streamed %d bytes\n\n", r.Bytes)
+	fmt.Fprintln(w, "FROM:")
+	printStatus(w, r.From, false)
+	fmt.Fprintln(w, "TO:")
+	printStatus(w, r.To, false)
+	return nil
+}
+
+func init() {
+	pipeCmd.Flags().StringVar(&pipeFrom, "from", "", "pod[/container]:command whose standard output is streamed")
+	pipeCmd.Flags().StringVar(&pipeTo, "to", "", "pod[/container]:command receiving the stream on its standard input")
+	cmd.AddCommand(pipeCmd)
+}
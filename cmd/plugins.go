@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"context"
+	"k8sexec/pkg/checks"
+	"k8sexec/pkg/k8sexec"
+	"os"
+)
+
+// runPlugins runs check plugins found in PATH for targets and returns their
+// findings. Plugins which fail are reported as findings as well.
+func runPlugins(targets []k8sexec.Target) []checks.Finding {
+	in := checks.PluginInput{Kubeconfig: kubeconfig, Namespace: namespace}
+	for _, target := range targets {
+		image, imageID := targetImageID(target)
+		in.Targets = append(in.Targets, checks.PluginTarget{
+			Namespace: target.Namespace,
+			Pod:       target.Pod,
+			Container: target.Container,
+			Image:     image,
+			ImageID:   imageID,
+			Node:      targetNode(target),
+		})
+	}
+
+	var findings []checks.Finding
+	for _, plugin := range checks.DiscoverPlugins(os.Getenv("PATH")) {
+		pluginFindings, err := plugin.Run(context.TODO(), in)
+		if err != nil {
+			findings = append(findings, checks.Finding{
+				Check:     plugin.Name,
+				ID:        "plugin-failed",
+				Severity:  checks.SeverityInfo,
+				Namespace: namespace,
+				Title:     "check plugin failed",
+				Detail:    err.Error(),
+			})
+			continue
+		}
+		findings = append(findings, pluginFindings...)
+	}
+	return findings
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"k8sexec/pkg/checks"
+	"k8sexec/pkg/k8sexec"
+	"sort"
+	"strings"
+)
+
+var (
+	posture        bool
+	postureWeights string
+	// weights are loaded by loadWeights.
+	weights checks.Weights
+)
+
+// loadWeights loads the weights of posture scores from --posture-weights,
+// or the default ones.
+func loadWeights() error {
+	if postureWeights == "" {
+		weights = checks.DefaultWeights()
+		return nil
+	}
+	var err error
+	weights, err = checks.LoadWeights(postureWeights)
+	return err
+}
+
+// scorePosture scores workloads of targets by findings of their containers.
+func scorePosture(targets []k8sexec.Target, findings []checks.Finding) *checks.Posture {
+	resolveWorkloads(context.TODO(), targets)
+	workloads := map[string]string{}
+	var names []string
+	for _, target := range targets {
+		key := target.Namespace + "/" + target.Pod
+		if _, ok := workloads[key]; ok {
+			continue
+		}
+		workload := Workload{Namespace: target.Namespace, Kind: "Pod", Name: target.Pod}
+		if _pod := lookupPod(target); _pod != nil {
+			workload = podWorkload(context.TODO(), _pod)
+		}
+		workloads[key] = workload.String()
+		names = append(names, workload.String())
+	}
+
+	return checks.ScorePosture(names, findings, func(finding checks.Finding) string {
+		if name, ok := workloads[finding.Namespace+"/"+finding.Pod]; ok {
+			return name
+		}
+		return Workload{Namespace: finding.Namespace, Kind: "Pod", Name: finding.Pod}.String()
+	}, weights)
+}
+
+// printPosture prints posture scores, the worst first.
+func printPosture(w io.Writer, posture *checks.Posture) {
+	fmt.Fprintf(w, "POSTURE: average score %d of %d workloads\n", posture.Average, len(posture.Workloads))
+	for _, score := range posture.Workloads {
+		fmt.Fprintf(w, "%3d %s", score.Score, score.Workload)
+		if len(score.Deductions) > 0 {
+			deductions := make([]string, 0, len(score.Deductions))
+			for check, points := range score.Deductions {
+				deductions = append(deductions, fmt.Sprintf("%s -%d", check, points))
+			}
+			sort.Strings(deductions)
+			fmt.Fprintf(w, " (%s)", strings.Join(deductions, ", "))
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintln(w)
+}
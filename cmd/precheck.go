@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"k8sexec/pkg/k8sexec"
+	"os"
+)
+
+var precheck bool
+
+// precheckScript exits with 1 when the command $0 does not exist, other
+// non-zero exit codes mean that the probe could not run, e.g. without sh.
+const precheckScript = `command -v "$0" >/dev/null || exit 1`
+
+// runPrecheck checks in parallel that the command name exists in targets and
+// prints its coverage to stderr. It returns the targets in which it exists,
+// or could not be checked, and statuses failed with ErrCommandNotFound for
+// the others, so that the command is not executed in them.
+func runPrecheck(ctx context.Context, k8s *k8sexec.K8SExec, targets []k8sexec.Target, name string, opts k8sexec.ExecOptions) ([]k8sexec.Target, []*k8sexec.ExecutionStatus) {
+	probeOpts := k8sexec.ExecOptions{Parallel: opts.Parallel, MaxPerNode: opts.MaxPerNode, Node: opts.Node, Image: opts.Image}
+	probes := k8s.ExecAll(ctx, targets, []string{"sh", "-c", precheckScript, name}, probeOpts)
+
+	var found, unknown []k8sexec.Target
+	var missing []*k8sexec.ExecutionStatus
+	var details []string
+	for i, probe := range probes {
+		switch {
+		case probe.Error != "":
+			unknown = append(unknown, targets[i])
+			details = append(details, fmt.Sprintf("  %s/%s/%s: not checked: %s", probe.Namespace, probe.Pod, probe.Container, probe.Error))
+		case probe.RetCode == 1:
+			missing = append(missing, notFoundStatus(probe, name))
+			details = append(details, fmt.Sprintf("  %s/%s/%s: not found", probe.Namespace, probe.Pod, probe.Container))
+		case probe.RetCode != 0:
+			unknown = append(unknown, targets[i])
+			details = append(details, fmt.Sprintf("  %s/%s/%s: not checked: probe exited with %d", probe.Namespace, probe.Pod, probe.Container, probe.RetCode))
+		default:
+			found = append(found, targets[i])
+		}
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "PRECHECK: %s found in %d of %d containers, missing in %d, not checked in %d\n",
+		name, len(found), len(targets), len(missing), len(unknown))
+	for _, detail := range details {
+		_, _ = fmt.Fprintln(os.Stderr, detail)
+	}
+
+	for _, status := range missing {
+		if opts.Done != nil {
+			opts.Done(status)
+		}
+	}
+	return append(found, unknown...), missing
+}
+
+// notFoundStatus converts the status of a probe not finding the command name
+// into the status of the command.
+func notFoundStatus(probe *k8sexec.ExecutionStatus, name string) *k8sexec.ExecutionStatus {
+	status := *probe
+	status.Stdout, status.Stderr = "", ""
+	status.StdoutLines, status.StderrLines = nil, nil
+	status.Err = fmt.Errorf("%w: %s", k8sexec.ErrCommandNotFound, name)
+	status.Error = status.Err.Error()
+	status.ErrorKind = k8sexec.KindCommandNotFound
+	status.RetCode = -1
+	return &status
+}
@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8sexec/pkg/k8sexec"
+	"k8sexec/pkg/netcheck"
+	"k8sexec/pkg/schema"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProbesReport holds outcomes of health probes of all containers.
+type ProbesReport struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	Metadata      *RunMetadata   `json:"metadata,omitempty"`
+	Namespace     string         `json:"Namespace"`
+	Probes        []*ProbeResult `json:"Probes"`
+}
+
+// ProbeResult is the outcome of a health probe of a container executed by
+// kubex.
+type ProbeResult struct {
+	Namespace string `json:"Namespace"`
+	Pod       string `json:"Pod"`
+	Container string `json:"Container"`
+	// Probe is liveness, readiness or startup.
+	Probe string `json:"Probe"`
+	// Handler is exec, httpGet, tcpSocket or grpc.
+	Handler string `json:"Handler"`
+	Action  string `json:"Action"`
+	Passed  bool   `json:"Passed"`
+	Skipped bool   `json:"Skipped,omitempty"`
+	Detail  string `json:"Detail,omitempty"`
+}
+
+// execSetupTimeout is added to timeouts of probes for setting up executions.
+const execSetupTimeout = 10 * time.Second
+
+type probeJob struct {
+	target k8sexec.Target
+	pod    *corev1.Pod
+	spec   *corev1.Container
+	kind   string
+	probe  *corev1.Probe
+}
+
+var probesCmd = &cobra.Command{
+	Use:   "probes",
+	Short: "Executes liveness, readiness and startup probes of the selected containers",
+	Long: `Executes exec probes of the selected containers and replicates their httpGet and tcpSocket
+probes from inside the containers with curl, wget, nc or bash, reporting whether the probes
+would pass right now. gRPC probes are not supported.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runProbes()
+	},
+}
+
+func runProbes() error {
+	if err := validateOptions(); err != nil {
+		return err
+	}
+	if replayDir != "" {
+		return fmt.Errorf("probes need pod specs and cannot be replayed")
+	}
+
+	k8s := newK8SExec()
+	targets, err := loadTargets(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	if dryRun != "" && !simulate {
+		return printTargets(targets)
+	}
+
+	var jobs []probeJob
+	for _, target := range targets {
+		_pod := lookupPod(target)
+		if _pod == nil {
+			continue
+		}
+		for i := range _pod.Spec.Containers {
+			spec := &_pod.Spec.Containers[i]
+			if spec.Name != target.Container {
+				continue
+			}
+			for _, probe := range []struct {
+				kind  string
+				probe *corev1.Probe
+			}{{"startup", spec.StartupProbe}, {"liveness", spec.LivenessProbe}, {"readiness", spec.ReadinessProbe}} {
+				if probe.probe != nil {
+					jobs = append(jobs, probeJob{target: target, pod: _pod, spec: spec, kind: probe.kind, probe: probe.probe})
+				}
+			}
+		}
+	}
+
+	results := make([]*ProbeResult, len(jobs))
+	sem := make(chan struct{}, max(parallel, 1))
+	var wg sync.WaitGroup
+	for i, job := range jobs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = runProbe(k8s, job)
+		}()
+	}
+	wg.Wait()
+
+	return printReport(&ProbesReport{SchemaVersion: schema.Version, Namespace: namespace, Probes: results})
+}
+
+// WriteText implements output.TextWriter.
+func (r *ProbesReport) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "Namespace: %s\n", r.Namespace)
+	for _, result := range r.Probes {
+		outcome := "FAIL"
+		switch {
+		case result.Skipped:
+			outcome = "SKIP"
+		case result.Passed:
+			outcome = "PASS"
+		}
+		fmt.Fprintf(w, "%s %s/%s %s %s: %s", outcome, result.Pod, result.Container, result.Probe, result.Handler, result.Action)
+		if result.Detail != "" {
+			fmt.Fprintf(w, " (%s)", result.Detail)
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+func runProbe(k8s *k8sexec.K8SExec, job probeJob) *ProbeResult {
+	result := &ProbeResult{Namespace: job.target.Namespace, Pod: job.target.Pod, Container: job.target.Container, Probe: job.kind}
+
+	// kubelet defaults timeoutSeconds to 1
+	timeout := max(int(job.probe.TimeoutSeconds), 1)
+	handler := job.probe.ProbeHandler
+	var command []string
+
+	switch {
+	case handler.Exec != nil:
+		result.Handler, result.Action = "exec", strings.Join(handler.Exec.Command, " ")
+		command = handler.Exec.Command
+	case handler.HTTPGet != nil:
+		result.Handler = "httpGet"
+		port, err := probePort(handler.HTTPGet.Port, job.spec)
+		if err != nil {
+			result.Skipped, result.Detail = true, err.Error()
+			return result
+		}
+		host := handler.HTTPGet.Host
+		if host == "" {
+			host = job.pod.Status.PodIP
+		}
+		scheme := strings.ToLower(string(handler.HTTPGet.Scheme))
+		if scheme == "" {
+			scheme = "http"
+		}
+		result.Action = fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(host, strconv.Itoa(port)), handler.HTTPGet.Path)
+		command = []string{"sh", "-s", "--", strconv.Itoa(timeout), result.Action}
+		for _, header := range handler.HTTPGet.HTTPHeaders {
+			command = append(command, header.Name+": "+header.Value)
+		}
+	case handler.TCPSocket != nil:
+		result.Handler = "tcpSocket"
+		port, err := probePort(handler.TCPSocket.Port, job.spec)
+		if err != nil {
+			result.Skipped, result.Detail = true, err.Error()
+			return result
+		}
+		host := handler.TCPSocket.Host
+		if host == "" {
+			host = job.pod.Status.PodIP
+		}
+		result.Action = net.JoinHostPort(host, strconv.Itoa(port))
+		command = []string{"sh", "-s", "--", strconv.Itoa(timeout), result.Action}
+	case handler.GRPC != nil:
+		result.Handler, result.Action = "grpc", fmt.Sprintf("port %d", handler.GRPC.Port)
+		result.Skipped, result.Detail = true, "gRPC probes are not supported"
+		return result
+	default:
+		result.Skipped, result.Detail = true, "probe has no handler"
+		return result
+	}
+
+	var stdin io.Reader
+	switch result.Handler {
+	case "httpGet":
+		stdin = strings.NewReader(netcheck.HTTPScript)
+	case "tcpSocket":
+		stdin = strings.NewReader(netcheck.Script)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Duration(timeout)*time.Second+execSetupTimeout)
+	defer cancel()
+	status := k8s.ExecTarget(ctx, job.target, command, stdin)
+
+	switch {
+	case status.Error != "":
+		result.Detail = fmt.Sprintf("[%s] %s", status.ErrorKind, status.Error)
+	case result.Handler == "exec":
+		result.Passed = status.RetCode == 0
+		result.Detail = fmt.Sprintf("exit code %d", status.RetCode)
+	case result.Handler == "httpGet" && status.RetCode == 127:
+		result.Skipped, result.Detail = true, "neither curl nor wget is available in the container"
+	case result.Handler == "httpGet":
+		code, _ := strconv.Atoi(strings.TrimSpace(status.Stdout))
+		// kubelet considers codes from 200 to 399 successful
+		result.Passed = code >= 200 && code < 400
+		result.Detail = fmt.Sprintf("HTTP status %d", code)
+	case result.Handler == "tcpSocket":
+		probe := netcheck.ParseProbes(status.Stdout)[result.Action]
+		result.Passed = probe.Result == netcheck.ResultOpen
+		result.Skipped = probe.Result == netcheck.ResultUntested || probe.Result == ""
+		result.Detail = fmt.Sprintf("%s with %s", probe.Result, probe.Tool)
+	}
+	return result
+}
+
+// probePort resolves a port of a probe, which may be a name of a port of
+// the container.
+func probePort(port intstr.IntOrString, spec *corev1.Container) (int, error) {
+	if port.Type == intstr.Int {
+		return port.IntValue(), nil
+	}
+	for _, containerPort := range spec.Ports {
+		if containerPort.Name == port.StrVal {
+			return int(containerPort.ContainerPort), nil
+		}
+	}
+	return 0, fmt.Errorf("container has no port named %s", port.StrVal)
+}
+
+func init() {
+	cmd.AddCommand(probesCmd)
+}
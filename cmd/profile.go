@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"k8sexec/pkg/checks"
+	"k8sexec/pkg/inventory"
+	"k8sexec/pkg/k8sexec"
+	"os"
+)
+
+// runProfile runs checks of the named profile in all selected containers.
+func runProfile(k8s *k8sexec.K8SExec, name string) error {
+	profileChecks, err := checks.Profile(name)
+	if err != nil {
+		return err
+	}
+
+	enumStatus := NewEnumerationStatus("", nil, namespace)
+	enumStatus.Profile = name
+	return runChecks(k8s, enumStatus, profileChecks)
+}
+
+// runGolden compares files of all selected containers with the golden file
+// at path.
+func runGolden(k8s *k8sexec.K8SExec, path string) error {
+	golden, err := checks.LoadGolden(path)
+	if err != nil {
+		return err
+	}
+
+	enumStatus := NewEnumerationStatus("", nil, namespace)
+	enumStatus.Scan = "golden " + path
+	return runChecks(k8s, enumStatus, []*checks.Check{golden.Check()})
+}
+
+// runChecks runs checks in all selected containers and prints their findings
+// as a part of enumStatus.
+func runChecks(k8s *k8sexec.K8SExec, enumStatus *EnumerationStatus, list []*checks.Check) error {
+	targets, err := loadTargets(context.TODO())
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	if dryRun != "" && !simulate {
+		return printTargets(targets)
+	}
+
+	enumStatus.Checks, enumStatus.Findings = checks.Run(context.TODO(), k8s, targets, list, checks.Options{
+		Parallel:   parallel,
+		MaxPerNode: maxPerNode,
+		Args:       checkArgs,
+		Pod:        lookupPod,
+		// nil when replaying
+		APIServer: config,
+	})
+	if plugins {
+		enumStatus.Findings = append(enumStatus.Findings, runPlugins(targets)...)
+	}
+	if verifyImages {
+		enumStatus.Findings = append(enumStatus.Findings, verifyImageSignatures(targets)...)
+	}
+	attachRemediations(enumStatus.Findings, nil)
+	enumStatus.Summary = checks.Summarize(enumStatus.Findings)
+	if posture {
+		enumStatus.Posture = scorePosture(targets, enumStatus.Findings)
+	}
+	if includeSpec {
+		enumStatus.Pods = podSnapshots(targets)
+	}
+
+	if enumStatus.Profile != "" {
+		enumStatus.Baseline = containerInventories(k8s, uniqueImageTargets(targets), inventory.FingerprintScript, parseFingerprint)
+	}
+
+	return printEnumerationStatus(enumStatus)
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"k8sexec/pkg/checks"
+	"k8sexec/pkg/k8sexec"
+)
+
+var (
+	remediationsPath string
+	// remediations are loaded by loadRemediations, nil without
+	// --remediations.
+	remediations *checks.Remediations
+)
+
+// loadRemediations loads remediation rules from --remediations.
+func loadRemediations() error {
+	if remediationsPath == "" {
+		return nil
+	}
+	var err error
+	remediations, err = checks.LoadRemediations(remediationsPath)
+	return err
+}
+
+// attachRemediations sets remediations of findings and failed statuses
+// mapped by --remediations.
+func attachRemediations(findings []checks.Finding, statuses []*k8sexec.ExecutionStatus) {
+	if remediations == nil {
+		return
+	}
+	for i := range findings {
+		findings[i].Remediation = remediations.Finding(findings[i])
+	}
+	for _, status := range statuses {
+		status.Remediation = remediations.Status(status)
+	}
+}
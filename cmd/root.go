@@ -4,26 +4,27 @@ import (
 	"bytes"
 	"context"
 	_ "embed"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/hhruszka/k8sexec"
 	"github.com/spf13/cobra"
 	"io"
-	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
+	"k8sexec/pkg/checks"
+	"k8sexec/pkg/k8sexec"
+	"k8sexec/pkg/output"
+	"k8sexec/pkg/schema"
+	"k8sexec/pkg/toolbox"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // App global variables
 var (
 	config    *rest.Config
-	clientset *kubernetes.Clientset
+	clientset kubernetes.Interface
 )
 
 // CLI options variables
@@ -35,15 +36,58 @@ var (
 	debug      bool
 	version    bool
 	format     string
+	parallel   int
+	maxPerNode int
+	spread     string
+	node       string
+	recordDir  string
+	replayDir  string
+	dryRun     string
+	simulate   bool
+	profile    string
+	golden     string
+	guardPath  string
+	readOnly   bool
+	force      bool
+	auditLog   string
+	stream     bool
+	ordered    bool
+	metrics    bool
+	events     bool
+
+	iKnowWhatIAmDoing bool
+	nodeSelector      string
+	includeSpec       bool
+	plugins           bool
+	groupBy           string
+	aggregateBy       string
+	remoteTimeout     time.Duration
+	keepalive         time.Duration
+	dialTimeout       time.Duration
+	uniqueImages      bool
+	resolveCommands   bool
+	retries           int
+	targetsFile       string
+	envVars           []string
+	workdir           string
 )
 
+const dryRunServerSideTargets = "server-side-targets"
+
+// groupByImage groups results by images of containers with --group-by.
+const groupByImage = "image"
+
+// spreadNode selects at most one pod on each node with --spread.
+const spreadNode = "node"
+
 var appName string = filepath.Base(os.Args[0])
 var appVersion string
 
 func k8sInit() {
 	var err error
 
-	config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+	clientConfig = loadClientConfig()
+	config, err = clientConfig.ClientConfig()
 	if err != nil {
 		fmt.Println(err.Error())
 		os.Exit(1)
@@ -57,17 +101,148 @@ func k8sInit() {
 }
 
 type EnumerationStatus struct {
-	Stdin     string                     `json:"Stdin"`
-	Args      []string                   `json:"Args"`
-	Namespace string                     `json:"Namespace"`
-	Statuses  []*k8sexec.ExecutionStatus `json:"Statuses"`
+	SchemaVersion int                        `json:"schemaVersion"`
+	Metadata      *RunMetadata               `json:"metadata,omitempty"`
+	Stdin         string                     `json:"Stdin"`
+	Args          []string                   `json:"Args"`
+	Namespace     string                     `json:"Namespace"`
+	Statuses      []*k8sexec.ExecutionStatus `json:"Statuses"`
+	Profile       string                     `json:"Profile,omitempty"`
+	// Baseline fingerprints one container of each image of a profile run.
+	Baseline []*ContainerInventory `json:"Baseline,omitempty"`
+	Checks   []*checks.Result      `json:"Checks,omitempty"`
+	Scan     string                `json:"Scan,omitempty"`
+	Findings []checks.Finding      `json:"Findings,omitempty"`
+	Summary  []checks.Summary      `json:"Summary,omitempty"`
+	// Posture scores workloads by Findings, only set with --posture.
+	Posture *checks.Posture `json:"Posture,omitempty"`
+	// Overrides are guard rules overridden for commands of the run.
+	Overrides []Override `json:"Overrides,omitempty"`
+	// Skipped are pods excluded from the run, e.g. by annotation.
+	Skipped []Skipped `json:"Skipped,omitempty"`
+	// Issues are steps of the run which failed without failing it.
+	Issues []Issue `json:"Issues,omitempty"`
+	// Pods are snapshots of specs of targeted pods, only set with
+	// --include-spec.
+	Pods []*PodSnapshot `json:"Pods,omitempty"`
+	// Images groups Statuses by images with --group-by image.
+	Images []*ImageGroup `json:"Images,omitempty"`
+	// RunAborted is set when the run was interrupted, Statuses then only
+	// hold results collected so far.
+	RunAborted *RunAborted `json:"runAborted,omitempty"`
+	// Aggregation counts Statuses by a key with --aggregate.
+	Aggregation *Aggregation `json:"Aggregation,omitempty"`
 }
 
 func NewEnumerationStatus(pipeCommand string, command []string, namespace string) *EnumerationStatus {
 	if len(pipeCommand) > 40 {
 		pipeCommand = fmt.Sprintf("%s... too long", pipeCommand[:40])
 	}
-	return &EnumerationStatus{Stdin: pipeCommand, Args: command, Namespace: namespace}
+	return &EnumerationStatus{SchemaVersion: schema.Version, Stdin: pipeCommand, Args: command, Namespace: namespace}
+}
+
+// validateOptions checks options shared by all subcommands.
+func validateOptions() error {
+	if recordDir != "" && replayDir != "" {
+		return errors.New("--record and --replay cannot be used together")
+	}
+
+	if _, ok := output.Lookup(format); !ok {
+		return fmt.Errorf("unsupported output format %q, must be one of: %s", format, strings.Join(output.Names(), ", "))
+	}
+	if verifyImages {
+		if err := verifier.Validate(); err != nil {
+			return err
+		}
+	}
+	if encrypter.Enabled() {
+		if err := encrypter.Validate(); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case dryRun != "" && dryRun != dryRunServerSideTargets:
+		return fmt.Errorf("unsupported --dry-run value %q, only %q is supported", dryRun, dryRunServerSideTargets)
+	case simulate && dryRun == "":
+		return errors.New("--simulate requires --dry-run")
+	case replayDir != "" && (node != "" || nodeSelector != ""):
+		return errors.New("--node and --node-selector cannot be used with --replay")
+	case aggregateBy != "" && aggregateBy != aggregateExitCode && aggregateBy != aggregateImage && aggregateBy != aggregateWorkload:
+		return fmt.Errorf("unsupported --aggregate value %q, must be one of: %s, %s, %s", aggregateBy, aggregateExitCode, aggregateImage, aggregateWorkload)
+	case sortBy != "" && sortKeys[sortBy] == nil:
+		return fmt.Errorf("unsupported --sort value %q, must be one of: pod, container, exit-code, duration", sortBy)
+	case sortReverse && sortBy == "":
+		return errors.New("--reverse requires --sort")
+	case groupBy != "" && groupBy != groupByImage:
+		return fmt.Errorf("unsupported --group-by value %q, only %q is supported", groupBy, groupByImage)
+	case spread != "" && spread != spreadNode:
+		return fmt.Errorf("unsupported --spread value %q, only %q is supported", spread, spreadNode)
+	case stream && format != "text":
+		return errors.New("--stream requires the text output format")
+	case storeInCluster && (replayDir != "" || simulate):
+		return errors.New("--store-in-cluster cannot be used with --replay or --simulate")
+	case (metrics || events) && replayDir != "":
+		return errors.New("--metrics and --events cannot be used with --replay")
+	case encrypter.Enabled() && (stream || checkpointPath != ""):
+		return errors.New("--encrypt cannot be used with --stream or --checkpoint")
+	case signKeyPath != "" && stream:
+		return errors.New("--sign cannot be used with --stream")
+	case anonymize && stream:
+		return errors.New("--anonymize cannot be used with --stream")
+	case ordered && !stream:
+		return errors.New("--ordered requires --stream")
+	case annotationCommands != "" && annotationCommands != annotationCommandsInstead && annotationCommands != annotationCommandsAlso:
+		return fmt.Errorf("unsupported --annotation-commands value %q, must be one of: %s, %s", annotationCommands, annotationCommandsInstead, annotationCommandsAlso)
+	case annotationCommands == annotationCommandsAlso && (checkpointPath != "" || ordered):
+		return errors.New("--annotation-commands also cannot be used with --checkpoint or --ordered")
+	case allNamespaces && (pod != "" || replayDir != ""):
+		return errors.New("--all-namespaces cannot be used with --pod or --replay")
+	case cacheByImage && annotationCommands != "":
+		return errors.New("--cache-by-image cannot be used with --annotation-commands")
+	case failFast != "" && failFast != failFastAny && failFast != failFastError:
+		return fmt.Errorf("unsupported --fail-fast value %q, must be one of: %s, %s", failFast, failFastAny, failFastError)
+	case precheck && annotationCommands == annotationCommandsInstead:
+		return errors.New("--precheck cannot be used with --annotation-commands instead")
+	case targetsFile != "" && (pod != "" || replayDir != "" || allNamespaces || node != "" || nodeSelector != ""):
+		return errors.New("--targets-file cannot be used with --pod, --replay, --all-namespaces, --node or --node-selector")
+	case postureWeights != "" && !posture:
+		return errors.New("--posture-weights requires --posture")
+	case injectToolbox && toolboxPath == "" && len(toolbox.Architectures()) == 0:
+		return errors.New("--inject-toolbox requires --toolbox, no busybox binaries are embedded in this build")
+	case retries < 0:
+		return errors.New("--retries cannot be negative")
+	case resume && checkpointPath == "":
+		return errors.New("--resume requires --checkpoint")
+	}
+
+	if shard != "" {
+		if _, _, err := parseShard(shard); err != nil {
+			return err
+		}
+	}
+
+	if samplePods != "" {
+		if _, err := parseSample(samplePods); err != nil {
+			return err
+		}
+	}
+
+	if err := loadSigner(); err != nil {
+		return err
+	}
+
+	if posture {
+		if err := loadWeights(); err != nil {
+			return err
+		}
+	}
+
+	if err := loadRemediations(); err != nil {
+		return err
+	}
+
+	return parseWhere()
 }
 
 func run(args []string) error {
@@ -77,17 +252,36 @@ func run(args []string) error {
 		return nil
 	}
 
-	k8sInit()
+	if err := validateOptions(); err != nil {
+		return err
+	}
 
-	//Prepare to capture stdin
-	var stdinBuf bytes.Buffer
+	k8s := newK8SExec()
 
-	k8s, err := k8sexec.NewK8SExec(kubeconfig, namespace)
-	if err != nil {
-		return err
+	if profile != "" {
+		if len(args) > 0 {
+			return errors.New("--profile cannot be combined with a command")
+		}
+		return runProfile(k8s, profile)
+	}
+
+	if golden != "" {
+		if len(args) > 0 {
+			return errors.New("--golden cannot be combined with a command")
+		}
+		return runGolden(k8s, golden)
 	}
 
-	if fi, err := os.Stdin.Stat(); err == nil {
+	//Prepare to capture stdin
+	var stdinBuf bytes.Buffer
+
+	if stdinFile != "" {
+		data, err := os.ReadFile(stdinFile)
+		if err != nil {
+			return err
+		}
+		stdinBuf.Write(data)
+	} else if fi, err := os.Stdin.Stat(); err == nil {
 		if (fi.Mode() & os.ModeCharDevice) == 0 {
 			_, err = io.Copy(&stdinBuf, os.Stdin)
 			if err != nil {
@@ -106,82 +300,322 @@ func run(args []string) error {
 		args = []string{"sh"}
 	}
 
-	enumStatus := NewEnumerationStatus(stdinBuf.String(), args, namespace)
-	switch {
-	case pod != "" && container == "":
-		_pod, err := clientset.CoreV1().Pods(namespace).Get(context.TODO(), pod, metaV1.GetOptions{})
+	targets, err := loadTargets(context.TODO())
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+
+	if dryRun != "" && !simulate {
+		return printTargets(targets)
+	}
+
+	// containers completed by an interrupted sweep are not executed again
+	pending := targets
+	var progress *checkpoint
+	if checkpointPath != "" {
+		progress, err = openCheckpoint(checkpointPath, checkpointHeader(args, stdinBuf.Bytes()), resume)
 		if err != nil {
-			fmt.Println(err.Error())
-			os.Exit(1)
+			return err
 		}
+		defer progress.Close()
+		pending = progress.Remaining(targets)
+	}
 
-		if _pod.Status.Phase == "Running" {
-			for _, _container := range _pod.Spec.Containers {
-				// each execution of command will empty stdin therefore
-				// we need to preserve it and recreate for each iteration
-				streamedCmd := bytes.NewBuffer(stdinBuf.Bytes())
+	opts := execOptions(stdinBuf.Bytes())
+	if annotationCommands == annotationCommandsInstead {
+		opts.Command = annotatedCommand
+	}
+	var blocks *orderedStream
+	switch {
+	case ordered:
+		blocks = newOrderedStream(pending)
+		opts.Output = blocks.Output
+	case stream:
+		opts.Output = streamOutput
+	}
 
-				status := k8s.Exec(_pod.Name, _container.Name, args, streamedCmd)
-				enumStatus.Statuses = append(enumStatus.Statuses, status)
-			}
-		}
-	case pod != "" && container != "":
-		_pod, err := clientset.CoreV1().Pods(namespace).Get(context.TODO(), pod, metaV1.GetOptions{})
+	enumStatus := NewEnumerationStatus(stdinBuf.String(), args, namespace)
+	if includeSpec {
+		enumStatus.Pods = podSnapshots(targets)
+	}
+	var sink *resultSink
+	if resultsPath != "" {
+		sink, err = openResultSink(resultsPath)
 		if err != nil {
-			fmt.Println(err.Error())
-			os.Exit(1)
+			return err
+		}
+		defer sink.Close()
+	}
+	ctx, interrupted := interruptContext()
+	ctx, failures := watchFailures(ctx)
+	opts.Done = func(status *k8sexec.ExecutionStatus) {
+		if progress != nil {
+			progress.Record(status)
 		}
-		if _pod.Status.Phase != "Running" {
-			fmt.Printf("Pod %s is not in Running phase\n", pod)
-			os.Exit(1)
+		if sink != nil {
+			sink.Record(status)
 		}
+		if failures != nil {
+			failures.Record(status)
+		}
+	}
 
-		status := k8s.Exec(pod, container, args, &stdinBuf)
-		enumStatus.Statuses = append(enumStatus.Statuses, status)
-	case pod == "" && container == "":
-		pods, err := k8s.GetPods(metaV1.ListOptions{})
+	var statuses []*k8sexec.ExecutionStatus
+	if precheck {
+		pending, statuses = runPrecheck(ctx, k8s, pending, args[0], opts)
+	}
+	if canary && len(pending) > 1 {
+		status, err := runCanary(ctx, k8s, pending[0], len(pending)-1, args, opts)
 		if err != nil {
-			fmt.Println(err.Error())
-			os.Exit(1)
-		}
-
-		for _, _pod := range pods {
-			if _pod.Status.Phase == "Running" {
-				for _, _container := range _pod.Spec.Containers {
-					// each execution of command will empty stdin therefore
-					// we need to preserve it and recreate for each iteration
-					streamedCmd := bytes.NewBuffer(stdinBuf.Bytes())
-					status := k8s.Exec(_pod.Name, _container.Name, args, streamedCmd)
-					enumStatus.Statuses = append(enumStatus.Statuses, status)
-				}
-			}
+			return err
+		}
+		statuses, pending = append(statuses, status), pending[1:]
+	}
+	statuses = append(statuses, execAllByImage(ctx, k8s, pending, args, opts)...)
+	if progress != nil {
+		enumStatus.Statuses = progress.Merge(targets, statuses)
+	} else {
+		enumStatus.Statuses = statuses
+	}
+	if annotationCommands == annotationCommandsAlso && ctx.Err() == nil {
+		annotatedOpts := opts
+		annotatedOpts.Command = annotatedCommand
+		enumStatus.Statuses = append(enumStatus.Statuses, k8s.ExecOnTargets(ctx, annotatedTargets(targets), nil, annotatedOpts)...)
+	}
+	select {
+	case sig := <-interrupted:
+		enumStatus.RunAborted = abortedRun(sig, enumStatus.Statuses)
+	default:
+		enumStatus.RunAborted = failures.abortedRun(enumStatus.Statuses)
+	}
+	if metrics && !simulate {
+		attachUsage(k8s, enumStatus.Statuses)
+	}
+	if events && !simulate {
+		attachEvents(k8s, enumStatus.Statuses)
+	}
+
+	if blocks != nil {
+		blocks.Flush()
+	}
+	if stream {
+		enumStatus.Statuses = filterStatuses(enumStatus.Statuses)
+		sortStatuses(enumStatus.Statuses)
+		printStreamSummary(enumStatus.Statuses)
+		printIssues(os.Stdout, recordedIssues())
+		if enumStatus.RunAborted != nil {
+			printRunAborted(os.Stdout, enumStatus.RunAborted)
 		}
+	} else if err := printEnumerationStatus(enumStatus); err != nil {
+		return err
 	}
+	switch {
+	case enumStatus.RunAborted == nil:
+		return nil
+	case enumStatus.RunAborted.Failed != "":
+		return ErrFailedFast
+	}
+	return ErrAborted
+}
 
-	switch format {
-	case "json":
-		jsonBuff, err := json.MarshalIndent(enumStatus, "", "    ")
-		if err != nil {
-			fmt.Println(err.Error())
-			os.Exit(1)
-		}
-		fmt.Println(string(jsonBuff))
-	case "text":
-		fmt.Printf("STDIN COMMAND: %s\n", enumStatus.Stdin)
-		fmt.Printf("COMMAND: %q\n\n", enumStatus.Args)
-		fmt.Printf("Namespace: %s\n", enumStatus.Namespace)
-		for _, status := range enumStatus.Statuses {
-			fmt.Printf("CONTAINER: %s/%s\n", status.Pod, status.Container)
-			fmt.Printf("Returned exit code: %d [%s]\n", status.RetCode, k8sexec.GetExitCodeDescription(status.RetCode))
-			if strings.Trim(strings.Join(status.Error, "\n"), "\n") != "" {
-				fmt.Printf("Returned error: %s\n", strings.Join(status.Error, "\n"))
-			}
-			fmt.Printf("Standard output:\n%s", strings.Join(status.Stdout, "\n"))
-			fmt.Printf("Standard error:\n%s", strings.Join(status.Stderr, "\n"))
-			fmt.Println()
+// printStatus prints the outcome of a command in a container in the text
+// format, images are omitted when statuses are grouped by them.
+func printStatus(w io.Writer, status *k8sexec.ExecutionStatus, grouped bool) {
+	fmt.Fprintf(w, "CONTAINER: %s/%s\n", status.Pod, status.Container)
+	if status.Node != "" {
+		fmt.Fprintf(w, "Node: %s\n", status.Node)
+	}
+	if status.Image != "" && !grouped {
+		fmt.Fprintf(w, "Image: %s\n", status.Image)
+	}
+	if status.CachedFrom != "" {
+		fmt.Fprintf(w, "Cached from: %s\n", status.CachedFrom)
+	}
+	if status.Attempts > 1 {
+		fmt.Fprintf(w, "Attempts: %d\n", status.Attempts)
+	}
+	if status.Path != "" {
+		fmt.Fprintf(w, "Resolved path: %s\n", status.Path)
+	}
+	if len(status.Command) > 0 {
+		fmt.Fprintf(w, "Command: %q\n", status.Command)
+	}
+	fmt.Fprintf(w, "Returned exit code: %d [%s]\n", status.RetCode, k8sexec.GetExitCodeDescription(status.RetCode))
+	if status.Usage != nil {
+		fmt.Fprintf(w, "Resource usage: cpu %dm, memory %dMi\n", status.Usage.CPUMillicores, status.Usage.MemoryBytes>>20)
+	}
+	if strings.Trim(status.Error, "\n") != "" {
+		fmt.Fprintf(w, "Returned error [%s]: %s\n", status.ErrorKind, status.Error)
+	}
+	if status.Remediation != "" {
+		fmt.Fprintf(w, "Remediation: %s\n", status.Remediation)
+	}
+	fmt.Fprintf(w, "Standard output:\n%s", status.Stdout)
+	fmt.Fprintf(w, "Standard error:\n%s", status.Stderr)
+	if len(status.Events) > 0 {
+		fmt.Fprintln(w, "Pod events:")
+		for _, event := range status.Events {
+			fmt.Fprintf(w, "  %s %s %s (x%d): %s\n", event.LastSeen.Format(time.RFC3339), event.Type, event.Reason, event.Count, event.Message)
 		}
 	}
+	fmt.Fprintln(w)
+}
 
+// printReport prints report in the format selected with --output.
+func printReport(report any) error {
+	metadata := attachRunMetadata(report)
+	if anonymize {
+		if err := anonymizeReport(report); err != nil {
+			return fmt.Errorf("failed to anonymize the report: %w", err)
+		}
+	}
+
+	var rendered bytes.Buffer
+	if metadata != nil && format == "text" {
+		writeRunMetadata(&rendered, metadata)
+	}
+	if err := output.Write(&rendered, format, report); err != nil {
+		return err
+	}
+	data := rendered.Bytes()
+	if encrypter.Enabled() {
+		var err error
+		if data, err = encrypter.Encrypt(data); err != nil {
+			return fmt.Errorf("failed to encrypt the report: %w", err)
+		}
+	}
+	if _, err := os.Stdout.Write(data); err != nil {
+		return err
+	}
+	// the signature covers the report exactly as printed
+	if signer != nil {
+		return signReport(data)
+	}
+	return nil
+}
+
+func printEnumerationStatus(enumStatus *EnumerationStatus) error {
+	enumStatus.Overrides = recordedOverrides()
+	enumStatus.Skipped = recordedSkips()
+	enumStatus.Issues = recordedIssues()
+	enumStatus.Statuses = filterStatuses(enumStatus.Statuses)
+	sortStatuses(enumStatus.Statuses)
+	attachRemediations(nil, enumStatus.Statuses)
+	for _, result := range enumStatus.Checks {
+		sortStatuses(result.Statuses)
+	}
+	if groupBy == groupByImage {
+		enumStatus.Images = groupByImages(enumStatus.Statuses)
+	}
+	if aggregateBy != "" {
+		enumStatus.Aggregation = aggregate(enumStatus.Statuses, aggregateBy)
+	}
+	if storeInCluster {
+		storeRunSummary(enumStatus)
+	}
+	return printReport(enumStatus)
+}
+
+// WriteText implements output.TextWriter.
+func (s *EnumerationStatus) WriteText(w io.Writer) error {
+	switch {
+	case s.Profile != "":
+		fmt.Fprintf(w, "PROFILE: %s\n\n", s.Profile)
+	case s.Scan != "":
+		fmt.Fprintf(w, "SCAN: %s\n\n", s.Scan)
+	default:
+		fmt.Fprintf(w, "STDIN COMMAND: %s\n", s.Stdin)
+		fmt.Fprintf(w, "COMMAND: %q\n\n", s.Args)
+	}
+	fmt.Fprintf(w, "Namespace: %s\n", s.Namespace)
+	for _, override := range s.Overrides {
+		fmt.Fprintf(w, "OVERRIDE: %s/%s %q: %s\n", override.Pod, override.Container, override.Command, override.Reason)
+	}
+	for _, skip := range s.Skipped {
+		fmt.Fprintf(w, "SKIPPED: %s: %s\n", strings.TrimSuffix(skip.Namespace+"/"+skip.Pod, "/"), skip.Reason)
+	}
+	if len(s.Baseline) > 0 {
+		fmt.Fprintln(w, "BASELINE:")
+		for _, item := range s.Baseline {
+			fmt.Fprintf(w, "IMAGE: %s (%s/%s)\n", item.Image, item.Pod, item.Container)
+			if item.Error != "" {
+				fmt.Fprintf(w, "Error: %s\n", item.Error)
+			} else {
+				printFingerprint(w, item.Fingerprint)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	if len(s.Images) > 0 {
+		for _, group := range s.Images {
+			fmt.Fprintf(w, "IMAGE: %s\n", group.Image)
+			if group.ImageID != "" {
+				fmt.Fprintf(w, "Image ID: %s\n", group.ImageID)
+			}
+			fmt.Fprintf(w, "Containers: %d, failed: %d\n\n", len(group.Statuses), group.Failed)
+			for _, status := range group.Statuses {
+				printStatus(w, status, len(s.Images) > 0)
+			}
+		}
+	} else {
+		for _, status := range s.Statuses {
+			printStatus(w, status, len(s.Images) > 0)
+		}
+	}
+	for _, result := range s.Checks {
+		for _, status := range result.Statuses {
+			if status.Error != "" {
+				fmt.Fprintf(w, "CHECK %s FAILED IN CONTAINER: %s/%s [%s]: %s\n", result.Check, status.Pod, status.Container, status.ErrorKind, status.Error)
+			}
+		}
+	}
+	if s.Aggregation != nil {
+		fmt.Fprintf(w, "AGGREGATED BY %s:\n", strings.ToUpper(s.Aggregation.By))
+		for _, group := range s.Aggregation.Groups {
+			fmt.Fprintf(w, "%s on %d containers", group.Key, group.Containers)
+			if group.Description != "" {
+				fmt.Fprintf(w, " [%s]", group.Description)
+			} else if group.Failed > 0 {
+				fmt.Fprintf(w, ", %d failed", group.Failed)
+			}
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w)
+	}
+	printIssues(w, s.Issues)
+	if s.RunAborted != nil {
+		printRunAborted(w, s.RunAborted)
+	}
+	if s.Profile != "" || s.Scan != "" {
+		fmt.Fprintf(w, "FINDINGS: %d\n", len(s.Findings))
+		for _, finding := range s.Findings {
+			fmt.Fprintf(w, "[%s] %s/%s %s: %s", strings.ToUpper(string(finding.Severity)), finding.Pod, finding.Container, finding.Check, finding.Title)
+			if finding.Detail != "" {
+				fmt.Fprintf(w, " (%s)", finding.Detail)
+			}
+			fmt.Fprintln(w)
+			if finding.Remediation != "" {
+				fmt.Fprintf(w, "  Remediation: %s\n", finding.Remediation)
+			}
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "SUMMARY:")
+		for _, summary := range s.Summary {
+			fmt.Fprintf(w, "%d containers: [%s] %s", summary.Containers, strings.ToUpper(string(summary.Severity)), summary.Title)
+			if summary.Detail != "" {
+				fmt.Fprintf(w, " (%s)", summary.Detail)
+			}
+			fmt.Fprintln(w)
+			if summary.Remediation != "" {
+				fmt.Fprintf(w, "  Remediation: %s\n", summary.Remediation)
+			}
+		}
+	}
+	if s.Posture != nil {
+		fmt.Fprintln(w)
+		printPosture(w, s.Posture)
+	}
 	return nil
 }
 
@@ -189,28 +623,109 @@ var cmd = &cobra.Command{
 	Use:   appName + " [flags] [args]",
 	Short: appName + " is a command line application that executes commands in all containers in a given namespace or in a selected pods",
 	Long:  ``,
+	Args:  cobra.ArbitraryArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		return run(args)
 	},
 }
 
 func init() {
-	if home := homedir.HomeDir(); home != "" {
-		cmd.Flags().StringVarP(&kubeconfig, "kubeconfig", "k", filepath.Join(home, ".kube", "config"), "(optional) absolute path to the kubeconfig file")
-	} else {
-		cmd.Flags().StringVarP(&kubeconfig, "kubeconfig", "k", "", "absolute path to the kubeconfig file")
-	}
+	cmd.PersistentFlags().StringVarP(&kubeconfig, "kubeconfig", "k", "", "(optional) path to the kubeconfig file, by default the files listed in KUBECONFIG merged, or ~/.kube/config")
 
-	cmd.Flags().StringVarP(&namespace, "namespace", "n", "default", "CNF namespace")
-	cmd.Flags().StringVarP(&pod, "pod", "p", "", "a pod name, if not provided then all containers in a namespace will be enumerated.")
-	cmd.Flags().StringVarP(&container, "container", "c", "", "a container name")
-	//cmd.Flags().BoolVarP(&debug, "debug", "d", false, "debug")
+	cmd.PersistentFlags().StringVarP(&namespace, "namespace", "n", "default", "CNF namespace")
+	cmd.PersistentFlags().StringVarP(&pod, "pod", "p", "", "a pod name, if not provided then all containers in a namespace will be enumerated.")
+	cmd.PersistentFlags().StringVarP(&container, "container", "c", "", "a container name")
+	cmd.PersistentFlags().BoolVarP(&debug, "debug", "d", false, "print debug logs, e.g. timings of API calls, to stderr")
 	cmd.Flags().BoolVarP(&version, "version", "v", false, "prints "+appName+" version")
-	cmd.Flags().StringVarP(&format, "output", "o", "text", "Output format: text, or json")
+	cmd.PersistentFlags().StringVarP(&format, "output", "o", "text", "Output format: text, json, or one registered with the output package")
+	cmd.PersistentFlags().IntVar(&parallel, "parallel", 1, "number of containers the command is executed in concurrently")
+	cmd.PersistentFlags().StringVar(&node, "node", "", "only select pods scheduled on the given node")
+	cmd.PersistentFlags().StringVar(&nodeSelector, "node-selector", "", "only select pods scheduled on nodes matching the given label selector")
+	cmd.PersistentFlags().BoolVar(&storeInCluster, "store-in-cluster", false, "store a summary of the run in a ConfigMap in --store-namespace for in-cluster consumers")
+	cmd.PersistentFlags().StringVar(&storeNamespace, "store-namespace", "kubex", "namespace run summaries are stored in")
+	cmd.PersistentFlags().BoolVar(&includeSpec, "include-spec", false, "embed snapshots of security-relevant parts of specs of targeted pods in the JSON report")
+	cmd.PersistentFlags().BoolVar(&plugins, "plugins", false, "with --profile or scan, also run check plugins found in PATH as "+checks.PluginPrefix+"<name> executables")
+	cmd.PersistentFlags().BoolVar(&verifyImages, "verify-images", false, "with --profile or scan, verify cosign signatures of each image and report unsigned ones as findings")
+	cmd.PersistentFlags().StringVar(&verifier.Cosign, "cosign", "cosign", "cosign binary used by --verify-images")
+	cmd.PersistentFlags().StringVar(&verifier.Key, "cosign-key", "", "public key images are verified with")
+	cmd.PersistentFlags().StringVar(&verifier.Identity, "cosign-identity", "", "regular expression of the certificate identity of keyless signatures")
+	cmd.PersistentFlags().StringVar(&verifier.Issuer, "cosign-oidc-issuer", "", "regular expression of the OIDC issuer of keyless signatures")
+	cmd.PersistentFlags().StringVar(&verifier.AttestationType, "cosign-attestation", "", "verify attestations of the given predicate type, e.g. slsaprovenance, instead of signatures")
+	cmd.PersistentFlags().StringVar(&spread, "spread", "", "select at most one pod per node, must be \"node\"")
+	cmd.PersistentFlags().BoolVar(&injectToolbox, "inject-toolbox", false, "inject the static binary of --toolbox, e.g. busybox, into containers without a shell to run commands through it, removed afterwards")
+	cmd.PersistentFlags().StringVar(&toolboxPath, "toolbox", "", "static binary, e.g. busybox, injected with --inject-toolbox; required unless busybox binaries were added to pkg/toolbox/bin when building")
+	cmd.PersistentFlags().StringVar(&workdir, "workdir", "", "absolute directory commands are executed in, requires sh in containers unless a script is piped to a shell")
+	cmd.PersistentFlags().StringArrayVar(&envVars, "env", nil, "set the environment variable KEY=VALUE for executed commands, can be repeated")
+	cmd.Flags().StringVar(&failFast, "fail-fast", "", "abort the sweep on the first container failing with an error or non-zero exit code (\"any\"), or with an error executing the command only (\"error\")")
+	cmd.Flags().BoolVar(&precheck, "precheck", false, "check in parallel that the command exists in all containers first, print its coverage and execute it only where it exists")
+	cmd.Flags().BoolVar(&canary, "canary", false, "execute the command in one container first, print its result and ask for confirmation before executing it in the others")
+	cmd.Flags().IntVar(&canaryExitCode, "canary-exit-code", -1, "with --canary, continue without confirmation when the canary exits with the given code, abort otherwise")
+	cmd.Flags().StringVar(&annotationCommands, "annotation-commands", "", "execute commands pods declare with --command-annotation \"instead\" of the given command or \"also\" after it")
+	cmd.Flags().StringVar(&commandAnnotation, "command-annotation", "kubex.io/command", "annotation of pods declaring a command executed with sh -c in their containers with --annotation-commands")
+	cmd.PersistentFlags().IntVar(&maxPods, "max-pods", 0, "select at most the given number of randomly sampled pods, 0 for no limit")
+	cmd.PersistentFlags().StringVar(&samplePods, "sample", "", "select the given percentage of randomly sampled pods, e.g. 10%")
+	cmd.PersistentFlags().Int64Var(&sampleSeed, "seed", 0, "seed of --max-pods and --sample reproducing a sample, 0 picks a random seed printed to stderr")
+	cmd.PersistentFlags().BoolVar(&uniqueImages, "unique-images", false, "select only one container of each distinct image digest, e.g. for static checks of images")
+	cmd.PersistentFlags().BoolVar(&cacheByImage, "cache-by-image", false, "execute commands introspecting images, e.g. reading os-release or package inventories, in one container of each image digest and report its result for the others")
+	cmd.PersistentFlags().DurationVar(&cacheTTL, "cache-ttl", 0, "reuse listings of pods and workloads cached on disk by earlier invocations for the given time, e.g. 60s during an interactive assessment")
+	cmd.PersistentFlags().StringVar(&remediationsPath, "remediations", "", "YAML file with rules mapping findings and failures of commands to remediations embedded in reports")
+	cmd.PersistentFlags().BoolVar(&posture, "posture", false, "score the security posture of each workload from 0 to 100 by findings of profiles and scans, and summarize it for all of them")
+	cmd.PersistentFlags().StringVar(&postureWeights, "posture-weights", "", "YAML file with weights of checks and points of severities deducted from posture scores, overriding the defaults")
+	cmd.PersistentFlags().StringVar(&targetsFile, "targets-file", "", "JSON array of containers, objects with Namespace, Pod and Container fields, to execute commands in instead of selecting them, e.g. exported from a CMDB")
+	cmd.PersistentFlags().IntVar(&retries, "retries", 0, "number of times commands failing to establish their exec stream, e.g. on transient API server errors, are retried; started commands are never retried")
+	cmd.PersistentFlags().BoolVar(&resolveCommands, "resolve", false, "resolve commands with command -v in each container first, report the absolute path executed and fail containers without the command with CommandNotFound instead of executing it")
+	cmd.PersistentFlags().BoolVar(&strict, "strict", false, "exit with a non-zero status when issues were encountered, e.g. failures to list pods of a namespace or query the metrics API")
+	cmd.PersistentFlags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "select pods of all namespaces, skipping namespaces whose pods cannot be listed or exec'ed into")
+	cmd.PersistentFlags().StringVar(&skipAnnotation, "skip-annotation", "kubex.io/skip", "pods annotated with it set to \"true\" are excluded when all pods of a namespace are selected, empty to include them")
+	cmd.PersistentFlags().DurationVar(&dialTimeout, "dial-timeout", 0, "time establishing the exec stream of each container may take, so that containers on unreachable nodes fail fast with a Timeout error, e.g. 10s")
+	cmd.PersistentFlags().DurationVar(&keepalive, "keepalive", 0, "interval of pings keeping exec streams of long-running commands alive through load balancers dropping idle connections, e.g. 15s, client-go sends them every 5s by default")
+	cmd.PersistentFlags().DurationVar(&remoteTimeout, "remote-timeout", 0, "kill commands still running after the given duration in the container with timeout or busybox timeout when available, e.g. 60s")
+	cmd.PersistentFlags().IntVar(&maxPerNode, "max-per-node", 0, "number of containers on the same node the command is executed in concurrently, 0 for no limit")
+	cmd.Flags().StringVar(&profile, "profile", "", "run built-in checks of a profile instead of a command: "+strings.Join(checks.Profiles(), ", "))
+	cmd.Flags().BoolVar(&stream, "stream", false, "print output lines live as they arrive, prefixed with [pod/container], instead of a report")
+	cmd.Flags().BoolVar(&metrics, "metrics", false, "report current CPU and memory usage of containers from the metrics API")
+	cmd.PersistentFlags().BoolVar(&anonymize, "anonymize", false, "replace names of pods, namespaces, nodes and images in reports with deterministic pseudonyms")
+	cmd.PersistentFlags().StringVar(&anonymizeMap, "anonymize-map", "kubex-anonymize.json", "file mapping pseudonyms of --anonymize to the names they replace, created when it does not exist")
+	cmd.PersistentFlags().StringArrayVar(&encrypter.Recipients, "encrypt", nil, "encrypt the report and --results files with age to the given age or SSH public key or recipients file, can be repeated")
+	cmd.PersistentFlags().StringVar(&encrypter.Age, "age", "age", "age binary used by --encrypt")
+	cmd.PersistentFlags().StringVar(&signKeyPath, "sign", "", "PEM encoded RSA, ECDSA or Ed25519 private key to write a detached signature of the printed report with")
+	cmd.PersistentFlags().StringVar(&signaturePath, "signature", "kubex-report.sig", "file the detached signature of --sign is written to")
+	cmd.PersistentFlags().StringVar(&whereExpr, "where", "", "only report results matching an expression over their fields, e.g. 'RetCode != 0 && Stdout contains \"root\"'")
+	cmd.PersistentFlags().StringVar(&sortBy, "sort", "", "sort results by pod, container, exit-code or duration")
+	cmd.PersistentFlags().BoolVar(&sortReverse, "reverse", false, "with --sort, sort results in descending order")
+	cmd.PersistentFlags().StringVar(&aggregateBy, "aggregate", "", "count results by exit-code, image or workload in an aggregation section")
+	cmd.Flags().StringVar(&groupBy, "group-by", "", "group results by images of containers, must be \"image\"")
+	cmd.Flags().BoolVar(&events, "events", false, "report recent events of pods, e.g. explaining crash-loops or image pull problems")
+	cmd.Flags().BoolVar(&ordered, "ordered", false, "with --stream, print the output of each container as a whole block once it finished, in a stable order")
+	cmd.Flags().StringVar(&resultsPath, "results", "", "write the status of each container as soon as it completes to the given NDJSON file, or to a JSON file per container in the given directory")
+	cmd.Flags().StringVar(&checkpointPath, "checkpoint", "", "record statuses of completed containers to the given file as the sweep progresses")
+	cmd.Flags().BoolVar(&resume, "resume", false, "with --checkpoint, skip containers completed by an interrupted sweep and merge their recorded results")
+	cmd.Flags().StringVar(&golden, "golden", "", "compare files of containers with the expected state described by a golden YAML file instead of running a command")
+	cmd.PersistentFlags().StringVar(&recordDir, "record", "", "directory to record executed commands and their outputs to")
+	cmd.PersistentFlags().StringVar(&replayDir, "replay", "", "directory to replay recorded commands from instead of executing them in a cluster")
+	cmd.PersistentFlags().StringVar(&dryRun, "dry-run", "", "only print containers selected in the cluster, must be \""+dryRunServerSideTargets+"\"")
+	cmd.PersistentFlags().Lookup("dry-run").NoOptDefVal = dryRunServerSideTargets
+	cmd.Flags().Lookup("fail-fast").NoOptDefVal = failFastAny
+	cmd.PersistentFlags().StringVar(&guardPath, "guard", os.Getenv("KUBEX_GUARD"), "YAML file with allow and deny regular expressions restricting commands that may be executed, defaults to $KUBEX_GUARD")
+	cmd.PersistentFlags().BoolVar(&readOnly, "read-only", false, "refuse to run commands which look like they change files, processes or the system, e.g. rm, mv, dd, package installs or redirections")
+	cmd.PersistentFlags().BoolVar(&force, "force", false, "run commands refused by --read-only or the readOnly guard rule, overrides are recorded")
+	cmd.PersistentFlags().BoolVar(&iKnowWhatIAmDoing, "i-know-what-i-am-doing", false, "run commands not on the allow list of the guard, overrides are recorded")
+	cmd.PersistentFlags().StringVar(&auditLog, "audit-log", os.Getenv("KUBEX_AUDIT_LOG"), "file to append an audit entry for every command and guard override to, defaults to $KUBEX_AUDIT_LOG")
+	cmd.PersistentFlags().BoolVar(&simulate, "simulate", false, "with --dry-run, produce a full report with empty outputs instead of executing the command")
+	// used by pods of dispatched Jobs
+	cmd.PersistentFlags().StringVar(&shard, "shard", "", "only select containers of the shard INDEX/COUNT")
+	cmd.Flags().StringVar(&stdinFile, "stdin-file", "", "read stdin of the command from the given file")
+	cmd.PersistentFlags().StringVar(&auditUserName, "audit-user", "", "user named in audit entries instead of the one running kubex")
+	_ = cmd.PersistentFlags().MarkHidden("shard")
+	_ = cmd.PersistentFlags().MarkHidden("audit-user")
+	_ = cmd.Flags().MarkHidden("stdin-file")
 
 	// Disable automatic printing of usage when an error occurs
 	cmd.SilenceUsage = true
 
+	// the completion command would clash with commands executed in containers
+	cmd.CompletionOptions.DisableDefaultCmd = true
+
 	// support for '--'
 	cmd.Flags().SetInterspersed(false)
 
@@ -220,6 +735,7 @@ func init() {
 		if err := cmd.ParseFlags(args); err != nil {
 			return err
 		}
+		startRun(cmd)
 		return nil
 	}
 
@@ -234,5 +750,9 @@ func init() {
 }
 
 func Execute() error {
-	return cmd.Execute()
+	defer removeToolboxes()
+	if err := cmd.Execute(); err != nil {
+		return err
+	}
+	return checkIssues()
 }
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"k8sexec/pkg/k8sexec"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	maxPods    int
+	samplePods string
+	sampleSeed int64
+)
+
+// parseSample parses the --sample percentage of pods, e.g. 10%.
+func parseSample(sample string) (float64, error) {
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(sample, "%"), 64)
+	if err != nil || !strings.HasSuffix(sample, "%") || percent <= 0 || percent > 100 {
+		return 0, fmt.Errorf("invalid --sample value %q, must be a percentage of pods, e.g. 10%%", sample)
+	}
+	return percent, nil
+}
+
+// sampleTargets keeps targets of randomly sampled pods, at most --max-pods
+// of them and --sample percent of all pods, in their order. Samples are
+// reproduced with the same --seed, a random seed is printed to stderr.
+func sampleTargets(targets []k8sexec.Target) []k8sexec.Target {
+	var pods []string
+	seen := map[string]bool{}
+	for _, target := range targets {
+		key := target.Namespace + "/" + target.Pod
+		if !seen[key] {
+			seen[key] = true
+			pods = append(pods, key)
+		}
+	}
+
+	n := len(pods)
+	if samplePods != "" {
+		percent, _ := parseSample(samplePods)
+		n = int(math.Ceil(float64(len(pods)) * percent / 100))
+	}
+	if maxPods > 0 && maxPods < n {
+		n = maxPods
+	}
+	if n >= len(pods) {
+		return targets
+	}
+
+	seed := sampleSeed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+		_, _ = fmt.Fprintf(os.Stderr, "Sampled %d of %d pods, reproduce with --seed %d\n", n, len(pods), seed)
+	}
+	sampled := map[string]bool{}
+	for _, i := range rand.New(rand.NewSource(seed)).Perm(len(pods))[:n] {
+		sampled[pods[i]] = true
+	}
+
+	var kept []k8sexec.Target
+	for _, target := range targets {
+		if sampled[target.Namespace+"/"+target.Pod] {
+			kept = append(kept, target)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"k8sexec/pkg/k8sexec"
+	"k8sexec/pkg/schema"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// SBOMReport holds SBOMs generated for all images.
+type SBOMReport struct {
+	SchemaVersion int           `json:"schemaVersion"`
+	Metadata      *RunMetadata  `json:"metadata,omitempty"`
+	Namespace     string        `json:"Namespace"`
+	Images        []*SBOMResult `json:"Images"`
+}
+
+// SBOMResult is an SBOM generated for an image.
+type SBOMResult struct {
+	Image     string `json:"Image"`
+	Namespace string `json:"Namespace"`
+	Pod       string `json:"Pod"`
+	Container string `json:"Container"`
+	File      string `json:"File,omitempty"`
+	Error     string `json:"Error,omitempty"`
+}
+
+var (
+	sbomScanner     string
+	sbomScannerArgs string
+	sbomOutputDir   string
+)
+
+// writableDirs are tried in order to upload the scanner to.
+var writableDirs = []string{"/tmp", "/var/tmp", "/dev/shm", "/run"}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+var sbomCmd = &cobra.Command{
+	Use:   "sbom",
+	Short: "Generates an SBOM for each image by running an uploaded scanner in one of its containers",
+	Long: `Generates an SBOM for each image by uploading a statically linked SBOM generator,
+e.g. syft, into one container of each image, running it there and saving its output.
+The uploaded binary is removed afterwards.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSBOM()
+	},
+}
+
+func runSBOM() error {
+	if err := validateOptions(); err != nil {
+		return err
+	}
+	if sbomScanner == "" {
+		return fmt.Errorf("--scanner is required")
+	}
+	scanner, err := os.ReadFile(sbomScanner)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(sbomOutputDir, 0o700); err != nil {
+		return err
+	}
+
+	k8s := newK8SExec()
+	targets, err := loadTargets(context.TODO())
+	if err != nil {
+		return err
+	}
+	targets = uniqueImageTargets(targets)
+
+	if dryRun != "" && !simulate {
+		return printTargets(targets)
+	}
+
+	results := make([]*SBOMResult, len(targets))
+	sem := make(chan struct{}, max(parallel, 1))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = generateSBOM(k8s, target, scanner)
+		}()
+	}
+	wg.Wait()
+
+	return printReport(&SBOMReport{SchemaVersion: schema.Version, Namespace: namespace, Images: results})
+}
+
+// WriteText implements output.TextWriter.
+func (r *SBOMReport) WriteText(w io.Writer) error {
+	for _, result := range r.Images {
+		if result.Error != "" {
+			fmt.Fprintf(w, "IMAGE: %s (%s/%s) failed: %s\n", result.Image, result.Pod, result.Container, result.Error)
+		} else {
+			fmt.Fprintf(w, "IMAGE: %s (%s/%s) SBOM: %s\n", result.Image, result.Pod, result.Container, result.File)
+		}
+	}
+	return nil
+}
+
+func generateSBOM(k8s *k8sexec.K8SExec, target k8sexec.Target, scanner []byte) *SBOMResult {
+	result := &SBOMResult{Image: targetImage(target), Namespace: target.Namespace, Pod: target.Pod, Container: target.Container}
+	ctx := context.TODO()
+
+	dir, err := findWritableDir(k8s, target)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	// unique, so that concurrent runs, or containers sharing dir, e.g. on an
+	// emptyDir volume, neither replace nor remove the scanner of each other
+	suffix := make([]byte, 8)
+	_, _ = rand.Read(suffix)
+	path := dir + "/.kubex-sbom-scanner-" + hex.EncodeToString(suffix)
+	if err := k8s.Upload(ctx, target, bytes.NewReader(scanner), path, 0o700); err != nil {
+		result.Error = fmt.Sprintf("failed to upload scanner: %v", err)
+		return result
+	}
+	defer func() {
+		// the scanner is removed even when the sweep is being canceled
+		if err := k8s.Remove(context.Background(), target, path); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "Failed to remove %s from %s/%s: %v\n", path, target.Pod, target.Container, err)
+		}
+	}()
+
+	status := k8s.ExecTarget(ctx, target, append([]string{path}, strings.Fields(sbomScannerArgs)...), nil)
+	if status.Error != "" || status.RetCode != 0 {
+		result.Error = fmt.Sprintf("scanner failed with exit code %d: %s%s", status.RetCode, status.Error, status.Stderr)
+		return result
+	}
+
+	name := result.Image
+	if name == "" {
+		name = target.Pod + "_" + target.Container
+	}
+	result.File = filepath.Join(sbomOutputDir, unsafeFileChars.ReplaceAllString(name, "_")+".json")
+	if err := os.WriteFile(result.File, []byte(status.Stdout), 0o600); err != nil {
+		result.Error = err.Error()
+		result.File = ""
+	}
+	return result
+}
+
+func findWritableDir(k8s *k8sexec.K8SExec, target k8sexec.Target) (string, error) {
+	script := `for dir in "$@"; do if [ -d "$dir" ] && [ -w "$dir" ]; then echo "$dir"; exit 0; fi; done; exit 1`
+	status := k8s.ExecTarget(context.TODO(), target, append([]string{"sh", "-c", script, "sh"}, writableDirs...), nil)
+	if status.Error != "" {
+		return "", status.Err
+	}
+	if status.RetCode != 0 {
+		return "", fmt.Errorf("no writable directory among %v", writableDirs)
+	}
+	return strings.TrimSpace(status.Stdout), nil
+}
+
+func init() {
+	sbomCmd.Flags().StringVar(&sbomScanner, "scanner", "", "statically linked SBOM generator binary to upload, e.g. syft")
+	sbomCmd.Flags().StringVar(&sbomScannerArgs, "scanner-args", "scan dir:/ -o cyclonedx-json -q", "arguments of the scanner making it print the SBOM to standard output")
+	sbomCmd.Flags().StringVar(&sbomOutputDir, "output-dir", "sboms", "directory SBOMs are saved to")
+	cmd.AddCommand(sbomCmd)
+}
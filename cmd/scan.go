@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"k8sexec/pkg/checks"
+	"strings"
+)
+
+// checkArgs overrides default parameters of checks by their names.
+var checkArgs = map[string][]string{}
+
+var scanCmd = &cobra.Command{
+	Use:   "scan",
+	Short: "Runs a single built-in check in all selected containers",
+}
+
+func runScan(check *checks.Check, args []string) error {
+	if err := validateOptions(); err != nil {
+		return err
+	}
+
+	enumStatus := NewEnumerationStatus("", args, namespace)
+	enumStatus.Scan = check.Name
+	if len(args) > 0 {
+		checkArgs[check.Name] = args
+	}
+	return runChecks(newK8SExec(), enumStatus, []*checks.Check{check})
+}
+
+func init() {
+	for _, name := range checks.Names() {
+		check, _ := checks.Get(name)
+		checkCmd := &cobra.Command{
+			Use:   check.Name,
+			Short: "Scans for " + check.Description,
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runScan(check, args)
+			},
+		}
+		if check.ArgsUsage != "" {
+			checkCmd.Use += " " + check.ArgsUsage
+			checkCmd.Args = cobra.ArbitraryArgs
+			checkCmd.Long = fmt.Sprintf("Scans for %s.\n\nDefault parameters: %s", check.Description, strings.Join(check.Args, " "))
+		}
+		scanCmd.AddCommand(checkCmd)
+	}
+
+	cmd.AddCommand(scanCmd)
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/cobra"
+	"k8sexec/pkg/schema"
+	"sort"
+	"strings"
+)
+
+// outputs are types of machine-readable outputs by their names.
+var outputs = map[string]any{
+	"report":    &EnumerationStatus{},
+	"targets":   &TargetsReport{},
+	"inventory": &InventoryReport{},
+	"netcheck":  &NetcheckReport{},
+	"compare":   &CompareReport{},
+	"testspec":  &TestReport{},
+	"bench":     &BenchReport{},
+	"probes":    &ProbesReport{},
+	"sbom":      &SBOMReport{},
+	"pipe":      &PipeReport{},
+	"list":      &ListReport{},
+	"shells":    &ShellsReport{},
+	"utilities": &WhichReport{},
+}
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema [output]",
+	Short: "Prints the JSON Schema of machine-readable outputs",
+	Long: `Prints the JSON Schema of the given machine-readable output, or of all of them,
+so that reports can be validated. Outputs report their schemaVersion, which is
+bumped whenever fields are renamed, removed or change their meaning.
+
+Outputs: ` + strings.Join(outputNames(), ", "),
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runSchema(args)
+	},
+}
+
+func outputNames() []string {
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func runSchema(args []string) error {
+	generator := schema.NewGenerator()
+
+	var document schema.Schema
+	if len(args) == 1 {
+		output, ok := outputs[args[0]]
+		if !ok {
+			return fmt.Errorf("unknown output %q, must be one of: %s", args[0], strings.Join(outputNames(), ", "))
+		}
+		document = generator.Document("kubex "+args[0], generator.Ref(output))
+	} else {
+		var refs []schema.Schema
+		for _, name := range outputNames() {
+			refs = append(refs, generator.Ref(outputs[name]))
+		}
+		document = generator.Document("kubex output", schema.Schema{"oneOf": refs})
+	}
+	document["description"] = fmt.Sprintf("schemaVersion %d", schema.Version)
+
+	jsonBuff, err := json.MarshalIndent(document, "", "    ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(jsonBuff))
+	return nil
+}
+
+func init() {
+	cmd.AddCommand(schemaCmd)
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"github.com/spf13/cobra"
+	"k8sexec/pkg/k8sexec"
+	"k8sexec/pkg/script"
+	"os"
+	"sync"
+	"time"
+)
+
+// kindScript is the error kind of scripts failing at runtime, e.g. using
+// undefined variables.
+const kindScript = "Script"
+
+var scriptCmd = &cobra.Command{
+	Use:   "run-script file",
+	Short: "Runs a script orchestrating commands in each of the selected containers",
+	Long: `Runs a script in each of the selected containers which executes commands depending on
+the output of earlier ones or for each line of their output, e.g. for each path found by
+find. Every command is executed with sh -c, the output of commands and printed texts are
+reported for each container.
+
+  set files = run find /etc -name '*.conf'
+  for file in files {
+      run grep -l password {{file}}
+      if status == "0" {
+          print password found in {{file}}
+      }
+  }
+
+See the documentation of the k8sexec/pkg/script package for the language.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runScript(args[0])
+	},
+}
+
+func runScript(path string) error {
+	if err := validateOptions(); err != nil {
+		return err
+	}
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	parsed, err := script.Parse(string(source))
+	if err != nil {
+		return err
+	}
+
+	k8s := newK8SExec()
+	targets, err := loadTargets(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	if dryRun != "" && !simulate {
+		return printTargets(targets)
+	}
+
+	enumStatus := NewEnumerationStatus("", []string{"run-script", path}, namespace)
+	enumStatus.Statuses = make([]*k8sexec.ExecutionStatus, len(targets))
+	sem := make(chan struct{}, max(parallel, 1))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			enumStatus.Statuses[i] = runTargetScript(k8s, target, parsed)
+		}()
+	}
+	wg.Wait()
+
+	return printEnumerationStatus(enumStatus)
+}
+
+// runTargetScript runs parsed in the container of target. Its status reports
+// the script as a single command, scripts stopped by fail exit with code 1.
+func runTargetScript(k8s *k8sexec.K8SExec, target k8sexec.Target, parsed *script.Script) *k8sexec.ExecutionStatus {
+	image, imageID := targetImageID(target)
+	status := &k8sexec.ExecutionStatus{
+		Version:   k8sexec.StatusVersion,
+		Namespace: target.Namespace,
+		Pod:       target.Pod,
+		Container: target.Container,
+		Node:      targetNode(target),
+		Image:     image,
+		ImageID:   imageID,
+		StartedAt: time.Now(),
+	}
+
+	var execErr error
+	out, err := parsed.Run(context.TODO(), func(ctx context.Context, command string) (script.Result, error) {
+		executed := k8s.ExecTarget(ctx, target, []string{"sh", "-c", command}, nil)
+		var exitErr *k8sexec.ErrNonZeroExit
+		if executed.Err != nil && !errors.As(executed.Err, &exitErr) {
+			execErr = executed.Err
+			return script.Result{}, executed.Err
+		}
+		return script.Result{Stdout: executed.Stdout, Stderr: executed.Stderr, ExitCode: executed.RetCode}, nil
+	})
+
+	status.FinishedAt = time.Now()
+	status.Duration = status.FinishedAt.Sub(status.StartedAt)
+	status.Stdout = out.Stdout.String()
+	status.Stderr = out.Stderr.String()
+
+	var failErr *script.FailError
+	switch {
+	case errors.As(err, &failErr):
+		status.RetCode = 1
+		status.Err = &k8sexec.ErrNonZeroExit{Code: 1}
+		status.ErrorKind = k8sexec.KindNonZeroExit
+		status.Error = failErr.Message
+	case err != nil:
+		status.RetCode = -1
+		status.Err = err
+		status.Error = err.Error()
+		status.ErrorKind = kindScript
+		if err == execErr {
+			status.ErrorKind = k8sexec.ErrorKind(err)
+		}
+	}
+	return status
+}
+
+func init() {
+	cmd.AddCommand(scriptCmd)
+}
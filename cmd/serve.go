@@ -0,0 +1,334 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"k8sexec/pkg/k8sexec"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// States of jobs submitted to serve.
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCanceled  = "canceled"
+)
+
+// Limits of requests submitting jobs.
+const (
+	maxJobRequestBytes = 4 << 20
+	maxJobParallel     = 64
+)
+
+// JobRequest submits a command to be executed in the selected containers.
+type JobRequest struct {
+	Selector TargetSelector `json:"Selector"`
+	// Command is executed in every container, Stdin is streamed to it. The
+	// command defaults to sh when only Stdin is given.
+	Command []string `json:"Command"`
+	Stdin   string   `json:"Stdin,omitempty"`
+	// Parallel overrides --parallel for the job, up to 64 containers.
+	Parallel int `json:"Parallel,omitempty"`
+}
+
+// Job is a command submitted to serve and the progress of its execution.
+type Job struct {
+	ID          string     `json:"ID"`
+	State       string     `json:"State"`
+	Request     JobRequest `json:"Request"`
+	SubmittedAt time.Time  `json:"SubmittedAt"`
+	StartedAt   time.Time  `json:"StartedAt"`
+	FinishedAt  time.Time  `json:"FinishedAt"`
+	Targets     int        `json:"Targets"`
+	Completed   int        `json:"Completed"`
+	Error       string     `json:"Error,omitempty"`
+
+	result *EnumerationStatus
+	cancel context.CancelFunc
+}
+
+var (
+	serveListen    string
+	serveToken     string
+	serveNoToken   bool
+	serveRetention time.Duration
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serves an HTTP API executing commands in containers as jobs",
+	Long: `Serves an HTTP API which lets other tools submit commands to be executed in selected
+containers, poll their progress and fetch their results:
+
+  POST   /jobs               submits a job, e.g. {"Selector": {"Namespace": "my-namespace"}, "Command": ["id"]}
+  GET    /jobs               lists jobs
+  GET    /jobs/{id}          returns the progress of a job
+  GET    /jobs/{id}/results  returns the report of a finished job, as with --output json
+  DELETE /jobs/{id}          cancels a running job and forgets it
+
+Requests must carry the token given with --token as a bearer token, serving without
+one requires --insecure-no-token. Jobs are submitted as application/json and are kept in
+memory until they are deleted, the --retention after they finished passed or the server
+stops. The --guard, --read-only and --audit-log options apply to all jobs.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runServe()
+	},
+}
+
+// jobServer runs jobs submitted over HTTP.
+type jobServer struct {
+	k8s  *k8sexec.K8SExec
+	mu   sync.Mutex
+	jobs map[string]*Job
+	ids  []string
+}
+
+func runServe() error {
+	if err := validateOptions(); err != nil {
+		return err
+	}
+	if replayDir != "" || dryRun != "" {
+		return errors.New("serve cannot be used with --replay or --dry-run")
+	}
+	if serveToken == "" && !serveNoToken {
+		return errors.New("serve requires --token, or --insecure-no-token to accept requests from anyone reaching --listen")
+	}
+
+	server := &jobServer{k8s: newK8SExec(), jobs: map[string]*Job{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /jobs", server.submit)
+	mux.HandleFunc("GET /jobs", server.list)
+	mux.HandleFunc("GET /jobs/{id}", server.get)
+	mux.HandleFunc("GET /jobs/{id}/results", server.results)
+	mux.HandleFunc("DELETE /jobs/{id}", server.delete)
+
+	httpServer := &http.Server{
+		Addr:              serveListen,
+		Handler:           authorize(mux),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       time.Minute,
+		WriteTimeout:      5 * time.Minute,
+		IdleTimeout:       2 * time.Minute,
+	}
+	log.Printf("Serving jobs on %s", serveListen)
+	return httpServer.ListenAndServe()
+}
+
+// authorize rejects requests without the --token bearer token, unless serving
+// without one was allowed by --insecure-no-token.
+func authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serveToken != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+serveToken)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid bearer token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *jobServer) submit(w http.ResponseWriter, r *http.Request) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, errors.New("jobs must be submitted as application/json"))
+		return
+	}
+	var request JobRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxJobRequestBytes)).Decode(&request); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request exceeds %d bytes", tooLarge.Limit))
+			return
+		}
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if request.Parallel < 0 || request.Parallel > maxJobParallel {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("Parallel must be between 0 and %d", maxJobParallel))
+		return
+	}
+	if request.Selector.Namespace == "" {
+		request.Selector.Namespace = namespace
+	}
+	if len(request.Command) == 0 {
+		if request.Stdin == "" {
+			writeError(w, http.StatusBadRequest, errors.New("no command provided either by Command or Stdin"))
+			return
+		}
+		request.Command = []string{"sh"}
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &Job{ID: hex.EncodeToString(id), State: JobPending, Request: request, SubmittedAt: time.Now(), cancel: cancel}
+
+	s.mu.Lock()
+	s.jobs[job.ID] = job
+	s.ids = append(s.ids, job.ID)
+	snapshot := *job
+	s.mu.Unlock()
+
+	go s.run(ctx, job)
+	writeJSON(w, http.StatusAccepted, &snapshot)
+}
+
+// run executes job and records its progress.
+func (s *jobServer) run(ctx context.Context, job *Job) {
+	defer job.cancel()
+
+	s.update(func() {
+		job.State = JobRunning
+		job.StartedAt = time.Now()
+	})
+
+	targets, err := selectTargets(ctx, job.Request.Selector)
+	if err != nil {
+		s.finish(job, func() {
+			job.State = JobFailed
+			job.Error = err.Error()
+		})
+		return
+	}
+	s.update(func() { job.Targets = len(targets) })
+
+	opts := execOptions([]byte(job.Request.Stdin))
+	if job.Request.Parallel > 0 {
+		opts.Parallel = job.Request.Parallel
+	}
+
+	index := make(map[k8sexec.Target]int, len(targets))
+	for i, target := range targets {
+		index[target] = i
+	}
+	result := NewEnumerationStatus(job.Request.Stdin, job.Request.Command, job.Request.Selector.Namespace)
+	result.Statuses = make([]*k8sexec.ExecutionStatus, len(targets))
+	for status := range s.k8s.ExecAsync(ctx, targets, job.Request.Command, opts) {
+		target := k8sexec.Target{Namespace: status.Namespace, Pod: status.Pod, Container: status.Container}
+		result.Statuses[index[target]] = status
+		s.update(func() { job.Completed++ })
+	}
+
+	s.finish(job, func() {
+		job.result = result
+		job.State = JobSucceeded
+		if ctx.Err() != nil {
+			job.State = JobCanceled
+		}
+	})
+}
+
+// finish records the outcome of a job and forgets it after --retention.
+func (s *jobServer) finish(job *Job, change func()) {
+	s.update(func() {
+		change()
+		job.FinishedAt = time.Now()
+	})
+	time.AfterFunc(serveRetention, func() { s.forget(job.ID) })
+}
+
+// forget removes a job from the server.
+func (s *jobServer) forget(jobID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.jobs, jobID)
+	for i, id := range s.ids {
+		if id == jobID {
+			s.ids = append(s.ids[:i], s.ids[i+1:]...)
+			break
+		}
+	}
+}
+
+// update changes a job while holding the lock of the server.
+func (s *jobServer) update(change func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	change()
+}
+
+// job returns a copy of the job of the request, or writes an error.
+func (s *jobServer) job(w http.ResponseWriter, r *http.Request) (*Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job %s not found", r.PathValue("id")))
+		return nil, false
+	}
+	snapshot := *job
+	return &snapshot, true
+}
+
+func (s *jobServer) list(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	jobs := make([]Job, 0, len(s.ids))
+	for _, id := range s.ids {
+		jobs = append(jobs, *s.jobs[id])
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+func (s *jobServer) get(w http.ResponseWriter, r *http.Request) {
+	if job, ok := s.job(w, r); ok {
+		writeJSON(w, http.StatusOK, job)
+	}
+}
+
+func (s *jobServer) results(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.job(w, r)
+	if !ok {
+		return
+	}
+	if job.result == nil {
+		writeError(w, http.StatusConflict, fmt.Errorf("job %s is %s", job.ID, job.State))
+		return
+	}
+	writeJSON(w, http.StatusOK, job.result)
+}
+
+func (s *jobServer) delete(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.job(w, r)
+	if !ok {
+		return
+	}
+	job.cancel()
+	s.forget(job.ID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, code int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"Error": err.Error()})
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveListen, "listen", "127.0.0.1:8080", "address to listen on")
+	serveCmd.Flags().StringVar(&serveToken, "token", os.Getenv("KUBEX_SERVE_TOKEN"), "bearer token required from clients (default $KUBEX_SERVE_TOKEN)")
+	serveCmd.Flags().BoolVar(&serveNoToken, "insecure-no-token", false, "serve without --token, accepting jobs from anyone who can reach --listen")
+	serveCmd.Flags().DurationVar(&serveRetention, "retention", time.Hour, "how long finished jobs are kept for their results")
+	cmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServeSubmitRejects(t *testing.T) {
+	server := &jobServer{jobs: map[string]*Job{}}
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        int
+	}{
+		{"form", "application/x-www-form-urlencoded", `{"Command": ["id"]}`, http.StatusUnsupportedMediaType},
+		{"no content type", "", `{"Command": ["id"]}`, http.StatusUnsupportedMediaType},
+		{"too large", "application/json", `{"Stdin": "` + strings.Repeat("x", maxJobRequestBytes) + `"}`, http.StatusRequestEntityTooLarge},
+		{"parallel", "application/json", `{"Command": ["id"], "Parallel": 1000}`, http.StatusBadRequest},
+		{"negative parallel", "application/json", `{"Command": ["id"], "Parallel": -1}`, http.StatusBadRequest},
+		{"no command", "application/json; charset=utf-8", `{}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				request.Header.Set("Content-Type", tt.contentType)
+			}
+			recorder := httptest.NewRecorder()
+			server.submit(recorder, request)
+			if recorder.Code != tt.want {
+				t.Errorf("status %d, want %d: %s", recorder.Code, tt.want, recorder.Body)
+			}
+		})
+	}
+	if len(server.jobs) != 0 {
+		t.Errorf("rejected requests created jobs: %v", server.jobs)
+	}
+}
+
+func TestServeAuthorize(t *testing.T) {
+	defer func(token string) { serveToken = token }(serveToken)
+	serveToken = "secret"
+	handler := authorize(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for header, want := range map[string]int{"": http.StatusUnauthorized, "Bearer other": http.StatusUnauthorized, "Bearer secret": http.StatusOK} {
+		request := httptest.NewRequest(http.MethodGet, "/jobs", nil)
+		request.Header.Set("Authorization", header)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != want {
+			t.Errorf("Authorization %q: status %d, want %d", header, recorder.Code, want)
+		}
+	}
+}
+
+func TestServeRetention(t *testing.T) {
+	defer func(retention time.Duration) { serveRetention = retention }(serveRetention)
+	serveRetention = time.Millisecond
+	server := &jobServer{jobs: map[string]*Job{}}
+	job := &Job{ID: "done", State: JobRunning}
+	server.jobs[job.ID] = job
+	server.ids = append(server.ids, job.ID)
+
+	server.finish(job, func() { job.State = JobSucceeded })
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		server.mu.Lock()
+		_, kept := server.jobs[job.ID]
+		ids := len(server.ids)
+		server.mu.Unlock()
+		if !kept && ids == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("finished job was not evicted after the retention")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if job.FinishedAt.IsZero() {
+		t.Error("FinishedAt not set")
+	}
+}
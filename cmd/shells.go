@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"k8sexec/pkg/checks"
+	"k8sexec/pkg/k8sexec"
+	"k8sexec/pkg/schema"
+	"strings"
+	"text/tabwriter"
+)
+
+// shellProbes are commands doing nothing, executed directly so that
+// interpreters are found in containers without any shell.
+var shellProbes = []struct {
+	name    string
+	command []string
+}{
+	{"sh", []string{"sh", "-c", "exit 0"}},
+	{"bash", []string{"bash", "-c", "exit 0"}},
+	{"python3", []string{"python3", "-c", "pass"}},
+	{"python", []string{"python", "-c", "pass"}},
+	{"perl", []string{"perl", "-e", "1"}},
+}
+
+// ShellsReport lists shells and interpreters available in containers.
+type ShellsReport struct {
+	SchemaVersion int                `json:"schemaVersion"`
+	Metadata      *RunMetadata       `json:"metadata,omitempty"`
+	Namespace     string             `json:"Namespace"`
+	Shells        []string           `json:"Shells"`
+	Containers    []*ContainerShells `json:"Containers"`
+	Findings      []checks.Finding   `json:"Findings,omitempty"`
+}
+
+// ContainerShells are shells and interpreters available in a container.
+type ContainerShells struct {
+	Namespace string   `json:"Namespace"`
+	Pod       string   `json:"Pod"`
+	Container string   `json:"Container"`
+	Image     string   `json:"Image,omitempty"`
+	Available []string `json:"Available"`
+	// Unknown are shells which could not be probed, e.g. because of
+	// transport errors.
+	Unknown []string `json:"Unknown,omitempty"`
+}
+
+var shellsCmd = &cobra.Command{
+	Use:   "shells",
+	Short: "Reports which shells and interpreters exist in the selected containers",
+	Long: `Reports which shells and interpreters (sh, bash, python, perl) exist in each of the selected
+containers, executing them directly so that containers without a shell are probed as well.
+This tells which containers commands and scripts can be executed in, and every interpreter
+is a finding, as it helps attackers who got into a container.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runShells()
+	},
+}
+
+func runShells() error {
+	if err := validateOptions(); err != nil {
+		return err
+	}
+
+	k8s := newK8SExec()
+	targets, err := loadTargets(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	if dryRun != "" && !simulate {
+		return printTargets(targets)
+	}
+
+	report := &ShellsReport{SchemaVersion: schema.Version, Namespace: namespace}
+	for _, target := range targets {
+		report.Containers = append(report.Containers, &ContainerShells{
+			Namespace: target.Namespace,
+			Pod:       target.Pod,
+			Container: target.Container,
+			Image:     targetImage(target),
+			Available: []string{},
+		})
+	}
+
+	for _, probe := range shellProbes {
+		report.Shells = append(report.Shells, probe.name)
+		statuses := k8s.ExecAll(context.TODO(), targets, probe.command, execOptions(nil))
+		for i, status := range statuses {
+			entry := report.Containers[i]
+			switch {
+			case status.ErrorKind == "":
+				entry.Available = append(entry.Available, probe.name)
+				report.Findings = append(report.Findings, checks.Finding{
+					Check:     "shells",
+					ID:        "interpreter-available",
+					Severity:  checks.SeverityLow,
+					Namespace: entry.Namespace,
+					Pod:       entry.Pod,
+					Container: entry.Container,
+					Title:     "Shell or interpreter available",
+					Detail:    probe.name,
+				})
+			case status.ErrorKind != k8sexec.KindNonZeroExit:
+				entry.Unknown = append(entry.Unknown, probe.name)
+			}
+		}
+	}
+
+	return printReport(report)
+}
+
+// WriteText implements output.TextWriter.
+func (r *ShellsReport) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "Namespace: %s\n", r.Namespace)
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "POD\tCONTAINER\tSHELLS\tUNKNOWN")
+	for _, entry := range r.Containers {
+		available := strings.Join(entry.Available, ",")
+		if available == "" {
+			available = "-"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", entry.Pod, entry.Container, available, strings.Join(entry.Unknown, ","))
+	}
+	return table.Flush()
+}
+
+func init() {
+	cmd.AddCommand(shellsCmd)
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+var (
+	signKeyPath   string
+	signaturePath string
+	// signer signs reports with the --sign key.
+	signer crypto.Signer
+)
+
+// loadSigner loads the private key of --sign, an RSA, ECDSA or Ed25519 key in
+// PEM encoded PKCS#8, PKCS#1 or SEC 1 form.
+func loadSigner() error {
+	if signKeyPath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(signKeyPath)
+	if err != nil {
+		return err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return fmt.Errorf("%s is not a PEM encoded private key", signKeyPath)
+	}
+
+	var key any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return fmt.Errorf("unsupported key type %q in %s", block.Type, signKeyPath)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", signKeyPath, err)
+	}
+	var ok bool
+	if signer, ok = key.(crypto.Signer); !ok {
+		return fmt.Errorf("unsupported key in %s", signKeyPath)
+	}
+	return nil
+}
+
+// signReport writes a detached signature of report to --signature. The
+// signature is raw, i.e. ASN.1 for ECDSA and PKCS #1 v1.5 for RSA over the
+// SHA-256 digest of the report, or Ed25519 over the report itself, so that it
+// can be verified with openssl.
+func signReport(report []byte) error {
+	var signature []byte
+	var err error
+	if _, ok := signer.(ed25519.PrivateKey); ok {
+		signature, err = signer.Sign(rand.Reader, report, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(report)
+		signature, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to sign the report: %w", err)
+	}
+	return os.WriteFile(signaturePath, signature, 0o644)
+}
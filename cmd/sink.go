@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"k8sexec/pkg/encrypt"
+	"k8sexec/pkg/k8sexec"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	// resultsPath is a file or directory statuses are written to as they
+	// complete.
+	resultsPath string
+	// encrypter encrypts the report and results written to resultsPath
+	// with --encrypt.
+	encrypter encrypt.Encrypter
+)
+
+// resultSink writes statuses of containers as soon as they complete, so that
+// they are not lost when kubex crashes before printing the report.
+type resultSink struct {
+	mu sync.Mutex
+	// dir holds a JSON file for each container, file a line of JSON for
+	// each of them otherwise.
+	dir  string
+	file *os.File
+	enc  *json.Encoder
+	// encrypted is the stream of the NDJSON file with --encrypt.
+	encrypted io.WriteCloser
+}
+
+// openResultSink opens the sink at path, a directory when it exists as one
+// or ends with a slash, an NDJSON file that is appended to otherwise. With
+// --encrypt, files are encrypted with age and an NDJSON file is replaced, as
+// encrypted files cannot be appended to.
+func openResultSink(path string) (*resultSink, error) {
+	if fi, err := os.Stat(path); (err == nil && fi.IsDir()) || strings.HasSuffix(path, "/") {
+		if err := os.MkdirAll(path, 0o700); err != nil {
+			return nil, err
+		}
+		return &resultSink{dir: path}, nil
+	}
+
+	if encrypter.Enabled() {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		encrypted, err := encrypter.NewWriter(file)
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+		return &resultSink{file: file, enc: json.NewEncoder(encrypted), encrypted: encrypted}, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &resultSink{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+// Record writes status. Failures are reported on stderr, they do not fail
+// the sweep.
+func (s *resultSink) Record(status *k8sexec.ExecutionStatus) {
+	if err := s.write(status); err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Failed to write the result of %s/%s: %v\n", status.Pod, status.Container, err)
+		recordIssue("write the result", status.Namespace+"/"+status.Pod+"/"+status.Container, err)
+	}
+}
+
+func (s *resultSink) write(status *k8sexec.ExecutionStatus) error {
+	if s.file != nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.enc.Encode(status)
+	}
+
+	data, err := json.MarshalIndent(status, "", "    ")
+	if err != nil {
+		return err
+	}
+	// renamed once complete, so that files are never partially written
+	path := filepath.Join(s.dir, fmt.Sprintf("%s_%s_%s.json", status.Namespace, status.Pod, status.Container))
+	if encrypter.Enabled() {
+		if data, err = encrypter.Encrypt(data); err != nil {
+			return err
+		}
+		path += ".age"
+	}
+	if err := os.WriteFile(path+".tmp", data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// Close closes the NDJSON file of the sink.
+func (s *resultSink) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	if s.encrypted != nil {
+		if err := s.encrypted.Close(); err != nil {
+			_ = s.file.Close()
+			return err
+		}
+	}
+	return s.file.Close()
+}
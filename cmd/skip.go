@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"sync"
+)
+
+// skipAnnotation excludes pods annotated with it set to "true" from
+// enumeration of all pods of a namespace.
+var skipAnnotation string
+
+// Skipped is a pod or namespace excluded from a run.
+type Skipped struct {
+	Namespace string `json:"Namespace"`
+	// Pod is empty for skipped namespaces.
+	Pod    string `json:"Pod,omitempty"`
+	Reason string `json:"Reason"`
+}
+
+var skipped struct {
+	sync.Mutex
+	list []Skipped
+}
+
+func recordSkipped(namespace, pod, reason string) {
+	skipped.Lock()
+	defer skipped.Unlock()
+	skipped.list = append(skipped.list, Skipped{Namespace: namespace, Pod: pod, Reason: reason})
+}
+
+// recordedSkips returns pods and namespaces skipped so far.
+func recordedSkips() []Skipped {
+	skipped.Lock()
+	defer skipped.Unlock()
+	return append([]Skipped(nil), skipped.list...)
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"cmp"
+	"k8sexec/pkg/k8sexec"
+	"slices"
+)
+
+// Keys results are sorted by with --sort.
+var sortKeys = map[string]func(a, b *k8sexec.ExecutionStatus) int{
+	"pod": func(a, b *k8sexec.ExecutionStatus) int {
+		return cmp.Or(cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Pod, b.Pod), cmp.Compare(a.Container, b.Container))
+	},
+	"container": func(a, b *k8sexec.ExecutionStatus) int {
+		return cmp.Or(cmp.Compare(a.Container, b.Container), cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Pod, b.Pod))
+	},
+	"exit-code": func(a, b *k8sexec.ExecutionStatus) int {
+		return cmp.Compare(a.RetCode, b.RetCode)
+	},
+	"duration": func(a, b *k8sexec.ExecutionStatus) int {
+		return cmp.Compare(a.Duration, b.Duration)
+	},
+}
+
+var (
+	sortBy      string
+	sortReverse bool
+)
+
+// sortStatuses sorts statuses by the key selected with --sort, ties keep
+// their order.
+func sortStatuses(statuses []*k8sexec.ExecutionStatus) {
+	compare, ok := sortKeys[sortBy]
+	if !ok {
+		return
+	}
+	slices.SortStableFunc(statuses, func(a, b *k8sexec.ExecutionStatus) int {
+		if sortReverse {
+			return compare(b, a)
+		}
+		return compare(a, b)
+	})
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8sexec/pkg/k8sexec"
+)
+
+// PodSnapshot is a trimmed copy of the spec of a targeted pod, with the parts
+// relevant for offline analysis of its security posture.
+type PodSnapshot struct {
+	Namespace       string                     `json:"Namespace"`
+	Name            string                     `json:"Name"`
+	Node            string                     `json:"Node"`
+	ServiceAccount  string                     `json:"ServiceAccount"`
+	HostNetwork     bool                       `json:"HostNetwork"`
+	HostPID         bool                       `json:"HostPID"`
+	HostIPC         bool                       `json:"HostIPC"`
+	SecurityContext *corev1.PodSecurityContext `json:"SecurityContext,omitempty"`
+	Volumes         []corev1.Volume            `json:"Volumes,omitempty"`
+	Containers      []ContainerSnapshot        `json:"Containers"`
+}
+
+// ContainerSnapshot is a trimmed copy of the spec of a container.
+type ContainerSnapshot struct {
+	Name            string                  `json:"Name"`
+	Image           string                  `json:"Image"`
+	SecurityContext *corev1.SecurityContext `json:"SecurityContext,omitempty"`
+	VolumeMounts    []corev1.VolumeMount    `json:"VolumeMounts,omitempty"`
+}
+
+// podSnapshots returns snapshots of pods of targets, each pod once. Pods
+// which are not known, e.g. when replaying, are skipped.
+func podSnapshots(targets []k8sexec.Target) []*PodSnapshot {
+	seen := map[string]bool{}
+	var snapshots []*PodSnapshot
+	for _, target := range targets {
+		_pod := lookupPod(target)
+		if _pod == nil || seen[_pod.Namespace+"/"+_pod.Name] {
+			continue
+		}
+		seen[_pod.Namespace+"/"+_pod.Name] = true
+
+		snapshot := &PodSnapshot{
+			Namespace:       _pod.Namespace,
+			Name:            _pod.Name,
+			Node:            _pod.Spec.NodeName,
+			ServiceAccount:  _pod.Spec.ServiceAccountName,
+			HostNetwork:     _pod.Spec.HostNetwork,
+			HostPID:         _pod.Spec.HostPID,
+			HostIPC:         _pod.Spec.HostIPC,
+			SecurityContext: _pod.Spec.SecurityContext,
+			Volumes:         _pod.Spec.Volumes,
+		}
+		for _, _container := range _pod.Spec.Containers {
+			snapshot.Containers = append(snapshot.Containers, ContainerSnapshot{
+				Name:            _container.Name,
+				Image:           _container.Image,
+				SecurityContext: _container.SecurityContext,
+				VolumeMounts:    _container.VolumeMounts,
+			})
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8sexec/pkg/checks"
+	"k8sexec/pkg/k8sexec"
+	"k8sexec/pkg/schema"
+	"os"
+	"time"
+)
+
+var (
+	storeInCluster bool
+	storeNamespace string
+)
+
+// Labels of ConfigMaps holding run summaries, so that consumers can find
+// them.
+const (
+	storeManagedByLabel = "app.kubernetes.io/managed-by"
+	storeNamespaceLabel = "kubex.io/namespace"
+	storeKindLabel      = "kubex.io/kind"
+)
+
+// RunSummary summarizes a run stored in the cluster with --store-in-cluster.
+type RunSummary struct {
+	SchemaVersion int                     `json:"schemaVersion"`
+	Namespace     string                  `json:"Namespace"`
+	Args          []string                `json:"Args,omitempty"`
+	Profile       string                  `json:"Profile,omitempty"`
+	Scan          string                  `json:"Scan,omitempty"`
+	StartedAt     time.Time               `json:"StartedAt"`
+	FinishedAt    time.Time               `json:"FinishedAt"`
+	Containers    int                     `json:"Containers"`
+	Failed        int                     `json:"Failed"`
+	ErrorKinds    map[string]int          `json:"ErrorKinds,omitempty"`
+	Findings      map[checks.Severity]int `json:"Findings,omitempty"`
+}
+
+// summarizeRun returns the summary of enumStatus.
+func summarizeRun(enumStatus *EnumerationStatus) *RunSummary {
+	summary := &RunSummary{
+		SchemaVersion: schema.Version,
+		Namespace:     enumStatus.Namespace,
+		Args:          enumStatus.Args,
+		Profile:       enumStatus.Profile,
+		Scan:          enumStatus.Scan,
+		ErrorKinds:    map[string]int{},
+		Findings:      map[checks.Severity]int{},
+	}
+
+	statuses := enumStatus.Statuses
+	for _, result := range enumStatus.Checks {
+		statuses = append(statuses, result.Statuses...)
+	}
+	containers := map[k8sexec.Target]bool{}
+	failed := map[k8sexec.Target]bool{}
+	for _, status := range statuses {
+		target := k8sexec.Target{Namespace: status.Namespace, Pod: status.Pod, Container: status.Container}
+		containers[target] = true
+		if status.ErrorKind != "" {
+			failed[target] = true
+			summary.ErrorKinds[status.ErrorKind]++
+		}
+		if summary.StartedAt.IsZero() || status.StartedAt.Before(summary.StartedAt) {
+			summary.StartedAt = status.StartedAt
+		}
+		if status.FinishedAt.After(summary.FinishedAt) {
+			summary.FinishedAt = status.FinishedAt
+		}
+	}
+	summary.Containers = len(containers)
+	summary.Failed = len(failed)
+
+	for _, finding := range enumStatus.Findings {
+		summary.Findings[finding.Severity]++
+	}
+	return summary
+}
+
+// storeRunSummary writes the summary of enumStatus into a new ConfigMap in
+// --store-namespace. Failures are reported on stderr, they do not fail the
+// run.
+func storeRunSummary(enumStatus *EnumerationStatus) {
+	summary := summarizeRun(enumStatus)
+	data, err := json.MarshalIndent(summary, "", "    ")
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Failed to store the run summary: %v\n", err)
+		return
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metaV1.ObjectMeta{
+			GenerateName: "kubex-run-",
+			Namespace:    storeNamespace,
+			Labels: map[string]string{
+				storeManagedByLabel: "kubex",
+				storeNamespaceLabel: enumStatus.Namespace,
+				storeKindLabel:      "run-summary",
+			},
+		},
+		Data: map[string]string{"summary.json": string(data)},
+	}
+	created, err := clientset.CoreV1().ConfigMaps(storeNamespace).Create(context.TODO(), configMap, metaV1.CreateOptions{})
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "Failed to store the run summary: %v\n", err)
+		return
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "Stored the run summary in ConfigMap %s/%s\n", created.Namespace, created.Name)
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"k8sexec/pkg/k8sexec"
+	"os"
+	"sync"
+)
+
+// podColors are ANSI colors output lines of pods are told apart by.
+var podColors = []string{"31", "32", "33", "34", "35", "36", "91", "92", "93", "94", "95", "96"}
+
+// streamMu serializes lines written by all targets.
+var streamMu sync.Mutex
+
+// prefixWriter writes complete lines to out, each preceded by a prefix.
+type prefixWriter struct {
+	out    io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := indexNewLine(w.buf)
+		if i < 0 {
+			return len(p), nil
+		}
+		if err := w.writeLine(w.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[i+1:]
+	}
+}
+
+// Close writes the last line when it does not end with a new line.
+func (w *prefixWriter) Close() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	line := append(w.buf, '\n')
+	w.buf = nil
+	return w.writeLine(line)
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	streamMu.Lock()
+	defer streamMu.Unlock()
+	_, err := w.out.Write(append([]byte(w.prefix), line...))
+	return err
+}
+
+func indexNewLine(b []byte) int {
+	for i, c := range b {
+		if c == '\n' {
+			return i
+		}
+	}
+	return -1
+}
+
+// streamOutput returns writers printing output of target live, prefixed
+// with [pod/container] and colorized per pod on terminals.
+func streamOutput(target k8sexec.Target) (io.Writer, io.Writer) {
+	prefix := fmt.Sprintf("[%s/%s] ", target.Pod, target.Container)
+	if colorize() {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(target.Namespace + "/" + target.Pod))
+		prefix = fmt.Sprintf("\x1b[%sm%s\x1b[0m", podColors[h.Sum32()%uint32(len(podColors))], prefix)
+	}
+	return &prefixWriter{out: os.Stdout, prefix: prefix}, &prefixWriter{out: os.Stderr, prefix: prefix}
+}
+
+// colorize reports whether standard output is a terminal output may be
+// colored on.
+func colorize() bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	fi, err := os.Stdout.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// printStreamSummary prints the outcome of every execution after its output
+// has been streamed.
+func printStreamSummary(statuses []*k8sexec.ExecutionStatus) {
+	fmt.Println()
+	for _, status := range statuses {
+		fmt.Printf("[%s/%s] exit code %d [%s]", status.Pod, status.Container, status.RetCode, k8sexec.GetExitCodeDescription(status.RetCode))
+		if status.Error != "" {
+			fmt.Printf(" [%s]: %s", status.ErrorKind, status.Error)
+		}
+		fmt.Println()
+	}
+}
+
+// orderedStream buffers output of every target and prints it as a block once
+// the target and all targets before it have finished, so that blocks appear
+// in the order of targets.
+type orderedStream struct {
+	mu      sync.Mutex
+	targets []k8sexec.Target
+	index   map[k8sexec.Target]int
+	blocks  []*outputBlock
+	next    int
+}
+
+// outputBlock is the output of a target, written to stdout and stderr in
+// the order it has been received.
+type outputBlock struct {
+	stream *orderedStream
+	chunks []outputChunk
+	open   int
+}
+
+type outputChunk struct {
+	stderr bool
+	data   []byte
+}
+
+// blockWriter buffers output of one stream of a target in its block.
+type blockWriter struct {
+	block  *outputBlock
+	stderr bool
+}
+
+func newOrderedStream(targets []k8sexec.Target) *orderedStream {
+	s := &orderedStream{targets: targets, index: map[k8sexec.Target]int{}, blocks: make([]*outputBlock, len(targets))}
+	for i, target := range targets {
+		s.index[target] = i
+		s.blocks[i] = &outputBlock{stream: s, open: 2}
+	}
+	return s
+}
+
+// Output can be used as k8sexec.ExecOptions.Output.
+func (s *orderedStream) Output(target k8sexec.Target) (io.Writer, io.Writer) {
+	i, ok := s.index[target]
+	if !ok {
+		return streamOutput(target)
+	}
+	block := s.blocks[i]
+	return &blockWriter{block: block}, &blockWriter{block: block, stderr: true}
+}
+
+func (w *blockWriter) Write(p []byte) (int, error) {
+	w.block.stream.mu.Lock()
+	defer w.block.stream.mu.Unlock()
+	w.block.chunks = append(w.block.chunks, outputChunk{stderr: w.stderr, data: append([]byte(nil), p...)})
+	return len(p), nil
+}
+
+// Close marks the stream as finished and prints blocks which are complete.
+func (w *blockWriter) Close() error {
+	s := w.block.stream
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.block.open--
+	for s.next < len(s.blocks) && s.blocks[s.next].open == 0 {
+		s.print(s.next)
+		s.next++
+	}
+	return nil
+}
+
+// Flush prints blocks not printed yet, e.g. of targets which have not been
+// executed.
+func (s *orderedStream) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ; s.next < len(s.blocks); s.next++ {
+		s.print(s.next)
+	}
+}
+
+func (s *orderedStream) print(i int) {
+	stdout, stderr := streamOutput(s.targets[i])
+	for _, chunk := range s.blocks[i].chunks {
+		if chunk.stderr {
+			_, _ = stderr.Write(chunk.data)
+		} else {
+			_, _ = stdout.Write(chunk.data)
+		}
+	}
+	_ = stdout.(io.Closer).Close()
+	_ = stderr.(io.Closer).Close()
+	s.blocks[i].chunks = nil
+}
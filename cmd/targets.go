@@ -0,0 +1,299 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8sexec/pkg/k8sexec"
+	"os"
+	"sync"
+	"time"
+)
+
+// archLabel is the label of nodes holding their architecture.
+const archLabel = "kubernetes.io/arch"
+
+// nodeArchs caches architectures of nodes by their names, an empty string
+// when they could not be read.
+var nodeArchs = struct {
+	sync.Mutex
+	archs map[string]string
+}{archs: map[string]string{}}
+
+// resolvedPods holds pods of targets returned by resolveTargets by namespace and name.
+var resolvedPods = struct {
+	sync.RWMutex
+	pods map[string]*corev1.Pod
+}{pods: map[string]*corev1.Pod{}}
+
+// TargetSelector selects containers commands are executed in.
+type TargetSelector struct {
+	Namespace string `json:"Namespace"`
+	// Pod and Container select a single pod or container, all running pods
+	// of Namespace are selected when Pod is empty.
+	Pod       string `json:"Pod,omitempty"`
+	Container string `json:"Container,omitempty"`
+	// LabelSelector restricts pods of Namespace to those matching it.
+	LabelSelector string `json:"LabelSelector,omitempty"`
+	// Node and NodeSelector restrict pods to those scheduled on the node or
+	// on nodes matching the label selector.
+	Node         string `json:"Node,omitempty"`
+	NodeSelector string `json:"NodeSelector,omitempty"`
+}
+
+// selectorResolver is a k8sexec.PodResolver resolving containers selected by
+// selector in its namespace, or in all namespaces when all is set.
+type selectorResolver struct {
+	selector TargetSelector
+	all      bool
+}
+
+// Resolve implements k8sexec.PodResolver.
+func (r selectorResolver) Resolve(ctx context.Context) ([]k8sexec.Target, error) {
+	if r.all {
+		return selectAllNamespaces(ctx, r.selector)
+	}
+	return selectTargets(ctx, r.selector)
+}
+
+// podResolver returns the resolver of the containers selected by the
+// --replay, --targets-file, --pod and --container options.
+func podResolver() (k8sexec.PodResolver, error) {
+	switch {
+	case replayDir != "":
+		targets, err := k8sexec.LoadTargets(replayDir)
+		return k8sexec.Targets(targets), err
+	case targetsFile != "":
+		return readTargetsFile(targetsFile)
+	}
+	selector := TargetSelector{Namespace: namespace, Pod: pod, Container: container, Node: node, NodeSelector: nodeSelector}
+	return selectorResolver{selector: selector, all: allNamespaces}, nil
+}
+
+// readTargetsFile reads pre-computed targets from a JSON array of objects
+// with Namespace, Pod and Container fields, e.g. containers of list
+// containers -o json or an export of a CMDB. Targets without a namespace are
+// in the namespace of --namespace.
+func readTargetsFile(path string) (k8sexec.Targets, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var targets k8sexec.Targets
+	if err := json.Unmarshal(data, &targets); err != nil {
+		return nil, fmt.Errorf("failed to parse targets of %s: %w", path, err)
+	}
+	for i := range targets {
+		targets[i].Namespace = cmp.Or(targets[i].Namespace, namespace)
+		if targets[i].Pod == "" || targets[i].Container == "" {
+			return nil, fmt.Errorf("target %d of %s has no pod or container", i, path)
+		}
+	}
+	return targets, nil
+}
+
+// selectTargets returns running containers selected by selector.
+func selectTargets(ctx context.Context, selector TargetSelector) ([]k8sexec.Target, error) {
+	var targets []k8sexec.Target
+
+	nodes, err := selectedNodes(ctx, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case selector.Pod != "" && selector.Container == "":
+		_pod, err := clientset.CoreV1().Pods(selector.Namespace).Get(ctx, selector.Pod, metaV1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		if _pod.Status.Phase == corev1.PodRunning && onSelectedNode(_pod, nodes) {
+			targets = append(targets, podTargets(_pod)...)
+		}
+	case selector.Pod != "" && selector.Container != "":
+		_pod, err := clientset.CoreV1().Pods(selector.Namespace).Get(ctx, selector.Pod, metaV1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		if _pod.Status.Phase != corev1.PodRunning {
+			return nil, fmt.Errorf("pod %s is not in Running phase", selector.Pod)
+		}
+		if !onSelectedNode(_pod, nodes) {
+			return nil, fmt.Errorf("pod %s is not scheduled on a selected node", selector.Pod)
+		}
+
+		rememberPod(_pod)
+		targets = append(targets, k8sexec.Target{Namespace: selector.Namespace, Pod: selector.Pod, Container: selector.Container})
+	case selector.Pod == "" && selector.Container == "":
+		listOptions := metaV1.ListOptions{LabelSelector: selector.LabelSelector}
+		if selector.Node != "" {
+			listOptions.FieldSelector = "spec.nodeName=" + selector.Node
+		}
+		started := time.Now()
+		pods, err := cached("pods/"+selector.Namespace+"?"+listOptions.LabelSelector+"&"+listOptions.FieldSelector, func() (*corev1.PodList, error) {
+			return clientset.CoreV1().Pods(selector.Namespace).List(ctx, listOptions)
+		})
+		if err != nil {
+			return nil, err
+		}
+		debugf("listed %d pods of namespace %s in %s", len(pods.Items), selector.Namespace, time.Since(started).Round(time.Millisecond))
+
+		for i := range pods.Items {
+			if pods.Items[i].Status.Phase != corev1.PodRunning || !onSelectedNode(&pods.Items[i], nodes) {
+				continue
+			}
+			if skipAnnotation != "" && pods.Items[i].Annotations[skipAnnotation] == "true" {
+				recordSkipped(pods.Items[i].Namespace, pods.Items[i].Name, "skipped by annotation "+skipAnnotation)
+				continue
+			}
+			targets = append(targets, podTargets(&pods.Items[i])...)
+		}
+	}
+
+	return targets, nil
+}
+
+// selectedNodes returns names of nodes selected by Node and NodeSelector of
+// selector, nil when all nodes are selected.
+func selectedNodes(ctx context.Context, selector TargetSelector) (map[string]bool, error) {
+	if selector.Node == "" && selector.NodeSelector == "" {
+		return nil, nil
+	}
+
+	nodes := map[string]bool{}
+	if selector.NodeSelector == "" {
+		nodes[selector.Node] = true
+		return nodes, nil
+	}
+
+	list, err := clientset.CoreV1().Nodes().List(ctx, metaV1.ListOptions{LabelSelector: selector.NodeSelector})
+	if err != nil {
+		return nil, err
+	}
+	for _, _node := range list.Items {
+		if selector.Node == "" || _node.Name == selector.Node {
+			nodes[_node.Name] = true
+		}
+	}
+	return nodes, nil
+}
+
+// onSelectedNode reports whether pod is scheduled on one of nodes, a nil
+// nodes selects all of them.
+func onSelectedNode(pod *corev1.Pod, nodes map[string]bool) bool {
+	return nodes == nil || nodes[pod.Spec.NodeName]
+}
+
+// rememberPod makes pod available to lookupPod.
+func rememberPod(pod *corev1.Pod) {
+	resolvedPods.Lock()
+	defer resolvedPods.Unlock()
+	resolvedPods.pods[pod.Namespace+"/"+pod.Name] = pod
+}
+
+func podTargets(pod *corev1.Pod) []k8sexec.Target {
+	rememberPod(pod)
+	targets := make([]k8sexec.Target, 0, len(pod.Spec.Containers))
+	for _, _container := range pod.Spec.Containers {
+		targets = append(targets, k8sexec.Target{Namespace: pod.Namespace, Pod: pod.Name, Container: _container.Name})
+	}
+	return targets
+}
+
+// lookupPod returns the pod of a target returned by resolveTargets.
+func lookupPod(target k8sexec.Target) *corev1.Pod {
+	resolvedPods.RLock()
+	defer resolvedPods.RUnlock()
+	return resolvedPods.pods[target.Namespace+"/"+target.Pod]
+}
+
+// targetImage returns the image of the container of target, an empty string
+// when its pod is not known.
+func targetImage(target k8sexec.Target) string {
+	image, _ := targetImageID(target)
+	return image
+}
+
+// targetImageID returns the image of the container of target and the image
+// ID it resolved to, empty strings when its pod is not known.
+func targetImageID(target k8sexec.Target) (string, string) {
+	return k8sexec.ContainerImage(lookupPod(target), target.Container)
+}
+
+// targetNode returns the name of the node the pod of target is scheduled on,
+// an empty string when its pod is not known.
+func targetNode(target k8sexec.Target) string {
+	if _pod := lookupPod(target); _pod != nil {
+		return _pod.Spec.NodeName
+	}
+	return ""
+}
+
+// targetArch returns the architecture of the node the pod of target is
+// scheduled on, from its kubernetes.io/arch label or the node selector of the
+// pod, an empty string when not known.
+func targetArch(target k8sexec.Target) string {
+	_pod := lookupPod(target)
+	if _pod == nil {
+		return ""
+	}
+	if arch := _pod.Spec.NodeSelector[archLabel]; arch != "" {
+		return arch
+	}
+	if _pod.Spec.NodeName == "" || clientset == nil {
+		return ""
+	}
+
+	nodeArchs.Lock()
+	defer nodeArchs.Unlock()
+	arch, ok := nodeArchs.archs[_pod.Spec.NodeName]
+	if !ok {
+		// nodes may not be readable, the architecture is probed then
+		if _node, err := clientset.CoreV1().Nodes().Get(context.TODO(), _pod.Spec.NodeName, metaV1.GetOptions{}); err == nil {
+			arch = _node.Labels[archLabel]
+		}
+		nodeArchs.archs[_pod.Spec.NodeName] = arch
+	}
+	return arch
+}
+
+// spreadNodeTargets keeps only targets of the first pod on each node. Targets
+// on unknown nodes are all kept.
+func spreadNodeTargets(targets []k8sexec.Target) []k8sexec.Target {
+	podOfNode := map[string]string{}
+	var spread []k8sexec.Target
+	for _, target := range targets {
+		node := targetNode(target)
+		key := target.Namespace + "/" + target.Pod
+		if node != "" {
+			if selected, ok := podOfNode[node]; ok && selected != key {
+				continue
+			}
+			podOfNode[node] = key
+		}
+		spread = append(spread, target)
+	}
+	return spread
+}
+
+// uniqueImageTargets keeps only the first target of each image digest, or of
+// each image reference when the digest is not known yet. Targets with unknown
+// images are all kept.
+func uniqueImageTargets(targets []k8sexec.Target) []k8sexec.Target {
+	seen := map[string]bool{}
+	var unique []k8sexec.Target
+	for _, target := range targets {
+		image, imageID := targetImageID(target)
+		key := cmp.Or(imageID, image)
+		if key != "" && seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, target)
+	}
+	return unique
+}
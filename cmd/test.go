@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"k8sexec/pkg/k8sexec"
+	"k8sexec/pkg/schema"
+	"k8sexec/pkg/testspec"
+	"strings"
+)
+
+// TestReport holds results of the tests of a spec.
+type TestReport struct {
+	SchemaVersion int           `json:"schemaVersion"`
+	Metadata      *RunMetadata  `json:"metadata,omitempty"`
+	Namespace     string        `json:"Namespace"`
+	Spec          string        `json:"Spec"`
+	Passed        int           `json:"Passed"`
+	Failed        int           `json:"Failed"`
+	Results       []*TestResult `json:"Results"`
+}
+
+// TestResult is the result of a test in a container.
+type TestResult struct {
+	Test      string                   `json:"Test"`
+	Namespace string                   `json:"Namespace"`
+	Pod       string                   `json:"Pod"`
+	Container string                   `json:"Container"`
+	Passed    bool                     `json:"Passed"`
+	Failures  []string                 `json:"Failures,omitempty"`
+	Status    *k8sexec.ExecutionStatus `json:"Status"`
+}
+
+var testCmd = &cobra.Command{
+	Use:   "testspec spec.yaml",
+	Short: "Runs tests of a spec in the selected containers and reports them as pass or fail",
+	Long: `Runs commands declared by tests of a YAML spec in the selected containers matching their
+selectors and verifies their exit codes and outputs. The exit status is non-zero when a test fails.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runTest(args[0])
+	},
+}
+
+func runTest(path string) error {
+	if err := validateOptions(); err != nil {
+		return err
+	}
+	spec, err := testspec.Load(path)
+	if err != nil {
+		return err
+	}
+
+	k8s := newK8SExec()
+	targets, err := loadTargets(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	if dryRun != "" && !simulate {
+		return printTargets(targets)
+	}
+
+	report := &TestReport{SchemaVersion: schema.Version, Namespace: namespace, Spec: path}
+	for _, test := range spec.Tests {
+		var selected []k8sexec.Target
+		for _, target := range targets {
+			var labels map[string]string
+			if _pod := lookupPod(target); _pod != nil {
+				labels = _pod.Labels
+			}
+			if test.Selector.Matches(target, targetImage(target), labels) {
+				selected = append(selected, target)
+			}
+		}
+
+		statuses := k8s.ExecAll(context.TODO(), selected, test.Command, execOptions([]byte(test.Script)))
+		for _, status := range statuses {
+			result := &TestResult{
+				Test:      test.Name,
+				Namespace: status.Namespace,
+				Pod:       status.Pod,
+				Container: status.Container,
+				Failures:  test.Expect.Evaluate(status),
+				Status:    status,
+			}
+			result.Passed = len(result.Failures) == 0
+			if result.Passed {
+				report.Passed++
+			} else {
+				report.Failed++
+			}
+			report.Results = append(report.Results, result)
+		}
+	}
+
+	if err := printReport(report); err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d tests failed", report.Failed, report.Passed+report.Failed)
+	}
+	return nil
+}
+
+// WriteText implements output.TextWriter.
+func (r *TestReport) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "TEST: %s\n", r.Spec)
+	fmt.Fprintf(w, "Namespace: %s\n", r.Namespace)
+	for _, result := range r.Results {
+		if result.Passed {
+			fmt.Fprintf(w, "PASS %s %s/%s\n", result.Test, result.Pod, result.Container)
+		} else {
+			fmt.Fprintf(w, "FAIL %s %s/%s: %s\n", result.Test, result.Pod, result.Container, strings.Join(result.Failures, "; "))
+		}
+	}
+	fmt.Fprintf(w, "\n%d passed, %d failed\n", r.Passed, r.Failed)
+	return nil
+}
+
+func init() {
+	cmd.AddCommand(testCmd)
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"k8sexec/pkg/k8sexec"
+	"k8sexec/pkg/toolbox"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	injectToolbox bool
+	toolboxPath   string
+)
+
+// toolboxExecutor injects toolboxes with --inject-toolbox, they are removed
+// when kubex exits.
+var toolboxExecutor *k8sexec.ToolboxExecutor
+
+// toolboxBinary returns the binary injected into target, the one given with
+// --toolbox or the embedded busybox built for its architecture.
+func toolboxBinary(target k8sexec.Target) ([]byte, error) {
+	if toolboxPath != "" {
+		return os.ReadFile(toolboxPath)
+	}
+	arch := targetArch(target)
+	if binary, ok := toolbox.Busybox(arch); ok {
+		return binary, nil
+	}
+	if arch == "" {
+		return nil, fmt.Errorf("%w: architecture of the container is unknown, use --toolbox", k8sexec.ErrUnsupported)
+	}
+	return nil, fmt.Errorf("%w: no busybox is embedded for %s, only for %s, use --toolbox", k8sexec.ErrUnsupported, arch, strings.Join(toolbox.Architectures(), ", "))
+}
+
+// removeToolboxes removes injected toolboxes from all containers.
+func removeToolboxes() {
+	if toolboxExecutor == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	for _, err := range toolboxExecutor.Cleanup(ctx) {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+	}
+}
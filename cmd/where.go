@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"k8sexec/pkg/k8sexec"
+	"k8sexec/pkg/where"
+	"reflect"
+)
+
+var (
+	whereExpr   string
+	whereFilter *where.Expr
+)
+
+// parseWhere parses the --where expression.
+func parseWhere() error {
+	if whereExpr == "" {
+		return nil
+	}
+	var err error
+	if whereFilter, err = where.Parse(whereExpr, reflect.TypeOf(k8sexec.ExecutionStatus{})); err != nil {
+		return fmt.Errorf("--where: %w", err)
+	}
+	return nil
+}
+
+// filterStatuses returns statuses matching the --where expression, all of
+// them without one.
+func filterStatuses(statuses []*k8sexec.ExecutionStatus) []*k8sexec.ExecutionStatus {
+	if whereFilter == nil {
+		return statuses
+	}
+	var matching []*k8sexec.ExecutionStatus
+	for _, status := range statuses {
+		if whereFilter.Match(status) {
+			matching = append(matching, status)
+		}
+	}
+	return matching
+}
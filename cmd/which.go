@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"k8sexec/pkg/schema"
+	"strings"
+	"text/tabwriter"
+)
+
+// whichScript prints the path of each of its arguments found in PATH.
+const whichScript = `for util; do path=$(command -v "$util" 2>/dev/null) && printf '%s\t%s\n' "$util" "$path"; done; exit 0`
+
+// WhichReport is a matrix of utilities available in containers.
+type WhichReport struct {
+	SchemaVersion int                   `json:"schemaVersion"`
+	Metadata      *RunMetadata          `json:"metadata,omitempty"`
+	Namespace     string                `json:"Namespace"`
+	Utilities     []string              `json:"Utilities"`
+	Containers    []*ContainerUtilities `json:"Containers"`
+}
+
+// ContainerUtilities are paths of utilities found in a container.
+type ContainerUtilities struct {
+	Namespace string `json:"Namespace"`
+	Pod       string `json:"Pod"`
+	Container string `json:"Container"`
+	// Paths holds paths of found utilities by their names.
+	Paths map[string]string `json:"Paths"`
+	// Error is set when utilities could not be probed, e.g. in containers
+	// without a shell.
+	Error string `json:"Error,omitempty"`
+}
+
+var whichCmd = &cobra.Command{
+	Use:   "utilities utility...",
+	Short: "Reports which of the given utilities exist in the selected containers",
+	Long: `Looks up all given utilities in PATH of each of the selected containers in a single pass
+and prints a matrix of containers and utilities. Containers need sh, see the shells command
+for containers without one.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runWhich(args)
+	},
+}
+
+func runWhich(utilities []string) error {
+	if err := validateOptions(); err != nil {
+		return err
+	}
+
+	k8s := newK8SExec()
+	targets, err := loadTargets(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	if dryRun != "" && !simulate {
+		return printTargets(targets)
+	}
+
+	report := &WhichReport{SchemaVersion: schema.Version, Namespace: namespace, Utilities: utilities}
+	command := append([]string{"sh", "-c", whichScript, "sh"}, utilities...)
+	for _, status := range k8s.ExecAll(context.TODO(), targets, command, execOptions(nil)) {
+		entry := &ContainerUtilities{Namespace: status.Namespace, Pod: status.Pod, Container: status.Container, Paths: map[string]string{}}
+		if status.ErrorKind != "" {
+			entry.Error = fmt.Sprintf("[%s] %s", status.ErrorKind, status.Error)
+		}
+		for _, line := range strings.Split(status.Stdout, "\n") {
+			if util, path, ok := strings.Cut(line, "\t"); ok {
+				entry.Paths[util] = path
+			}
+		}
+		report.Containers = append(report.Containers, entry)
+	}
+
+	return printReport(report)
+}
+
+// WriteText implements output.TextWriter.
+func (r *WhichReport) WriteText(w io.Writer) error {
+	fmt.Fprintf(w, "Namespace: %s\n", r.Namespace)
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprint(table, "POD\tCONTAINER")
+	for _, util := range r.Utilities {
+		fmt.Fprintf(table, "\t%s", util)
+	}
+	fmt.Fprintln(table)
+	for _, entry := range r.Containers {
+		fmt.Fprintf(table, "%s\t%s", entry.Pod, entry.Container)
+		for _, util := range r.Utilities {
+			switch {
+			case entry.Error != "":
+				fmt.Fprint(table, "\t?")
+			case entry.Paths[util] != "":
+				fmt.Fprint(table, "\tyes")
+			default:
+				fmt.Fprint(table, "\t-")
+			}
+		}
+		fmt.Fprintln(table)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	for _, entry := range r.Containers {
+		if entry.Error != "" {
+			fmt.Fprintf(w, "%s/%s: %s\n", entry.Pod, entry.Container, entry.Error)
+		}
+	}
+	return nil
+}
+
+func init() {
+	cmd.AddCommand(whichCmd)
+}
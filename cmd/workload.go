@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"context"
+	corev1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8sexec/pkg/k8sexec"
+	"sync"
+	"time"
+)
+
+// Workload is the top-level controller of a pod, e.g. a Deployment.
+type Workload struct {
+	Namespace string `json:"Namespace"`
+	Kind      string `json:"Kind"`
+	Name      string `json:"Name"`
+	// UID tells workloads replaced by ones of the same name apart.
+	UID string `json:"UID,omitempty"`
+}
+
+// String returns the workload as namespace/Kind/name.
+func (w Workload) String() string {
+	return w.Namespace + "/" + w.Kind + "/" + w.Name
+}
+
+// workloadOwners caches controllers of ReplicaSets and Jobs by their UIDs, so
+// that pods of a ReplicaSet or Job replaced by one of the same name are not
+// attributed to the controller of the new one.
+var workloadOwners = struct {
+	sync.Mutex
+	owners map[string]*metaV1.OwnerReference
+}{owners: map[string]*metaV1.OwnerReference{}}
+
+// podWorkload returns the workload of pod: the controller of its ReplicaSet
+// or Job when it has one, e.g. a Deployment or CronJob, its own controller
+// otherwise, or the pod itself when it is not controlled.
+func podWorkload(ctx context.Context, pod *corev1.Pod) Workload {
+	owner := metaV1.GetControllerOf(pod)
+	if owner == nil {
+		return Workload{Namespace: pod.Namespace, Kind: "Pod", Name: pod.Name, UID: string(pod.UID)}
+	}
+	if owner.Kind == "ReplicaSet" || owner.Kind == "Job" {
+		if parent := ownerController(ctx, pod.Namespace, owner); parent != nil {
+			owner = parent
+		}
+	}
+	return Workload{Namespace: pod.Namespace, Kind: owner.Kind, Name: owner.Name, UID: string(owner.UID)}
+}
+
+// ownerController returns the controller of the ReplicaSet or Job owner, nil
+// when it has none, cannot be read or has been replaced by one of the same
+// name.
+func ownerController(ctx context.Context, namespace string, owner *metaV1.OwnerReference) *metaV1.OwnerReference {
+	key := string(owner.UID)
+	workloadOwners.Lock()
+	parent, ok := workloadOwners.owners[key]
+	workloadOwners.Unlock()
+	if ok {
+		return parent
+	}
+
+	parent, err := cached("owner/"+namespace+"/"+owner.Kind+"/"+owner.Name+"/"+key, func() (*metaV1.OwnerReference, error) {
+		var object metaV1.Object
+		var err error
+		switch owner.Kind {
+		case "ReplicaSet":
+			object, err = clientset.AppsV1().ReplicaSets(namespace).Get(ctx, owner.Name, metaV1.GetOptions{})
+		case "Job":
+			object, err = clientset.BatchV1().Jobs(namespace).Get(ctx, owner.Name, metaV1.GetOptions{})
+		}
+		if err != nil {
+			recordIssue("read the controller of "+owner.Kind, namespace+"/"+owner.Name, err)
+		}
+		if err != nil || object == nil {
+			return nil, err
+		}
+		if object.GetUID() != owner.UID {
+			return nil, nil
+		}
+		return metaV1.GetControllerOfNoCopy(object), nil
+	})
+	if err != nil {
+		// failures are not cached
+		return nil
+	}
+	workloadOwners.Lock()
+	defer workloadOwners.Unlock()
+	workloadOwners.owners[key] = parent
+	return parent
+}
+
+// resolveWorkloads reads the controllers of ReplicaSets and Jobs owning pods
+// of targets with one list call each per namespace, concurrently, instead of
+// reading them one by one when workloads of pods are looked up.
+func resolveWorkloads(ctx context.Context, targets []k8sexec.Target) {
+	started := time.Now()
+	namespaces := map[string]bool{}
+	pods := map[string]bool{}
+	workloadOwners.Lock()
+	for _, target := range targets {
+		_pod := lookupPod(target)
+		if _pod == nil || pods[_pod.Namespace+"/"+_pod.Name] {
+			continue
+		}
+		pods[_pod.Namespace+"/"+_pod.Name] = true
+		if owner := metaV1.GetControllerOf(_pod); owner != nil && (owner.Kind == "ReplicaSet" || owner.Kind == "Job") {
+			if _, ok := workloadOwners.owners[string(owner.UID)]; !ok {
+				namespaces[_pod.Namespace] = true
+			}
+		}
+	}
+	workloadOwners.Unlock()
+
+	sem := make(chan struct{}, max(parallel, 1))
+	var wg sync.WaitGroup
+	for _namespace := range namespaces {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			resolveNamespaceOwners(ctx, _namespace)
+		}()
+	}
+	wg.Wait()
+	debugf("resolved workloads of %d pods in %d namespaces in %s", len(pods), len(namespaces), time.Since(started).Round(time.Millisecond))
+}
+
+// resolveNamespaceOwners caches the controllers of all ReplicaSets and Jobs
+// of namespace. Owners that cannot be listed are recorded as issues and left
+// to ownerController.
+func resolveNamespaceOwners(ctx context.Context, namespace string) {
+	started := time.Now()
+	owners := map[string]*metaV1.OwnerReference{}
+	if replicaSets, err := clientset.AppsV1().ReplicaSets(namespace).List(ctx, metaV1.ListOptions{}); err != nil {
+		recordIssue("list ReplicaSets", namespace, err)
+	} else {
+		for i := range replicaSets.Items {
+			owners[string(replicaSets.Items[i].UID)] = metaV1.GetControllerOf(&replicaSets.Items[i])
+		}
+	}
+	if jobs, err := clientset.BatchV1().Jobs(namespace).List(ctx, metaV1.ListOptions{}); err != nil {
+		recordIssue("list Jobs", namespace, err)
+	} else {
+		for i := range jobs.Items {
+			owners[string(jobs.Items[i].UID)] = metaV1.GetControllerOf(&jobs.Items[i])
+		}
+	}
+
+	workloadOwners.Lock()
+	defer workloadOwners.Unlock()
+	for key, owner := range owners {
+		workloadOwners.owners[key] = owner
+	}
+	debugf("listed %d ReplicaSets and Jobs of namespace %s in %s", len(owners), namespace, time.Since(started).Round(time.Millisecond))
+}
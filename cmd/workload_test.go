@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	appsV1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8sexec/pkg/k8sexec"
+	"testing"
+)
+
+// useFakeClientset replaces the clientset with a fake one holding objects
+// and resets caches of pods, workloads and recorded issues for the test.
+func useFakeClientset(t *testing.T, objects ...runtime.Object) *fake.Clientset {
+	t.Helper()
+	fakeClientset := fake.NewSimpleClientset(objects...)
+	previous := clientset
+	clientset = fakeClientset
+	reset := func() {
+		workloadOwners.owners = map[string]*metaV1.OwnerReference{}
+		resolvedPods.pods = map[string]*corev1.Pod{}
+		issues.list = nil
+	}
+	reset()
+	t.Cleanup(func() {
+		clientset = previous
+		reset()
+	})
+	return fakeClientset
+}
+
+func controllerRef(kind, name, uid string) []metaV1.OwnerReference {
+	controller := true
+	return []metaV1.OwnerReference{{Kind: kind, Name: name, UID: types.UID(uid), Controller: &controller}}
+}
+
+func replicaSet(name, uid, deployment, deploymentUID string) *appsV1.ReplicaSet {
+	return &appsV1.ReplicaSet{ObjectMeta: metaV1.ObjectMeta{
+		Namespace:       "ns",
+		Name:            name,
+		UID:             types.UID(uid),
+		Labels:          map[string]string{"app": "web"},
+		OwnerReferences: controllerRef("Deployment", deployment, deploymentUID),
+	}}
+}
+
+func ownedPod(name string, labels map[string]string, replicaSet, replicaSetUID string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metaV1.ObjectMeta{
+			Namespace:       "ns",
+			Name:            name,
+			UID:             types.UID(name + "-uid"),
+			Labels:          labels,
+			OwnerReferences: controllerRef("ReplicaSet", replicaSet, replicaSetUID),
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+	}
+}
+
+// overlappingPods are pods of the Deployments web and web-canary, whose
+// labels both match the selector app=web of web.
+func overlappingPods() ([]runtime.Object, []*corev1.Pod) {
+	objects := []runtime.Object{
+		replicaSet("web-1", "rs-web", "web", "deploy-web"),
+		replicaSet("web-canary-1", "rs-canary", "web-canary", "deploy-canary"),
+	}
+	pods := []*corev1.Pod{
+		ownedPod("web-1-a", map[string]string{"app": "web"}, "web-1", "rs-web"),
+		ownedPod("web-1-b", map[string]string{"app": "web"}, "web-1", "rs-web"),
+		ownedPod("web-canary-1-a", map[string]string{"app": "web", "track": "canary"}, "web-canary-1", "rs-canary"),
+	}
+	return objects, pods
+}
+
+func TestPodWorkloadOverlappingSelectors(t *testing.T) {
+	objects, pods := overlappingPods()
+	useFakeClientset(t, objects...)
+
+	want := map[string]Workload{
+		"web-1-a":        {Namespace: "ns", Kind: "Deployment", Name: "web", UID: "deploy-web"},
+		"web-1-b":        {Namespace: "ns", Kind: "Deployment", Name: "web", UID: "deploy-web"},
+		"web-canary-1-a": {Namespace: "ns", Kind: "Deployment", Name: "web-canary", UID: "deploy-canary"},
+	}
+	for _, pod := range pods {
+		if got := podWorkload(context.Background(), pod); got != want[pod.Name] {
+			t.Errorf("podWorkload(%s) = %v, want %v", pod.Name, got, want[pod.Name])
+		}
+	}
+}
+
+func TestResolveWorkloadsOverlappingSelectors(t *testing.T) {
+	objects, pods := overlappingPods()
+	useFakeClientset(t, objects...)
+
+	var targets []k8sexec.Target
+	for _, pod := range pods {
+		targets = append(targets, podTargets(pod)...)
+	}
+	resolveWorkloads(context.Background(), targets)
+
+	entries := listWorkloads(targets)
+	if len(entries) != 2 {
+		t.Fatalf("listWorkloads returned %d workloads, want 2: %+v", len(entries), entries)
+	}
+	for _, entry := range entries {
+		want := map[string]int{"web": 2, "web-canary": 1}[entry.Name]
+		if entry.Pods != want {
+			t.Errorf("workload %s has %d pods, want %d", entry.Workload, entry.Pods, want)
+		}
+	}
+}
+
+func TestPodWorkloadReplacedOwner(t *testing.T) {
+	// web-1 has been deleted and recreated by another Deployment since the
+	// pod was created
+	useFakeClientset(t, replicaSet("web-1", "rs-new", "other", "deploy-other"))
+	pod := ownedPod("web-1-a", map[string]string{"app": "web"}, "web-1", "rs-old")
+
+	want := Workload{Namespace: "ns", Kind: "ReplicaSet", Name: "web-1", UID: "rs-old"}
+	if got := podWorkload(context.Background(), pod); got != want {
+		t.Errorf("podWorkload = %v, want %v", got, want)
+	}
+}
+
+func TestOwnerControllerFailuresNotCached(t *testing.T) {
+	fakeClientset := useFakeClientset(t)
+	pod := ownedPod("web-1-a", map[string]string{"app": "web"}, "web-1", "rs-web")
+
+	if got := podWorkload(context.Background(), pod); got.Kind != "ReplicaSet" {
+		t.Fatalf("podWorkload of a pod whose ReplicaSet cannot be read = %v, want the ReplicaSet", got)
+	}
+	if len(recordedIssues()) != 1 {
+		t.Errorf("recorded %d issues, want 1", len(recordedIssues()))
+	}
+
+	if _, err := fakeClientset.AppsV1().ReplicaSets("ns").Create(context.Background(), replicaSet("web-1", "rs-web", "web", "deploy-web"), metaV1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	want := Workload{Namespace: "ns", Kind: "Deployment", Name: "web", UID: "deploy-web"}
+	if got := podWorkload(context.Background(), pod); got != want {
+		t.Errorf("podWorkload after the ReplicaSet became readable = %v, want %v", got, want)
+	}
+}
+
+func TestResolveNamespaceOwnersListFailures(t *testing.T) {
+	fakeClientset := useFakeClientset(t)
+	fakeClientset.PrependReactor("list", "replicasets", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("forbidden")
+	})
+
+	resolveNamespaceOwners(context.Background(), "ns")
+	list := recordedIssues()
+	if len(list) != 1 || list[0].Step != "list ReplicaSets" || list[0].Subject != "ns" {
+		t.Errorf("recorded issues %+v, want one of listing ReplicaSets of ns", list)
+	}
+}
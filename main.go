@@ -2,12 +2,16 @@ package main
 
 import (
 	_ "embed"
+	"errors"
 	"k8sexec/cmd"
 	"os"
 )
 
 func main() {
 	if err := cmd.Execute(); err != nil {
+		if errors.Is(err, cmd.ErrAborted) {
+			os.Exit(cmd.ExitAborted)
+		}
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,88 @@
+package checks
+
+import (
+	"bufio"
+	"fmt"
+	"k8sexec/pkg/k8sexec"
+	"path"
+	"sort"
+	"strings"
+)
+
+// attackSurfaceWeights score tools of each category found in a container by
+// how much they help an attacker living off the land.
+var attackSurfaceWeights = map[string]int{
+	"package-manager": 3,
+	"compiler":        3,
+	"debugger":        3,
+	"downloader":      2,
+	"interpreter":     2,
+}
+
+func init() {
+	Register(&Check{
+		Name:        "attack-surface",
+		Description: "package managers, compilers, interpreters, downloaders and debuggers living off the land attacks use",
+		Script:      script("attack-surface.sh"),
+		Evaluate:    evaluateAttackSurface,
+	})
+}
+
+func evaluateAttackSurface(in Input) []Finding {
+	tools := map[string][]string{}
+	score := 0
+	scanner := bufio.NewScanner(strings.NewReader(in.Status.Stdout))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 3 || fields[0] != "tool" {
+			continue
+		}
+		tools[fields[1]] = append(tools[fields[1]], path.Base(fields[2]))
+		score += attackSurfaceWeights[fields[1]]
+	}
+
+	categories := make([]string, 0, len(tools))
+	for category := range tools {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	var findings []Finding
+	var details []string
+	for _, category := range categories {
+		findings = append(findings, Finding{
+			ID:       category + "-present",
+			Severity: SeverityLow,
+			Title:    category + " present",
+			Detail:   strings.Join(tools[category], ", "),
+		})
+		details = append(details, category+": "+strings.Join(tools[category], ", "))
+	}
+
+	// the score is reported per image, as all its containers share it
+	image, _ := k8sexec.ContainerImage(in.Pod, in.Target.Container)
+	if image == "" {
+		image = "unknown image"
+	}
+	finding := Finding{
+		ID:       "attack-surface-score",
+		Severity: attackSurfaceSeverity(score),
+		Title:    fmt.Sprintf("attack surface of %s scores %d", image, score),
+		Detail:   strings.Join(details, "; "),
+	}
+	return append(findings, finding)
+}
+
+// attackSurfaceSeverity returns the severity of an attack surface score.
+func attackSurfaceSeverity(score int) Severity {
+	switch {
+	case score == 0:
+		return SeverityInfo
+	case score < 6:
+		return SeverityLow
+	case score < 15:
+		return SeverityMedium
+	default:
+		return SeverityHigh
+	}
+}
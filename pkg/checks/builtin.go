@@ -0,0 +1,29 @@
+package checks
+
+func init() {
+	Register(&Check{
+		Name:        "sa-token",
+		Description: "service account token mounted in the container",
+		Script:      script("sa-token.sh"),
+	})
+	Register(&Check{
+		Name:        "runtime-socket",
+		Description: "container runtime sockets mounted in the container",
+		Script:      script("runtime-socket.sh"),
+	})
+	Register(&Check{
+		Name:        "shadow-readable",
+		Description: "/etc/shadow readable by the container user",
+		Script:      script("shadow-readable.sh"),
+	})
+	Register(&Check{
+		Name:        "ssh-keys",
+		Description: "SSH private keys in home directories",
+		Script:      script("ssh-keys.sh"),
+	})
+	Register(&Check{
+		Name:        "shell-history",
+		Description: "shell history files left in the image",
+		Script:      script("shell-history.sh"),
+	})
+}
@@ -0,0 +1,133 @@
+package checks
+
+import (
+	"bufio"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// capabilityNames are Linux capabilities indexed by their numbers.
+var capabilityNames = []string{
+	"CAP_CHOWN", "CAP_DAC_OVERRIDE", "CAP_DAC_READ_SEARCH", "CAP_FOWNER", "CAP_FSETID",
+	"CAP_KILL", "CAP_SETGID", "CAP_SETUID", "CAP_SETPCAP", "CAP_LINUX_IMMUTABLE",
+	"CAP_NET_BIND_SERVICE", "CAP_NET_BROADCAST", "CAP_NET_ADMIN", "CAP_NET_RAW", "CAP_IPC_LOCK",
+	"CAP_IPC_OWNER", "CAP_SYS_MODULE", "CAP_SYS_RAWIO", "CAP_SYS_CHROOT", "CAP_SYS_PTRACE",
+	"CAP_SYS_PACCT", "CAP_SYS_ADMIN", "CAP_SYS_BOOT", "CAP_SYS_NICE", "CAP_SYS_RESOURCE",
+	"CAP_SYS_TIME", "CAP_SYS_TTY_CONFIG", "CAP_MKNOD", "CAP_LEASE", "CAP_AUDIT_WRITE",
+	"CAP_AUDIT_CONTROL", "CAP_SETFCAP", "CAP_MAC_OVERRIDE", "CAP_MAC_ADMIN", "CAP_SYSLOG",
+	"CAP_WAKE_ALARM", "CAP_BLOCK_SUSPEND", "CAP_AUDIT_READ", "CAP_PERFMON", "CAP_BPF",
+	"CAP_CHECKPOINT_RESTORE",
+}
+
+// capabilitySeverities are severities of effective capabilities flagged by
+// default, capabilities flagged on request are reported as high.
+var capabilitySeverities = map[string]Severity{
+	"CAP_SYS_ADMIN":       SeverityCritical,
+	"CAP_SYS_MODULE":      SeverityCritical,
+	"CAP_SYS_PTRACE":      SeverityHigh,
+	"CAP_SYS_RAWIO":       SeverityHigh,
+	"CAP_DAC_READ_SEARCH": SeverityHigh,
+	"CAP_NET_ADMIN":       SeverityHigh,
+	"CAP_BPF":             SeverityHigh,
+	"CAP_SYS_BOOT":        SeverityHigh,
+	"CAP_MAC_ADMIN":       SeverityHigh,
+	"CAP_MAC_OVERRIDE":    SeverityHigh,
+	"CAP_PERFMON":         SeverityMedium,
+	"CAP_SYS_TIME":        SeverityMedium,
+	"CAP_NET_RAW":         SeverityMedium,
+	"CAP_SETFCAP":         SeverityLow,
+	"CAP_MKNOD":           SeverityLow,
+}
+
+func init() {
+	var policy []string
+	for _, name := range capabilityNames {
+		if _, ok := capabilitySeverities[name]; ok {
+			policy = append(policy, name)
+		}
+	}
+
+	Register(&Check{
+		Name:        "capabilities",
+		Description: "effective Linux capabilities of the container main process",
+		Script:      script("capabilities.sh"),
+		Args:        policy,
+		ArgsUsage:   "[flagged-capability...]",
+		Evaluate:    evaluateCapabilities,
+	})
+}
+
+// DecodeCapabilities returns names of capabilities set in a hexadecimal
+// capability bitmap as found in /proc/<pid>/status. Unknown capabilities
+// are returned by their numbers.
+func DecodeCapabilities(bitmap string) ([]string, error) {
+	value, err := strconv.ParseUint(strings.TrimSpace(bitmap), 16, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid capability bitmap %q: %w", bitmap, err)
+	}
+
+	var names []string
+	for value != 0 {
+		capability := bits.TrailingZeros64(value)
+		value &^= 1 << capability
+		if capability < len(capabilityNames) {
+			names = append(names, capabilityNames[capability])
+		} else {
+			names = append(names, fmt.Sprintf("CAP_%d", capability))
+		}
+	}
+	return names, nil
+}
+
+func evaluateCapabilities(in Input) []Finding {
+	sets := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(in.Status.Stdout))
+	for scanner.Scan() {
+		if set, bitmap, ok := strings.Cut(scanner.Text(), "\t"); ok {
+			sets[set] = bitmap
+		}
+	}
+
+	effective, err := DecodeCapabilities(sets["CapEff"])
+	if err != nil {
+		return []Finding{{Severity: SeverityInfo, ID: "scan-incomplete", Title: "capabilities could not be read", Detail: err.Error()}}
+	}
+
+	findings := []Finding{{
+		ID:       "effective-capabilities",
+		Severity: SeverityInfo,
+		Title:    fmt.Sprintf("%d effective capabilities", len(effective)),
+		Detail:   strings.Join(effective, ","),
+	}}
+
+	if bounding, err := DecodeCapabilities(sets["CapBnd"]); err == nil && len(effective) == len(bounding) && len(effective) >= len(capabilityNames)-3 {
+		findings = append(findings, Finding{
+			ID:       "all-capabilities",
+			Severity: SeverityCritical,
+			Title:    "all capabilities are effective, the container is likely privileged",
+		})
+	}
+
+	flagged := map[string]bool{}
+	for _, name := range in.Args {
+		flagged[strings.ToUpper(name)] = true
+	}
+	for _, name := range effective {
+		if !flagged[name] {
+			continue
+		}
+		severity, ok := capabilitySeverities[name]
+		if !ok {
+			severity = SeverityHigh
+		}
+		findings = append(findings, Finding{
+			ID:       "dangerous-capability",
+			Severity: severity,
+			Title:    "effective capability " + name,
+		})
+	}
+
+	return findings
+}
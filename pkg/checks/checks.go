@@ -0,0 +1,273 @@
+// Package checks implements security checks executed in containers and
+// bundles them into profiles.
+//
+// A check is a shell script executed with sh in every container. By default
+// findings are reported by the script itself with the finding function
+// defined in scripts/lib.sh, checks needing more than that evaluate the
+// output of the script in Go.
+package checks
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/rest"
+	"k8sexec/pkg/k8sexec"
+	"sort"
+	"strings"
+)
+
+// Severity of a finding.
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+// Finding is an issue or a notable fact found by a check in a container.
+type Finding struct {
+	Check     string   `json:"Check"`
+	ID        string   `json:"ID"`
+	Severity  Severity `json:"Severity"`
+	Namespace string   `json:"Namespace"`
+	Pod       string   `json:"Pod"`
+	Container string   `json:"Container"`
+	Title     string   `json:"Title"`
+	Detail    string   `json:"Detail,omitempty"`
+	// Remediation is the remediation of the finding, only set when mapped
+	// by Remediations.
+	Remediation string `json:"Remediation,omitempty"`
+}
+
+// Input is what a check evaluates in a single container.
+type Input struct {
+	Target k8sexec.Target
+	// Args are parameters the script has been executed with.
+	Args   []string
+	Status *k8sexec.ExecutionStatus
+	// Pod is the pod of the container, nil when not known, e.g. when
+	// targets are replayed.
+	Pod *corev1.Pod
+	// APIServer is the connection to the API server, nil when not known.
+	APIServer *rest.Config
+}
+
+// Check is a script executed in containers whose output is turned into findings.
+type Check struct {
+	Name        string
+	Description string
+	Script      string
+	// Args are default positional parameters of the script, ArgsUsage
+	// describes them. Checks without ArgsUsage do not accept parameters.
+	Args      []string
+	ArgsUsage string
+	// PodArgs returns parameters appended to Args for a container of pod,
+	// pod is nil when not known.
+	PodArgs func(pod *corev1.Pod) []string
+	// Sensitive checks print secrets, their output is dropped from results
+	// once evaluated.
+	Sensitive bool
+	// Evaluate turns the outcome of the script in a container into findings,
+	// findings reported by the script are used when nil.
+	Evaluate func(in Input) []Finding
+}
+
+// Result holds executions of a check.
+type Result struct {
+	Check    string                     `json:"Check"`
+	Statuses []*k8sexec.ExecutionStatus `json:"Statuses"`
+}
+
+// Options controls execution of checks.
+type Options struct {
+	Parallel int
+	// MaxPerNode limits concurrent executions in containers on the same
+	// node, see k8sexec.ExecOptions.
+	MaxPerNode int
+	// Args overrides default parameters of checks by their names.
+	Args map[string][]string
+	// Pod returns the pod of a target, it may be nil.
+	Pod func(target k8sexec.Target) *corev1.Pod
+	// APIServer is the connection to the API server checks may query with
+	// credentials found in containers, it may be nil.
+	APIServer *rest.Config
+}
+
+// node returns the node the pod of target is scheduled on, if known.
+func (o Options) node(target k8sexec.Target) string {
+	if o.Pod == nil {
+		return ""
+	}
+	if pod := o.Pod(target); pod != nil {
+		return pod.Spec.NodeName
+	}
+	return ""
+}
+
+// image returns the image of the container of target and the image ID it
+// resolved to, if known.
+func (o Options) image(target k8sexec.Target) (string, string) {
+	if o.Pod == nil {
+		return "", ""
+	}
+	return k8sexec.ContainerImage(o.Pod(target), target.Container)
+}
+
+var registry = map[string]*Check{}
+
+// Register makes a check available by its name.
+func Register(check *Check) {
+	if _, ok := registry[check.Name]; ok {
+		panic(fmt.Sprintf("check %s registered twice", check.Name))
+	}
+	registry[check.Name] = check
+}
+
+// Get returns the registered check with the given name.
+func Get(name string) (*Check, error) {
+	check, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown check %q", name)
+	}
+	return check, nil
+}
+
+// Names returns names of all registered checks.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Run executes checks in all targets and returns their results together with
+// all findings. Containers where a check could not be executed produce no
+// findings, their errors are available in the results.
+func Run(ctx context.Context, k8s *k8sexec.K8SExec, targets []k8sexec.Target, checks []*Check, opts Options) ([]*Result, []Finding) {
+	var results []*Result
+	var findings []Finding
+
+	for _, check := range checks {
+		args := check.Args
+		if override, ok := opts.Args[check.Name]; ok {
+			args = override
+		}
+
+		statuses, targetArgs := check.run(ctx, k8s, targets, args, opts)
+		results = append(results, &Result{Check: check.Name, Statuses: statuses})
+
+		for i, status := range statuses {
+			if status.Error == "" {
+				in := Input{Target: targets[i], Args: targetArgs[i], Status: status, APIServer: opts.APIServer}
+				if opts.Pod != nil {
+					in.Pod = opts.Pod(targets[i])
+				}
+				findings = append(findings, check.evaluate(in)...)
+			}
+
+			// failed executions, e.g. timed out ones, may hold partial output
+			if check.Sensitive {
+				status.Stdout = ""
+				status.StdoutLines = nil
+			}
+		}
+	}
+
+	return results, findings
+}
+
+// run executes the script of c in targets and returns statuses and
+// parameters of every target. Targets are executed together as long as their
+// parameters do not differ.
+func (c *Check) run(ctx context.Context, k8s *k8sexec.K8SExec, targets []k8sexec.Target, args []string, opts Options) ([]*k8sexec.ExecutionStatus, [][]string) {
+	targetArgs := make([][]string, len(targets))
+	var groups [][]int
+	group := map[string]int{}
+	for i, target := range targets {
+		targetArgs[i] = args
+		if c.PodArgs != nil {
+			var pod *corev1.Pod
+			if opts.Pod != nil {
+				pod = opts.Pod(target)
+			}
+			targetArgs[i] = append(append([]string{}, args...), c.PodArgs(pod)...)
+		}
+
+		key := strings.Join(targetArgs[i], "\x00")
+		if _, ok := group[key]; !ok {
+			group[key] = len(groups)
+			groups = append(groups, nil)
+		}
+		groups[group[key]] = append(groups[group[key]], i)
+	}
+
+	statuses := make([]*k8sexec.ExecutionStatus, len(targets))
+	for _, indexes := range groups {
+		groupTargets := make([]k8sexec.Target, len(indexes))
+		for j, i := range indexes {
+			groupTargets[j] = targets[i]
+		}
+
+		// the script is read from stdin, its parameters follow "--"
+		groupStatuses := k8s.ExecAll(ctx, groupTargets, append([]string{"sh", "-s", "--"}, targetArgs[indexes[0]]...), k8sexec.ExecOptions{
+			Stdin:      []byte(c.Script),
+			Parallel:   opts.Parallel,
+			MaxPerNode: opts.MaxPerNode,
+			Node:       opts.node,
+			Image:      opts.image,
+		})
+		for j, i := range indexes {
+			statuses[i] = groupStatuses[j]
+		}
+	}
+
+	return statuses, targetArgs
+}
+
+func (c *Check) evaluate(in Input) []Finding {
+	var findings []Finding
+	if c.Evaluate != nil {
+		findings = c.Evaluate(in)
+	} else {
+		findings = ReportedFindings(in)
+	}
+
+	for i := range findings {
+		findings[i].Check = c.Name
+		findings[i].Namespace = in.Status.Namespace
+		findings[i].Pod = in.Status.Pod
+		findings[i].Container = in.Status.Container
+	}
+	return findings
+}
+
+// ReportedFindings parses findings reported by a script with the finding
+// function of scripts/lib.sh, i.e. lines of the form
+//
+//	FINDING<TAB>severity<TAB>id<TAB>title<TAB>detail
+func ReportedFindings(in Input) []Finding {
+	var findings []Finding
+
+	scanner := bufio.NewScanner(strings.NewReader(in.Status.Stdout))
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\t", 5)
+		if len(fields) < 4 || fields[0] != "FINDING" {
+			continue
+		}
+
+		finding := Finding{Severity: Severity(fields[1]), ID: fields[2], Title: fields[3]}
+		if len(fields) == 5 {
+			finding.Detail = fields[4]
+		}
+		findings = append(findings, finding)
+	}
+
+	return findings
+}
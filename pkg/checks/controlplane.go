@@ -0,0 +1,85 @@
+package checks
+
+import (
+	"bufio"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	"k8sexec/pkg/netcheck"
+	"strings"
+)
+
+// controlPlaneSurfaces describe control plane surfaces probed by
+// scripts/control-plane.sh by their names.
+var controlPlaneSurfaces = map[string]struct {
+	title    string
+	severity Severity
+}{
+	"apiserver":        {"API server service", SeverityInfo},
+	"apiserver-node":   {"API server port of the node", SeverityLow},
+	"kubelet":          {"kubelet API", SeverityHigh},
+	"kubelet-readonly": {"unauthenticated read-only kubelet API", SeverityHigh},
+	"etcd":             {"etcd", SeverityCritical},
+	"cloud-metadata":   {"cloud instance metadata service", SeverityHigh},
+}
+
+func init() {
+	Register(&Check{
+		Name:        "control-plane",
+		Description: "kubelet, etcd, API server and cloud metadata reachable from the container network",
+		Script:      netcheck.ProbeLib + script("control-plane.sh"),
+		ArgsUsage:   "[node...]",
+		PodArgs:     nodeArgs,
+		Evaluate:    evaluateControlPlane,
+	})
+}
+
+// nodeArgs probes the node the pod runs on in addition to nodes given as
+// parameters.
+func nodeArgs(pod *corev1.Pod) []string {
+	if pod == nil || pod.Status.HostIP == "" {
+		return nil
+	}
+	return []string{pod.Status.HostIP}
+}
+
+func evaluateControlPlane(in Input) []Finding {
+	var findings []Finding
+	var blocked []string
+
+	scanner := bufio.NewScanner(strings.NewReader(in.Status.Stdout))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 5 || fields[0] != "surface" {
+			continue
+		}
+		name, address := fields[1], fields[2]
+		probe := netcheck.Probe{Destination: address, Result: fields[3], Tool: fields[4]}
+
+		surface, ok := controlPlaneSurfaces[name]
+		switch {
+		case !ok:
+			continue
+		case probe.Result == netcheck.ResultUntested:
+			return []Finding{{ID: "control-plane-untested", Severity: SeverityInfo, Title: "neither nc, curl nor bash is available in the container"}}
+		case probe.Result == netcheck.ResultOpen:
+			findings = append(findings, Finding{
+				ID:       name + "-reachable",
+				Severity: surface.severity,
+				Title:    surface.title + " is reachable",
+				Detail:   address,
+			})
+		default:
+			blocked = append(blocked, fmt.Sprintf("%s %s (%s)", name, address, probe.Result))
+		}
+	}
+
+	if len(blocked) > 0 {
+		findings = append(findings, Finding{
+			ID:       "control-plane-blocked",
+			Severity: SeverityInfo,
+			Title:    fmt.Sprintf("%d control plane surfaces are not reachable", len(blocked)),
+			Detail:   strings.Join(blocked, "; "),
+		})
+	}
+	return findings
+}
@@ -0,0 +1,106 @@
+package checks
+
+import (
+	"bufio"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	"strings"
+)
+
+func init() {
+	Register(&Check{
+		Name:        "dns",
+		Description: "resolvers used and lookups of cluster, search domain and external names",
+		Script:      script("dns.sh"),
+		Args:        []string{"example.com"},
+		ArgsUsage:   "[external-name...]",
+		Evaluate:    evaluateDNS,
+	})
+}
+
+type dnsLookup struct {
+	kind, name, result, addresses, tool string
+}
+
+func evaluateDNS(in Input) []Finding {
+	var resolvers, search, options []string
+	var lookups []dnsLookup
+
+	scanner := bufio.NewScanner(strings.NewReader(in.Status.Stdout))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		switch {
+		case fields[0] == "resolver" && len(fields) == 2:
+			resolvers = append(resolvers, fields[1])
+		case fields[0] == "search" && len(fields) == 2:
+			search = append(search, fields[1])
+		case fields[0] == "options" && len(fields) == 2:
+			options = append(options, fields[1])
+		case fields[0] == "lookup" && len(fields) == 6:
+			lookups = append(lookups, dnsLookup{kind: fields[1], name: fields[2], result: fields[3], addresses: fields[4], tool: fields[5]})
+		}
+	}
+
+	detail := fmt.Sprintf("search: %s; options: %s", strings.Join(search, " "), strings.Join(options, " "))
+	if in.Pod != nil {
+		policy := in.Pod.Spec.DNSPolicy
+		if policy == "" {
+			policy = corev1.DNSClusterFirst
+		}
+		detail += "; dnsPolicy: " + string(policy)
+	}
+	findings := []Finding{{
+		ID:       "dns-resolvers",
+		Severity: SeverityInfo,
+		Title:    "resolvers " + strings.Join(resolvers, ", "),
+		Detail:   detail,
+	}}
+	if len(resolvers) == 0 {
+		findings = append(findings, Finding{ID: "dns-no-resolver", Severity: SeverityMedium, Title: "no nameserver in /etc/resolv.conf"})
+	}
+
+	clusterResolved := false
+	for _, lookup := range lookups {
+		if lookup.kind == "cluster" && lookup.result == "ok" {
+			clusterResolved = true
+		}
+	}
+
+	for _, lookup := range lookups {
+		switch {
+		case lookup.result == "untested":
+			findings = append(findings, Finding{ID: "dns-untested", Severity: SeverityInfo, Title: "neither getent nor nslookup is available in the container"})
+			return findings
+		case lookup.kind == "cluster" && lookup.result != "ok":
+			findings = append(findings, Finding{
+				ID:       "dns-cluster-failed",
+				Severity: SeverityMedium,
+				Title:    "cluster service name " + lookup.name + " does not resolve",
+				Detail:   lookup.tool,
+			})
+		case lookup.kind == "search" && lookup.result != "ok" && clusterResolved:
+			findings = append(findings, Finding{
+				ID:       "dns-search-failed",
+				Severity: SeverityLow,
+				Title:    "short name " + lookup.name + " does not resolve through search domains",
+				Detail:   lookup.tool,
+			})
+		case lookup.kind == "external" && lookup.result == "ok":
+			findings = append(findings, Finding{
+				ID:       "dns-external-resolved",
+				Severity: SeverityInfo,
+				Title:    "external name " + lookup.name + " resolves",
+				Detail:   lookup.addresses,
+			})
+		case lookup.kind == "external":
+			findings = append(findings, Finding{
+				ID:       "dns-external-failed",
+				Severity: SeverityInfo,
+				Title:    "external name " + lookup.name + " does not resolve",
+				Detail:   lookup.tool,
+			})
+		}
+	}
+
+	return findings
+}
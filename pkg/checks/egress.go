@@ -0,0 +1,59 @@
+package checks
+
+import (
+	"fmt"
+	"k8sexec/pkg/netcheck"
+	"sort"
+	"strings"
+)
+
+func init() {
+	Register(&Check{
+		Name:        "egress",
+		Description: "outbound connections to the internet",
+		Script:      netcheck.ProbeLib + script("egress.sh"),
+		Args:        []string{"1.1.1.1:80", "1.1.1.1:443", "8.8.8.8:443", "example.com:80", "example.com:443"},
+		ArgsUsage:   "[host:port...]",
+		Evaluate:    evaluateEgress,
+	})
+}
+
+func evaluateEgress(in Input) []Finding {
+	probes := netcheck.ParseProbes(in.Status.Stdout)
+
+	var reached, blocked []string
+	for _, endpoint := range in.Args {
+		probe, ok := probes[endpoint]
+		switch {
+		case !ok || probe.Result == netcheck.ResultUntested:
+			return []Finding{{ID: "egress-untested", Severity: SeverityInfo, Title: "neither nc, curl nor bash is available in the container"}}
+		case probe.Reachable():
+			reached = append(reached, endpoint)
+		default:
+			blocked = append(blocked, fmt.Sprintf("%s (%s)", endpoint, probe.Result))
+		}
+	}
+	sort.Strings(reached)
+	sort.Strings(blocked)
+
+	if len(reached) == 0 {
+		return []Finding{{
+			ID:       "egress-blocked",
+			Severity: SeverityInfo,
+			Title:    "no external endpoint is reachable",
+			Detail:   strings.Join(blocked, " "),
+		}}
+	}
+
+	finding := Finding{
+		ID:       "egress-allowed",
+		Severity: SeverityMedium,
+		Title:    fmt.Sprintf("%d of %d external endpoints are reachable", len(reached), len(in.Args)),
+		Detail:   strings.Join(reached, " "),
+	}
+	if len(blocked) == 0 {
+		finding.ID = "egress-unrestricted"
+		finding.Title = "all external endpoints are reachable, egress is unrestricted"
+	}
+	return []Finding{finding}
+}
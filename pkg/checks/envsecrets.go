@@ -0,0 +1,117 @@
+package checks
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+var (
+	secretNameRe = regexp.MustCompile(`(?i)(passw|passwd|pwd|secret|token|api_?key|access_?key|private_?key|credential|auth)`)
+
+	secretValueRes = []struct {
+		kind string
+		re   *regexp.Regexp
+	}{
+		{"private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)},
+		{"AWS access key", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+		{"Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+		{"GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+		{"Slack token", regexp.MustCompile(`\bxox[baprs]-[A-Za-z0-9-]{10,}`)},
+		{"JSON web token", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+		{"bearer token", regexp.MustCompile(`(?i)bearer [A-Za-z0-9._~+/-]{20,}`)},
+		{"URL with credentials", regexp.MustCompile(`[a-z][a-z0-9+.-]*://[^/\s:@]+:[^/\s@]+@`)},
+	}
+
+	// ignoredVariables are set by Kubernetes or the runtime and never hold secrets
+	ignoredVariables = regexp.MustCompile(`^(PATH|HOME|HOSTNAME|PWD|OLDPWD|SHLVL|TERM|LANG|LC_[A-Z]+|KUBERNETES_[A-Z0-9_]+|[A-Z0-9_]+_(SERVICE_HOST|SERVICE_PORT[A-Z0-9_]*|PORT[A-Z0-9_]*))$`)
+)
+
+// minEntropy is the Shannon entropy per character from which values of
+// variables with suspicious names are considered random secrets.
+const minEntropy = 3.5
+
+func init() {
+	Register(&Check{
+		Name:        "env-secrets",
+		Description: "credentials exposed through environment variables",
+		Script:      script("env-secrets.sh"),
+		Sensitive:   true,
+		Evaluate:    evaluateEnvSecrets,
+	})
+}
+
+func evaluateEnvSecrets(in Input) []Finding {
+	separator := "\n"
+	if strings.Contains(in.Status.Stdout, "\x00") {
+		separator = "\x00"
+	}
+
+	var findings []Finding
+	for _, variable := range strings.Split(in.Status.Stdout, separator) {
+		name, value, ok := strings.Cut(variable, "=")
+		if !ok || value == "" || ignoredVariables.MatchString(name) {
+			continue
+		}
+
+		finding := Finding{ID: "env-secret", Detail: fmt.Sprintf("%s=%s", name, Redact(value))}
+		switch kind := secretValueKind(value); {
+		case kind != "":
+			finding.Severity = SeverityHigh
+			finding.Title = kind + " in environment variable " + name
+		case !secretNameRe.MatchString(name) || strings.HasPrefix(value, "/"):
+			// not a secret or a path to a file holding it
+			continue
+		case len(value) >= 16 && Entropy(value) >= minEntropy:
+			finding.Severity = SeverityMedium
+			finding.Title = "likely credential in environment variable " + name
+		case len(value) >= 6:
+			finding.Severity = SeverityLow
+			finding.Title = "possible credential in environment variable " + name
+		default:
+			continue
+		}
+		findings = append(findings, finding)
+	}
+
+	return findings
+}
+
+func secretValueKind(value string) string {
+	for _, secret := range secretValueRes {
+		if secret.re.MatchString(value) {
+			return secret.kind
+		}
+	}
+	return ""
+}
+
+// Entropy returns the Shannon entropy of s in bits per character.
+func Entropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+
+	counts := map[rune]int{}
+	for _, r := range s {
+		counts[r]++
+	}
+
+	var entropy float64
+	length := float64(len([]rune(s)))
+	for _, count := range counts {
+		p := float64(count) / length
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// Redact hides a secret value keeping only its first characters and length.
+func Redact(value string) string {
+	visible := 2
+	if len(value) < 8 {
+		visible = 0
+	}
+	return fmt.Sprintf("%s*** (%d chars)", value[:visible], len(value))
+}
@@ -0,0 +1,132 @@
+package checks
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"os"
+	"strings"
+)
+
+// Golden holds expected state of files in containers, e.g.
+//
+//	files:
+//	  - path: /etc/nginx/nginx.conf
+//	    sha256: 2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae
+//	  - path: /etc/passwd
+//	    mode: "-rw-r--r--"
+//	    owner: "0:0"
+//	  - path: /usr/bin/sudo
+//	    absent: true
+type Golden struct {
+	Files []GoldenFile `yaml:"files"`
+}
+
+// GoldenFile is the expected state of a file, empty fields are not
+// compared.
+type GoldenFile struct {
+	Path string `yaml:"path"`
+	// SHA256 is the expected checksum of the content.
+	SHA256 string `yaml:"sha256"`
+	// Content is the expected content, an alternative to SHA256.
+	Content string `yaml:"content"`
+	// Mode is the expected mode as printed by ls, e.g. -rw-r--r--.
+	Mode string `yaml:"mode"`
+	// Owner is the expected numeric owner as uid:gid.
+	Owner  string `yaml:"owner"`
+	Absent bool   `yaml:"absent"`
+}
+
+// LoadGolden reads the golden file at path.
+func LoadGolden(path string) (*Golden, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	golden := &Golden{}
+	if err := yaml.UnmarshalStrict(data, golden); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i := range golden.Files {
+		file := &golden.Files[i]
+		switch {
+		case file.Path == "":
+			return nil, fmt.Errorf("%s: file %d has no path", path, i+1)
+		case file.Content != "" && file.SHA256 != "":
+			return nil, fmt.Errorf("%s: %s has both content and sha256", path, file.Path)
+		case file.Content != "":
+			sum := sha256.Sum256([]byte(file.Content))
+			file.SHA256 = hex.EncodeToString(sum[:])
+		}
+		file.SHA256 = strings.ToLower(file.SHA256)
+	}
+	return golden, nil
+}
+
+// Check returns a check comparing files of containers with g.
+func (g *Golden) Check() *Check {
+	paths := make([]string, 0, len(g.Files))
+	for _, file := range g.Files {
+		paths = append(paths, file.Path)
+	}
+
+	return &Check{
+		Name:        "golden",
+		Description: "files deviating from a golden file",
+		Script:      script("golden.sh"),
+		Args:        paths,
+		Evaluate:    g.evaluate,
+	}
+}
+
+func (g *Golden) evaluate(in Input) []Finding {
+	type state struct {
+		mode, owner, sha256 string
+	}
+	states := map[string]*state{}
+
+	scanner := bufio.NewScanner(strings.NewReader(in.Status.Stdout))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 4 || fields[0] != "file" {
+			continue
+		}
+		mode, owner, _ := strings.Cut(fields[2], " ")
+		states[fields[1]] = &state{mode: mode, owner: strings.Replace(owner, " ", ":", 1), sha256: fields[3]}
+	}
+
+	var findings []Finding
+	deviations := 0
+	for _, file := range g.Files {
+		current, exists := states[file.Path]
+		switch {
+		case file.Absent && exists:
+			findings = append(findings, Finding{ID: "golden-present", Severity: SeverityHigh, Title: file.Path + " exists but should be absent"})
+		case file.Absent:
+		case !exists:
+			findings = append(findings, Finding{ID: "golden-missing", Severity: SeverityHigh, Title: file.Path + " is missing"})
+		default:
+			if file.SHA256 != "" && current.sha256 == "" {
+				findings = append(findings, Finding{ID: "golden-untested", Severity: SeverityInfo, Title: "checksum of " + file.Path + " cannot be computed in the container"})
+			} else if file.SHA256 != "" && current.sha256 != file.SHA256 {
+				findings = append(findings, Finding{ID: "golden-modified", Severity: SeverityHigh, Title: file.Path + " has been modified", Detail: "sha256 " + current.sha256})
+			}
+			if file.Mode != "" && strings.TrimRight(current.mode, ".+@") != file.Mode {
+				findings = append(findings, Finding{ID: "golden-mode", Severity: SeverityMedium, Title: fmt.Sprintf("%s has mode %s instead of %s", file.Path, current.mode, file.Mode)})
+			}
+			if file.Owner != "" && current.owner != file.Owner {
+				findings = append(findings, Finding{ID: "golden-owner", Severity: SeverityMedium, Title: fmt.Sprintf("%s is owned by %s instead of %s", file.Path, current.owner, file.Owner)})
+			}
+		}
+	}
+	for _, finding := range findings {
+		if finding.Severity != SeverityInfo {
+			deviations++
+		}
+	}
+
+	return append([]Finding{{ID: "golden", Severity: SeverityInfo, Title: fmt.Sprintf("%d of %d files deviate from the golden file", deviations, len(g.Files))}}, findings...)
+}
@@ -0,0 +1,97 @@
+package checks
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+)
+
+// hostInterfacePrefixes are prefixes of network interfaces of nodes, e.g. of
+// bridges and tunnels of CNI plugins, not found in pod network namespaces.
+var hostInterfacePrefixes = []string{
+	"cni", "docker", "flannel", "cali", "vxlan", "tunl", "cilium_", "weave", "veth", "br-", "kube-",
+	"ens", "enp", "eno", "bond",
+}
+
+func init() {
+	Register(&Check{
+		Name:        "host-namespaces",
+		Description: "host network, PID and IPC namespaces and privileged mode of the pod spec cross-checked from the container",
+		Script:      script("host-namespaces.sh"),
+		Evaluate:    evaluateHostNamespaces,
+	})
+}
+
+func evaluateHostNamespaces(in Input) []Finding {
+	var hostPIDSeen bool
+	var processes string
+	var hostInterfaces, devices []string
+	scanner := bufio.NewScanner(strings.NewReader(in.Status.Stdout))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		switch {
+		case fields[0] == "comm" && len(fields) == 3:
+			hostPIDSeen = hostPIDSeen || (fields[1] == "2" && fields[2] == "kthreadd")
+		case fields[0] == "processes" && len(fields) == 2:
+			processes = fields[1]
+		case fields[0] == "interface" && len(fields) == 2 && isHostInterface(fields[1]):
+			hostInterfaces = append(hostInterfaces, fields[1])
+		case fields[0] == "device" && len(fields) == 2:
+			devices = append(devices, fields[1])
+		}
+	}
+
+	var hostNetwork, hostPID, hostIPC, privileged bool
+	if in.Pod != nil {
+		hostNetwork, hostPID, hostIPC = in.Pod.Spec.HostNetwork, in.Pod.Spec.HostPID, in.Pod.Spec.HostIPC
+	}
+	if spec := containerSpec(in.Pod, in.Target.Container); spec != nil && spec.SecurityContext != nil && spec.SecurityContext.Privileged != nil {
+		privileged = *spec.SecurityContext.Privileged
+	}
+
+	var findings []Finding
+	for _, exposure := range []struct {
+		id, title string
+		severity  Severity
+		declared  bool
+		// observed is empty when the exposure was not observed
+		observed string
+	}{
+		{"host-network", "host network namespace", SeverityHigh, hostNetwork, strings.Join(hostInterfaces, ", ")},
+		{"host-pid", "host PID namespace", SeverityHigh, hostPID, observedHostPID(hostPIDSeen, processes)},
+		{"host-ipc", "host IPC namespace", SeverityHigh, hostIPC, ""},
+		{"privileged", "privileged mode", SeverityCritical, privileged, strings.Join(devices, ", ")},
+	} {
+		switch {
+		case exposure.declared && exposure.observed != "":
+			findings = append(findings, Finding{ID: exposure.id, Severity: exposure.severity, Title: "uses the " + exposure.title, Detail: "confirmed from the container: " + exposure.observed})
+		case exposure.declared:
+			findings = append(findings, Finding{ID: exposure.id, Severity: exposure.severity, Title: "uses the " + exposure.title, Detail: "declared in the pod spec, not observed from the container"})
+		case exposure.observed != "" && in.Pod != nil:
+			findings = append(findings, Finding{ID: exposure.id + "-undeclared", Severity: SeverityCritical, Title: exposure.title + " observed although not declared in the pod spec", Detail: exposure.observed})
+		case exposure.observed != "":
+			findings = append(findings, Finding{ID: exposure.id, Severity: exposure.severity, Title: exposure.title + " observed from the container", Detail: exposure.observed})
+		}
+	}
+	return findings
+}
+
+// observedHostPID describes the host PID namespace seen from a container, an
+// empty string when it is not seen.
+func observedHostPID(seen bool, processes string) string {
+	if !seen {
+		return ""
+	}
+	return fmt.Sprintf("kthreadd is visible as PID 2, %s processes", processes)
+}
+
+// isHostInterface reports whether the network interface name is one of a
+// node.
+func isHostInterface(name string) bool {
+	for _, prefix := range hostInterfacePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
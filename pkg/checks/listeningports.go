@@ -0,0 +1,129 @@
+package checks
+
+import (
+	"bufio"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Listener is a listening socket.
+type Listener struct {
+	Protocol string
+	Address  string
+	Port     int
+}
+
+// Wildcard reports whether l accepts connections on all addresses.
+func (l Listener) Wildcard() bool {
+	if l.Address == "*" {
+		return true
+	}
+	ip := net.ParseIP(l.Address)
+	return ip != nil && ip.IsUnspecified()
+}
+
+func (l Listener) String() string {
+	return net.JoinHostPort(l.Address, strconv.Itoa(l.Port)) + "/" + l.Protocol
+}
+
+func init() {
+	Register(&Check{
+		Name:        "listening-ports",
+		Description: "listening TCP and UDP sockets compared with container ports of the pod spec",
+		Script:      script("listening-ports.sh"),
+		Evaluate:    evaluateListeningPorts,
+	})
+}
+
+// ParseListeners parses the output of scripts/listening-ports.sh, duplicate
+// sockets, e.g. reported for both IPv4 and IPv6, are dropped.
+func ParseListeners(output string) []Listener {
+	var listeners []Listener
+	seen := map[Listener]bool{}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 4 || fields[0] != "listen" {
+			continue
+		}
+		port, err := strconv.Atoi(fields[3])
+		if err != nil {
+			continue
+		}
+
+		listener := Listener{Protocol: strings.ToLower(fields[1]), Address: fields[2], Port: port}
+		if ip := net.ParseIP(listener.Address); ip != nil {
+			listener.Address = ip.String()
+		}
+		if !seen[listener] {
+			seen[listener] = true
+			listeners = append(listeners, listener)
+		}
+	}
+
+	sort.Slice(listeners, func(i, j int) bool {
+		if listeners[i].Port != listeners[j].Port {
+			return listeners[i].Port < listeners[j].Port
+		}
+		return listeners[i].String() < listeners[j].String()
+	})
+	return listeners
+}
+
+// declaredPorts returns container ports of all containers of pod, they share
+// the network namespace so listeners of one are seen by all.
+func declaredPorts(pod *corev1.Pod) map[string]bool {
+	ports := map[string]bool{}
+	for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for _, container := range containers {
+			for _, port := range container.Ports {
+				protocol := strings.ToLower(string(port.Protocol))
+				if protocol == "" {
+					protocol = "tcp"
+				}
+				ports[fmt.Sprintf("%s/%d", protocol, port.ContainerPort)] = true
+			}
+		}
+	}
+	return ports
+}
+
+func evaluateListeningPorts(in Input) []Finding {
+	listeners := ParseListeners(in.Status.Stdout)
+
+	sockets := make([]string, 0, len(listeners))
+	for _, listener := range listeners {
+		sockets = append(sockets, listener.String())
+	}
+	findings := []Finding{{
+		ID:       "listening-ports",
+		Severity: SeverityInfo,
+		Title:    fmt.Sprintf("%d listening sockets", len(listeners)),
+		Detail:   strings.Join(sockets, " "),
+	}}
+
+	// sockets of the node are seen in pods using the host network
+	if in.Pod == nil || in.Pod.Spec.HostNetwork {
+		return findings
+	}
+
+	declared := declaredPorts(in.Pod)
+	for _, listener := range listeners {
+		if !listener.Wildcard() || declared[fmt.Sprintf("%s/%d", listener.Protocol, listener.Port)] {
+			continue
+		}
+		findings = append(findings, Finding{
+			ID:       "undeclared-listener",
+			Severity: SeverityMedium,
+			Title:    fmt.Sprintf("%s port %d listens on all addresses but is not declared in the pod spec", strings.ToUpper(listener.Protocol), listener.Port),
+			Detail:   listener.String(),
+		})
+	}
+
+	return findings
+}
@@ -0,0 +1,156 @@
+package checks
+
+import (
+	"bufio"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	"strings"
+)
+
+// Mount is an entry of /proc/mounts.
+type Mount struct {
+	Device     string
+	MountPoint string
+	FSType     string
+	Options    []string
+}
+
+// ReadOnly reports whether m is mounted read-only.
+func (m Mount) ReadOnly() bool {
+	for _, option := range m.Options {
+		if option == "ro" {
+			return true
+		}
+	}
+	return false
+}
+
+// runtimeMounts are mounted by the container runtime in every container.
+var runtimeMounts = map[string]bool{
+	"/":                    true,
+	"/etc/hosts":           true,
+	"/etc/hostname":        true,
+	"/etc/resolv.conf":     true,
+	"/dev/termination-log": true,
+	"/run/.containerenv":   true,
+	"/var/run/secrets/kubernetes.io/serviceaccount": true,
+}
+
+// sensitiveHostPaths expose the node when mounted from the host.
+var sensitiveHostPaths = []string{
+	"/etc", "/root", "/home", "/var/run", "/run", "/var/lib/kubelet", "/var/lib/docker", "/var/lib/containerd",
+	"/var/log", "/proc", "/sys", "/dev", "/boot",
+}
+
+func init() {
+	Register(&Check{
+		Name:        "mounts",
+		Description: "mounted volumes compared with volumes of the pod spec",
+		Script:      script("mounts.sh"),
+		Evaluate:    evaluateMounts,
+	})
+}
+
+// ParseMounts parses the content of /proc/mounts as printed by scripts/mounts.sh.
+func ParseMounts(output string) []Mount {
+	var mounts []Mount
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 4 {
+			continue
+		}
+		mounts = append(mounts, Mount{
+			Device:     fields[0],
+			MountPoint: strings.ReplaceAll(fields[1], `\040`, " "),
+			FSType:     fields[2],
+			Options:    strings.Split(fields[3], ","),
+		})
+	}
+	return mounts
+}
+
+func evaluateMounts(in Input) []Finding {
+	mounts := ParseMounts(in.Status.Stdout)
+	findings := []Finding{{
+		ID:       "mounts",
+		Severity: SeverityInfo,
+		Title:    fmt.Sprintf("%d mounts", len(mounts)),
+	}}
+
+	volumes := podVolumes(in.Pod)
+	declared := map[string]corev1.Volume{}
+	if spec := containerSpec(in.Pod, in.Target.Container); spec != nil {
+		for _, mount := range spec.VolumeMounts {
+			declared[strings.TrimSuffix(mount.MountPath, "/")] = volumes[mount.Name]
+		}
+	}
+
+	for _, mount := range mounts {
+		volume, ok := declared[mount.MountPoint]
+		switch {
+		case ok && volume.HostPath != nil:
+			finding := Finding{
+				ID:       "hostpath-mount",
+				Severity: SeverityHigh,
+				Title:    fmt.Sprintf("hostPath %s mounted at %s", volume.HostPath.Path, mount.MountPoint),
+				Detail:   strings.Join(mount.Options, ","),
+			}
+			if isSensitiveHostPath(volume.HostPath.Path) {
+				finding.Severity = SeverityCritical
+			}
+			if !mount.ReadOnly() {
+				finding.Title = "writable " + finding.Title
+			}
+			findings = append(findings, finding)
+		case ok && (volume.Secret != nil || volume.ConfigMap != nil) && !mount.ReadOnly():
+			findings = append(findings, Finding{
+				ID:       "writable-config-mount",
+				Severity: SeverityMedium,
+				Title:    fmt.Sprintf("volume %s with a Secret or ConfigMap is mounted writable at %s", volume.Name, mount.MountPoint),
+			})
+		case !ok && in.Pod != nil && !isRuntimeMount(mount):
+			findings = append(findings, Finding{
+				ID:       "undeclared-mount",
+				Severity: SeverityMedium,
+				Title:    "mount not declared in the pod spec at " + mount.MountPoint,
+				Detail:   fmt.Sprintf("%s %s %s", mount.Device, mount.FSType, strings.Join(mount.Options, ",")),
+			})
+		}
+	}
+
+	return findings
+}
+
+func podVolumes(pod *corev1.Pod) map[string]corev1.Volume {
+	volumes := map[string]corev1.Volume{}
+	if pod != nil {
+		for _, volume := range pod.Spec.Volumes {
+			volumes[volume.Name] = volume
+		}
+	}
+	return volumes
+}
+
+func isRuntimeMount(mount Mount) bool {
+	for _, prefix := range []string{"/proc", "/sys", "/dev"} {
+		if underPath(mount.MountPoint, prefix) {
+			return true
+		}
+	}
+	return runtimeMounts[mount.MountPoint]
+}
+
+func isSensitiveHostPath(path string) bool {
+	path = strings.TrimSuffix(path, "/")
+	if path == "" {
+		// the root of the host
+		return true
+	}
+	for _, sensitive := range sensitiveHostPaths {
+		if underPath(path, sensitive) || underPath(sensitive, path) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package checks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PluginPrefix is the prefix of names of executables in PATH providing
+// external checks, e.g. kubex-check-licenses provides the check licenses.
+const PluginPrefix = "kubex-check-"
+
+// PluginVersion is the version of the protocol between kubex and plugins.
+const PluginVersion = 1
+
+// Plugin is an external check. It is executed once for all targets, which it
+// receives as PluginInput encoded as JSON on stdin, and returns its findings
+// as PluginOutput encoded as JSON on stdout. Diagnostics are read from
+// stderr. Plugins usually inspect targets through the Kubernetes API with
+// the kubeconfig they receive.
+type Plugin struct {
+	Name string
+	Path string
+}
+
+// PluginInput is sent to plugins.
+type PluginInput struct {
+	Version    int            `json:"Version"`
+	Kubeconfig string         `json:"Kubeconfig,omitempty"`
+	Namespace  string         `json:"Namespace"`
+	Targets    []PluginTarget `json:"Targets"`
+}
+
+// PluginTarget is a container checked by a plugin.
+type PluginTarget struct {
+	Namespace string `json:"Namespace"`
+	Pod       string `json:"Pod"`
+	Container string `json:"Container"`
+	Image     string `json:"Image,omitempty"`
+	ImageID   string `json:"ImageID,omitempty"`
+	Node      string `json:"Node,omitempty"`
+}
+
+// PluginOutput is returned by plugins. Findings without Check are
+// attributed to the plugin.
+type PluginOutput struct {
+	Findings []Finding `json:"Findings"`
+}
+
+// DiscoverPlugins returns plugins found in directories of path, a list like
+// $PATH, sorted by their names. The first plugin of each name wins.
+func DiscoverPlugins(path string) []Plugin {
+	seen := map[string]bool{}
+	var plugins []Plugin
+	for _, dir := range filepath.SplitList(path) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), PluginPrefix)
+			name = strings.TrimSuffix(name, ".exe")
+			if !ok || name == "" || seen[name] {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil || info.IsDir() || info.Mode().Perm()&0o111 == 0 {
+				continue
+			}
+			seen[name] = true
+			plugins = append(plugins, Plugin{Name: name, Path: filepath.Join(dir, entry.Name())})
+		}
+	}
+	sort.Slice(plugins, func(i, j int) bool {
+		return plugins[i].Name < plugins[j].Name
+	})
+	return plugins
+}
+
+// Run executes the plugin for in and returns its findings.
+func (p Plugin) Run(ctx context.Context, in PluginInput) ([]Finding, error) {
+	in.Version = PluginVersion
+	stdin, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Path)
+	cmd.Stdin = bytes.NewReader(stdin)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("plugin %s failed: %w: %s", p.Name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var out PluginOutput
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return nil, fmt.Errorf("plugin %s returned invalid output: %w", p.Name, err)
+	}
+	for i := range out.Findings {
+		if out.Findings[i].Check == "" {
+			out.Findings[i].Check = p.Name
+		}
+		if out.Findings[i].Severity == "" {
+			out.Findings[i].Severity = SeverityInfo
+		}
+	}
+	return out.Findings, nil
+}
@@ -0,0 +1,57 @@
+package checks
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"strings"
+)
+
+// containerSpec returns the spec of the named container of pod, nil if
+// there is no such container or pod is nil.
+func containerSpec(pod *corev1.Pod, name string) *corev1.Container {
+	if pod == nil {
+		return nil
+	}
+	for i := range pod.Spec.Containers {
+		if pod.Spec.Containers[i].Name == name {
+			return &pod.Spec.Containers[i]
+		}
+	}
+	return nil
+}
+
+// secretMounts returns mounts of the named container whose volumes
+// contain Secrets or service account tokens.
+func secretMounts(pod *corev1.Pod, name string) []corev1.VolumeMount {
+	spec := containerSpec(pod, name)
+	if spec == nil {
+		return nil
+	}
+
+	secretVolumes := map[string]bool{}
+	for _, volume := range pod.Spec.Volumes {
+		switch {
+		case volume.Secret != nil:
+			secretVolumes[volume.Name] = true
+		case volume.Projected != nil:
+			for _, source := range volume.Projected.Sources {
+				if source.Secret != nil || source.ServiceAccountToken != nil {
+					secretVolumes[volume.Name] = true
+				}
+			}
+		}
+	}
+
+	var mounts []corev1.VolumeMount
+	for _, mount := range spec.VolumeMounts {
+		if secretVolumes[mount.Name] {
+			mounts = append(mounts, mount)
+		}
+	}
+	return mounts
+}
+
+// underPath reports whether file is dir or a path inside of it.
+func underPath(file, dir string) bool {
+	dir = strings.TrimSuffix(dir, "/")
+	return file == dir || strings.HasPrefix(file, dir+"/")
+}
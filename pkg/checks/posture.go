@@ -0,0 +1,157 @@
+package checks
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"os"
+	"sort"
+)
+
+// Weights configure posture scores, e.g.
+//
+//	checks:
+//	  capabilities: 3
+//	  runtime-user: 3
+//	  egress: 1
+//	severities:
+//	  critical: 10
+//	  high: 5
+//
+// Every distinct finding of a workload deducts the points of its severity
+// multiplied by the weight of its check from a score of 100. Checks without
+// a weight are weighted with Default. Weights not set in a file keep their
+// defaults.
+type Weights struct {
+	Checks     map[string]int   `yaml:"checks"`
+	Severities map[Severity]int `yaml:"severities"`
+	Default    int              `yaml:"default"`
+}
+
+// DefaultWeights weight checks of the posture of a workload: capabilities,
+// root user, writable root filesystem, token exposure and egress.
+func DefaultWeights() Weights {
+	return Weights{
+		Checks: map[string]int{
+			"capabilities":    3,
+			"runtime-user":    3,
+			"privesc":         3,
+			"host-namespaces": 3,
+			"sa-rbac":         3,
+			"readonly-rootfs": 2,
+			"sa-token":        2,
+			"mounts":          2,
+			"egress":          2,
+		},
+		Severities: map[Severity]int{
+			SeverityCritical: 10,
+			SeverityHigh:     5,
+			SeverityMedium:   2,
+			SeverityLow:      1,
+			SeverityInfo:     0,
+		},
+		Default: 1,
+	}
+}
+
+// LoadWeights reads weights overriding DefaultWeights from the YAML file at
+// path.
+func LoadWeights(path string) (Weights, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Weights{}, err
+	}
+
+	var file Weights
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return Weights{}, fmt.Errorf("%s: %w", path, err)
+	}
+	weights := DefaultWeights()
+	for check, weight := range file.Checks {
+		weights.Checks[check] = weight
+	}
+	for severity, points := range file.Severities {
+		weights.Severities[severity] = points
+	}
+	if file.Default != 0 {
+		weights.Default = file.Default
+	}
+	return weights, nil
+}
+
+// maxScore is the score of a workload without findings.
+const maxScore = 100
+
+// Posture holds posture scores of workloads and their summary.
+type Posture struct {
+	// Average is the average score of all workloads.
+	Average int `json:"Average"`
+	// Workloads are sorted by their scores, the worst first.
+	Workloads []*WorkloadScore `json:"Workloads"`
+}
+
+// WorkloadScore is the posture score of a workload, from 0 to 100.
+type WorkloadScore struct {
+	Workload string `json:"Workload"`
+	Score    int    `json:"Score"`
+	// Deductions are the points deducted by each check.
+	Deductions map[string]int `json:"Deductions,omitempty"`
+}
+
+// ScorePosture scores workloads by findings of their containers, workload
+// returns the workload of the container of a finding. Findings found in
+// several containers of a workload are deducted once.
+func ScorePosture(workloads []string, findings []Finding, workload func(Finding) string, weights Weights) *Posture {
+	type key struct{ workload, check, id, title, detail string }
+
+	scores := map[string]*WorkloadScore{}
+	posture := &Posture{}
+	add := func(name string) *WorkloadScore {
+		score, ok := scores[name]
+		if !ok {
+			score = &WorkloadScore{Workload: name, Score: maxScore}
+			scores[name] = score
+			posture.Workloads = append(posture.Workloads, score)
+		}
+		return score
+	}
+	for _, name := range workloads {
+		add(name)
+	}
+
+	seen := map[key]bool{}
+	for _, finding := range findings {
+		name := workload(finding)
+		k := key{name, finding.Check, finding.ID, finding.Title, finding.Detail}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+
+		weight, ok := weights.Checks[finding.Check]
+		if !ok {
+			weight = weights.Default
+		}
+		points := weight * weights.Severities[finding.Severity]
+		if points == 0 {
+			continue
+		}
+		score := add(name)
+		if score.Deductions == nil {
+			score.Deductions = map[string]int{}
+		}
+		score.Deductions[finding.Check] += points
+		score.Score = max(score.Score-points, 0)
+	}
+
+	total := 0
+	for _, score := range posture.Workloads {
+		total += score.Score
+	}
+	if len(posture.Workloads) > 0 {
+		posture.Average = total / len(posture.Workloads)
+	}
+	sort.SliceStable(posture.Workloads, func(i, j int) bool {
+		return posture.Workloads[i].Score < posture.Workloads[j].Score
+	})
+	return posture
+}
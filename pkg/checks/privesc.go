@@ -0,0 +1,77 @@
+package checks
+
+import (
+	"bufio"
+	"strings"
+)
+
+func init() {
+	Register(&Check{
+		Name:        "privesc",
+		Description: "privilege escalation tooling (sudo, su, pkexec, doas, setuid shells)",
+		Script:      script("privesc.sh"),
+		Evaluate:    evaluatePrivesc,
+	})
+}
+
+func evaluatePrivesc(in Input) []Finding {
+	var uid, noNewPrivs string
+	var findings []Finding
+	escalation := false
+
+	scanner := bufio.NewScanner(strings.NewReader(in.Status.Stdout))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		switch fields[0] {
+		case "uid":
+			if len(fields) == 2 {
+				uid = fields[1]
+			}
+		case "nonewprivs":
+			if len(fields) == 2 {
+				noNewPrivs = fields[1]
+			}
+		case "tool":
+			if len(fields) != 3 {
+				continue
+			}
+			finding := Finding{ID: "escalation-tool", Severity: SeverityLow, Title: "privilege escalation tool present", Detail: fields[1]}
+			if fields[2] == "yes" {
+				finding.Severity = SeverityMedium
+				finding.Title = "setuid privilege escalation tool present"
+				escalation = true
+			}
+			findings = append(findings, finding)
+		case "sudo-nopasswd":
+			findings = append(findings, Finding{ID: "sudo-nopasswd", Severity: SeverityCritical, Title: "sudo allows running commands without a password"})
+			escalation = true
+		case "suid-shell":
+			if len(fields) != 2 {
+				continue
+			}
+			findings = append(findings, Finding{ID: "suid-shell", Severity: SeverityCritical, Title: "setuid shell", Detail: fields[1]})
+			escalation = true
+		}
+	}
+
+	allowed := true
+	if spec := containerSpec(in.Pod, in.Target.Container); spec != nil && spec.SecurityContext != nil && spec.SecurityContext.AllowPrivilegeEscalation != nil {
+		allowed = *spec.SecurityContext.AllowPrivilegeEscalation
+	}
+
+	// no_new_privs prevents gaining privileges through setuid binaries
+	if escalation && uid != "0" && noNewPrivs != "1" {
+		detail := "no_new_privs is not set"
+		if allowed {
+			detail += ", allowPrivilegeEscalation is not disabled"
+		}
+		findings = append(findings, Finding{
+			ID:       "escalation-possible",
+			Severity: SeverityHigh,
+			Title:    "unprivileged process could escalate privileges",
+			Detail:   detail,
+		})
+	}
+
+	return findings
+}
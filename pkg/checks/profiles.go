@@ -0,0 +1,90 @@
+package checks
+
+import (
+	"fmt"
+	"sort"
+)
+
+// profiles bundle checks under a name.
+var profiles = map[string][]string{
+	"quick-enum": {
+		"sa-token",
+		"sa-rbac",
+		"runtime-socket",
+		"capabilities",
+		"runtime-user",
+		"env-secrets",
+	},
+	"container-hardening": {
+		"runtime-socket",
+		"shadow-readable",
+		"ssh-keys",
+		"shell-history",
+		"suid",
+		"world-writable",
+		"capabilities",
+		"seccomp-lsm",
+		"readonly-rootfs",
+		"privesc",
+		"runtime-user",
+		"mounts",
+		"secret-perms",
+		"attack-surface",
+		"writable-mounts",
+		"host-namespaces",
+	},
+	"cnf-baseline": {
+		"sa-token",
+		"runtime-socket",
+		"shadow-readable",
+		"ssh-keys",
+		"shell-history",
+		"suid",
+		"world-writable",
+		"capabilities",
+		"seccomp-lsm",
+		"readonly-rootfs",
+		"privesc",
+		"runtime-user",
+		"env-secrets",
+		"mounts",
+		"secret-perms",
+		"listening-ports",
+		"dns",
+		"egress",
+		"control-plane",
+		"sa-rbac",
+		"tls-certs",
+		"attack-surface",
+		"writable-mounts",
+		"host-namespaces",
+	},
+}
+
+// Profile returns checks of the profile with the given name.
+func Profile(name string) ([]*Check, error) {
+	names, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q, available profiles: %v", name, Profiles())
+	}
+
+	checks := make([]*Check, 0, len(names))
+	for _, name := range names {
+		check, err := Get(name)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// Profiles returns names of all profiles.
+func Profiles() []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,171 @@
+package checks
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"strings"
+	"sync"
+)
+
+// rbacQuery is a permission whose grant makes a workload identity
+// over-privileged. Queries without namespace are cluster-wide.
+type rbacQuery struct {
+	verb, group, resource, subresource string
+	clusterWide                        bool
+	severity                           Severity
+}
+
+var rbacQueries = []rbacQuery{
+	{verb: "*", group: "*", resource: "*", clusterWide: true, severity: SeverityCritical},
+	{verb: "list", resource: "secrets", clusterWide: true, severity: SeverityCritical},
+	{verb: "create", group: "rbac.authorization.k8s.io", resource: "clusterrolebindings", clusterWide: true, severity: SeverityCritical},
+	{verb: "escalate", group: "rbac.authorization.k8s.io", resource: "clusterroles", clusterWide: true, severity: SeverityCritical},
+	{verb: "get", resource: "nodes", subresource: "proxy", clusterWide: true, severity: SeverityHigh},
+	{verb: "get", resource: "secrets", severity: SeverityHigh},
+	{verb: "create", resource: "pods", severity: SeverityHigh},
+	{verb: "create", resource: "pods", subresource: "exec", severity: SeverityHigh},
+	{verb: "create", resource: "serviceaccounts", subresource: "token", severity: SeverityHigh},
+	{verb: "create", group: "rbac.authorization.k8s.io", resource: "rolebindings", severity: SeverityHigh},
+	{verb: "patch", group: "apps", resource: "deployments", severity: SeverityMedium},
+	{verb: "delete", resource: "pods", severity: SeverityMedium},
+}
+
+func (q rbacQuery) String() string {
+	resource := q.resource
+	if q.subresource != "" {
+		resource += "/" + q.subresource
+	}
+	if q.group != "" {
+		resource += "." + q.group
+	}
+	if q.clusterWide {
+		return q.verb + " " + resource + " cluster-wide"
+	}
+	return q.verb + " " + resource
+}
+
+// rbacCache holds findings by tokens, containers of the same workload
+// identity are evaluated once.
+var rbacCache = struct {
+	sync.Mutex
+	findings map[string][]Finding
+}{findings: map[string][]Finding{}}
+
+func init() {
+	Register(&Check{
+		Name:        "sa-rbac",
+		Description: "permissions of the mounted service account token",
+		Script:      script("sa-rbac.sh"),
+		Sensitive:   true,
+		Evaluate:    evaluateRBAC,
+	})
+}
+
+func evaluateRBAC(in Input) []Finding {
+	values := map[string]string{}
+	scanner := bufio.NewScanner(strings.NewReader(in.Status.Stdout))
+	for scanner.Scan() {
+		if key, value, ok := strings.Cut(scanner.Text(), "\t"); ok {
+			values[key] = value
+		}
+	}
+
+	token, namespace := values["token"], values["namespace"]
+	switch {
+	case token == "":
+		return []Finding{{ID: "sa-rbac-no-token", Severity: SeverityInfo, Title: "no service account token is mounted"}}
+	case in.APIServer == nil:
+		return []Finding{{ID: "sa-rbac-untested", Severity: SeverityInfo, Title: "the API server is not available to evaluate the service account token"}}
+	case namespace == "":
+		namespace = in.Target.Namespace
+	}
+
+	rbacCache.Lock()
+	defer rbacCache.Unlock()
+	if findings, ok := rbacCache.findings[token]; ok {
+		return append([]Finding(nil), findings...)
+	}
+
+	findings := reviewToken(in.APIServer, token, namespace)
+	rbacCache.findings[token] = findings
+	return append([]Finding(nil), findings...)
+}
+
+// reviewToken queries the API server as the identity of token for its
+// rules in namespace and for permissions of rbacQueries.
+func reviewToken(apiServer *rest.Config, token, namespace string) []Finding {
+	config := rest.AnonymousClientConfig(apiServer)
+	config.BearerToken = token
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return []Finding{{ID: "sa-rbac-failed", Severity: SeverityInfo, Title: "cannot create a client for the service account token", Detail: err.Error()}}
+	}
+	subject := tokenSubject(token)
+
+	authz := client.AuthorizationV1()
+	review, err := authz.SelfSubjectRulesReviews().Create(context.TODO(), &authorizationv1.SelfSubjectRulesReview{
+		Spec: authorizationv1.SelfSubjectRulesReviewSpec{Namespace: namespace},
+	}, metaV1.CreateOptions{})
+	if err != nil {
+		return []Finding{{ID: "sa-rbac-failed", Severity: SeverityInfo, Title: "the service account token was rejected by the API server", Detail: err.Error()}}
+	}
+
+	rules := make([]string, 0, len(review.Status.ResourceRules))
+	for _, rule := range review.Status.ResourceRules {
+		rules = append(rules, fmt.Sprintf("%s %s", strings.Join(rule.Verbs, ","), strings.Join(rule.Resources, ",")))
+	}
+	findings := []Finding{{
+		ID:       "sa-rbac-rules",
+		Severity: SeverityInfo,
+		Title:    fmt.Sprintf("%s has %d rules in namespace %s", subject, len(review.Status.ResourceRules), namespace),
+		Detail:   strings.Join(rules, "; "),
+	}}
+
+	for _, query := range rbacQueries {
+		attributes := &authorizationv1.ResourceAttributes{
+			Verb:        query.verb,
+			Group:       query.group,
+			Resource:    query.resource,
+			Subresource: query.subresource,
+		}
+		if !query.clusterWide {
+			attributes.Namespace = namespace
+		}
+
+		access, err := authz.SelfSubjectAccessReviews().Create(context.TODO(), &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: attributes},
+		}, metaV1.CreateOptions{})
+		if err != nil || !access.Status.Allowed {
+			continue
+		}
+		findings = append(findings, Finding{
+			ID:       "sa-rbac-overprivileged",
+			Severity: query.severity,
+			Title:    fmt.Sprintf("%s can %s", subject, query),
+		})
+	}
+
+	return findings
+}
+
+// tokenSubject returns the subject of a service account token, the token is
+// not verified.
+func tokenSubject(token string) string {
+	parts := strings.Split(token, ".")
+	if len(parts) == 3 {
+		var claims struct {
+			Sub string `json:"sub"`
+		}
+		if payload, err := base64.RawURLEncoding.DecodeString(parts[1]); err == nil && json.Unmarshal(payload, &claims) == nil && claims.Sub != "" {
+			return claims.Sub
+		}
+	}
+	return "service account"
+}
@@ -0,0 +1,69 @@
+package checks
+
+import (
+	"bufio"
+	"strings"
+)
+
+func init() {
+	Register(&Check{
+		Name:        "readonly-rootfs",
+		Description: "writability of the root filesystem compared with readOnlyRootFilesystem",
+		Script:      script("readonly-rootfs.sh"),
+		Args:        []string{"/", "/etc", "/usr", "/bin", "/opt", "/app", "/tmp"},
+		ArgsUsage:   "[path...]",
+		Evaluate:    evaluateReadOnlyRootFS,
+	})
+}
+
+func evaluateReadOnlyRootFS(in Input) []Finding {
+	declared := false
+	if spec := containerSpec(in.Pod, in.Target.Container); spec != nil && spec.SecurityContext != nil && spec.SecurityContext.ReadOnlyRootFilesystem != nil {
+		declared = *spec.SecurityContext.ReadOnlyRootFilesystem
+	}
+
+	var findings []Finding
+	var writable []string
+	scanner := bufio.NewScanner(strings.NewReader(in.Status.Stdout))
+	for scanner.Scan() {
+		state, dir, ok := strings.Cut(scanner.Text(), "\t")
+		if !ok {
+			continue
+		}
+
+		switch {
+		case dir == "/" && state == "writable" && declared:
+			findings = append(findings, Finding{
+				ID:       "readonly-rootfs-mismatch",
+				Severity: SeverityHigh,
+				Title:    "root filesystem is writable although readOnlyRootFilesystem is declared",
+			})
+		case dir == "/" && state == "writable":
+			findings = append(findings, Finding{
+				ID:       "writable-rootfs",
+				Severity: SeverityMedium,
+				Title:    "root filesystem is writable",
+			})
+		case dir == "/" && !declared:
+			findings = append(findings, Finding{
+				ID:       "readonly-rootfs-undeclared",
+				Severity: SeverityInfo,
+				Title:    "root filesystem is read-only but readOnlyRootFilesystem is not declared",
+			})
+		}
+
+		if state == "writable" {
+			writable = append(writable, dir)
+		}
+	}
+
+	if len(writable) > 0 {
+		findings = append(findings, Finding{
+			ID:       "writable-paths",
+			Severity: SeverityInfo,
+			Title:    "writable directories",
+			Detail:   strings.Join(writable, ", "),
+		})
+	}
+	return findings
+}
@@ -0,0 +1,117 @@
+package checks
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"k8sexec/pkg/k8sexec"
+	"os"
+	"regexp"
+)
+
+// RemediationRule maps findings, or failures of commands, to the text of
+// their remediation. Rules for findings match their check, ID and title,
+// rules for failures their exit code, error kind and standard error. Title
+// and Stderr are regular expressions, empty fields match everything.
+type RemediationRule struct {
+	Check       string `yaml:"check"`
+	ID          string `yaml:"id"`
+	Title       string `yaml:"title"`
+	ExitCode    *int   `yaml:"exitCode"`
+	ErrorKind   string `yaml:"errorKind"`
+	Stderr      string `yaml:"stderr"`
+	Remediation string `yaml:"remediation"`
+
+	title, stderr *regexp.Regexp
+}
+
+// Remediations holds remediation rules, the first matching rule applies,
+// e.g.
+//
+//	rules:
+//	  - check: capabilities
+//	    id: dangerous-capability
+//	    title: CAP_SYS_ADMIN
+//	    remediation: Drop CAP_SYS_ADMIN in securityContext.capabilities.drop.
+//	  - exitCode: 127
+//	    remediation: Install the command in the image or use --inject-toolbox.
+type Remediations struct {
+	Rules []*RemediationRule `yaml:"rules"`
+}
+
+// LoadRemediations reads remediation rules from the YAML file at path.
+func LoadRemediations(path string) (*Remediations, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	remediations := &Remediations{}
+	if err := yaml.UnmarshalStrict(data, remediations); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, rule := range remediations.Rules {
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("%s: rule %d: %w", path, i+1, err)
+		}
+	}
+	return remediations, nil
+}
+
+func (r *RemediationRule) compile() error {
+	finding := r.Check != "" || r.ID != "" || r.Title != ""
+	failure := r.ExitCode != nil || r.ErrorKind != "" || r.Stderr != ""
+	switch {
+	case r.Remediation == "":
+		return errors.New("remediation is empty")
+	case finding == failure:
+		return errors.New("rule must match either findings (check, id, title) or failures (exitCode, errorKind, stderr)")
+	}
+
+	var err error
+	if r.title, err = compileOptional(r.Title); err != nil {
+		return err
+	}
+	r.stderr, err = compileOptional(r.Stderr)
+	return err
+}
+
+func compileOptional(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	return regexp.Compile(expr)
+}
+
+// Finding returns the remediation of finding, an empty string when no rule
+// matches it.
+func (r *Remediations) Finding(finding Finding) string {
+	for _, rule := range r.Rules {
+		if rule.ExitCode != nil || rule.ErrorKind != "" || rule.stderr != nil {
+			continue
+		}
+		if (rule.Check == "" || rule.Check == finding.Check) && (rule.ID == "" || rule.ID == finding.ID) &&
+			(rule.title == nil || rule.title.MatchString(finding.Title)) {
+			return rule.Remediation
+		}
+	}
+	return ""
+}
+
+// Status returns the remediation of the failure of a command, an empty
+// string when it succeeded or no rule matches it.
+func (r *Remediations) Status(status *k8sexec.ExecutionStatus) string {
+	if status.ErrorKind == "" {
+		return ""
+	}
+	for _, rule := range r.Rules {
+		if rule.ExitCode == nil && rule.ErrorKind == "" && rule.stderr == nil {
+			continue
+		}
+		if (rule.ExitCode == nil || *rule.ExitCode == status.RetCode) && (rule.ErrorKind == "" || rule.ErrorKind == status.ErrorKind) &&
+			(rule.stderr == nil || rule.stderr.MatchString(status.Stderr)) {
+			return rule.Remediation
+		}
+	}
+	return ""
+}
@@ -0,0 +1,73 @@
+package checks
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+)
+
+// unsetLoginUID is the value of /proc/<pid>/loginuid of processes not
+// started from a login session.
+const unsetLoginUID = "4294967295"
+
+func init() {
+	Register(&Check{
+		Name:        "runtime-user",
+		Description: "users the container processes run as compared with runAsNonRoot",
+		Script:      script("runtime-user.sh"),
+		Evaluate:    evaluateRuntimeUser,
+	})
+}
+
+func evaluateRuntimeUser(in Input) []Finding {
+	values := map[string]string{}
+	var pid1UID string
+
+	scanner := bufio.NewScanner(strings.NewReader(in.Status.Stdout))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		switch {
+		case fields[0] == "pid1" && len(fields) == 3:
+			// effective UID
+			pid1UID = fields[2]
+		case len(fields) == 2:
+			values[fields[0]] = fields[1]
+		}
+	}
+
+	detail := fmt.Sprintf("id: %s; whoami: %s", values["id"], values["whoami"])
+	if loginuid, ok := values["loginuid"]; ok && loginuid != unsetLoginUID {
+		detail += "; loginuid: " + loginuid
+	}
+	findings := []Finding{{
+		ID:       "runtime-user",
+		Severity: SeverityInfo,
+		Title:    fmt.Sprintf("main process UID %s, exec UID %s", pid1UID, values["uid"]),
+		Detail:   detail,
+	}}
+
+	runAsNonRoot := false
+	if spec := containerSpec(in.Pod, in.Target.Container); spec != nil && spec.SecurityContext != nil && spec.SecurityContext.RunAsNonRoot != nil {
+		runAsNonRoot = *spec.SecurityContext.RunAsNonRoot
+	} else if in.Pod != nil && in.Pod.Spec.SecurityContext != nil && in.Pod.Spec.SecurityContext.RunAsNonRoot != nil {
+		runAsNonRoot = *in.Pod.Spec.SecurityContext.RunAsNonRoot
+	}
+
+	if pid1UID == "0" || values["uid"] == "0" {
+		finding := Finding{
+			ID:       "runs-as-root",
+			Severity: SeverityHigh,
+			Title:    "container processes run as UID 0",
+			Detail:   "runAsNonRoot is not set",
+		}
+		if runAsNonRoot {
+			finding.ID = "runs-as-root-mismatch"
+			finding.Severity = SeverityCritical
+			finding.Title = "container processes run as UID 0 although runAsNonRoot is declared"
+			finding.Detail = ""
+		}
+		findings = append(findings, finding)
+	}
+
+	return findings
+}
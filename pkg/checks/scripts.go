@@ -0,0 +1,22 @@
+package checks
+
+import (
+	"embed"
+)
+
+//go:embed scripts
+var scripts embed.FS
+
+// script returns the embedded script with the given name preceded by the
+// functions of scripts/lib.sh.
+func script(name string) string {
+	lib, err := scripts.ReadFile("scripts/lib.sh")
+	if err != nil {
+		panic(err)
+	}
+	body, err := scripts.ReadFile("scripts/" + name)
+	if err != nil {
+		panic(err)
+	}
+	return string(lib) + "\n" + string(body)
+}
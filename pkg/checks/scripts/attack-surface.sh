@@ -0,0 +1,17 @@
+# tools "living off the land" attacks use, by category
+check() {
+	category=$1
+	shift
+	for tool in "$@"; do
+		path=$(command -v "$tool" 2>/dev/null) || continue
+		case $path in
+		/*) printf 'tool\t%s\t%s\n' "$category" "$path" ;;
+		esac
+	done
+}
+
+check package-manager apk apt apt-get dpkg yum dnf microdnf rpm zypper pacman pip pip3 npm gem
+check compiler gcc cc c++ g++ clang make as ld go rustc tcc
+check interpreter python python2 python3 perl ruby php lua node
+check downloader curl wget nc ncat netcat socat ftp tftp scp ssh
+check debugger gdb strace ltrace tcpdump nmap lsof
@@ -0,0 +1,7 @@
+while read -r key value; do
+	case "$key" in
+	CapPrm: | CapEff: | CapBnd: | CapAmb:)
+		printf '%s\t%s\n' "${key%:}" "$value"
+		;;
+	esac
+done </proc/1/status
@@ -0,0 +1,24 @@
+# usage: control-plane.sh [node...]
+# prints surface<TAB>name<TAB>host:port<TAB>result<TAB>tool for control plane
+# surfaces of the API server, the nodes and the cloud metadata service
+
+# surface NAME HOST PORT
+surface() {
+	(
+		set -- "$1" "$2" "$3" $(probe "$2" "$3" 3)
+		printf 'surface\t%s\t%s:%s\t%s\t%s\n' "$1" "$2" "$3" "$4" "$5"
+	) &
+}
+
+if [ -n "$KUBERNETES_SERVICE_HOST" ]; then
+	surface apiserver "$KUBERNETES_SERVICE_HOST" "${KUBERNETES_SERVICE_PORT:-443}"
+fi
+surface cloud-metadata 169.254.169.254 80
+
+for node in "$@"; do
+	surface kubelet "$node" 10250
+	surface kubelet-readonly "$node" 10255
+	surface etcd "$node" 2379
+	surface apiserver-node "$node" 6443
+done
+wait
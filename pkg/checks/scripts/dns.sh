@@ -0,0 +1,60 @@
+# usage: dns.sh [external-name...]
+# prints resolver configuration and results of lookups as
+# lookup<TAB>kind<TAB>name<TAB>result<TAB>addresses<TAB>tool
+
+domain=cluster.local
+if [ -r /etc/resolv.conf ]; then
+	while read -r key value; do
+		case "$key" in
+		nameserver) printf 'resolver\t%s\n' "$value" ;;
+		search)
+			printf 'search\t%s\n' "$value"
+			for d in $value; do
+				case "$d" in
+				svc.*) domain=${d#svc.} ;;
+				esac
+			done
+			;;
+		options) printf 'options\t%s\n' "$value" ;;
+		esac
+	done </etc/resolv.conf
+fi
+
+# lookup KIND NAME
+lookup() {
+	addrs= tool=none
+	if command -v getent >/dev/null 2>&1; then
+		tool=getent
+		addrs=$(getent hosts "$2" 2>/dev/null | while read -r addr rest; do printf '%s ' "$addr"; done)
+	elif command -v nslookup >/dev/null 2>&1; then
+		tool=nslookup
+		# addresses following the Name: line, the ones before are of the server
+		addrs=$(nslookup "$2" 2>/dev/null | {
+			named=
+			while read -r key a b rest; do
+				case "$key" in
+				Name:) named=1 ;;
+				Address:) [ -n "$named" ] && printf '%s ' "$a" ;;
+				Address) [ -n "$named" ] && printf '%s ' "$b" ;;
+				esac
+			done
+		})
+	fi
+
+	if [ "$tool" = none ]; then
+		result=untested
+	elif [ -n "$addrs" ]; then
+		result=ok
+	else
+		result=failed
+	fi
+	printf 'lookup\t%s\t%s\t%s\t%s\t%s\n' "$1" "$2" "$result" "${addrs% }" "$tool"
+}
+
+lookup cluster "kubernetes.default.svc.$domain"
+# relies on the search domains of the pod
+lookup search kubernetes.default
+lookup search kubernetes.default.svc
+for name in "$@"; do
+	lookup external "$name"
+done
@@ -0,0 +1,9 @@
+# usage: egress.sh HOST:PORT...
+# prints probe<TAB>HOST:PORT<TAB>result<TAB>tool for every endpoint
+for endpoint in "$@"; do
+	(
+		set -- $(probe "${endpoint%:*}" "${endpoint##*:}" 3)
+		printf 'probe\t%s\t%s\t%s\n' "$endpoint" "$1" "$2"
+	) &
+done
+wait
@@ -0,0 +1,23 @@
+# usage: golden.sh path...
+# prints file<TAB>path<TAB>mode uid gid<TAB>sha256 of existing files and
+# missing<TAB>path of the others
+hash=
+if command -v sha256sum >/dev/null 2>&1; then
+	hash=sha256sum
+elif command -v busybox >/dev/null 2>&1 && busybox sha256sum /dev/null >/dev/null 2>&1; then
+	hash="busybox sha256sum"
+fi
+
+for path in "$@"; do
+	if [ ! -e "$path" ] && [ ! -L "$path" ]; then
+		printf 'missing\t%s\n' "$path"
+		continue
+	fi
+	set -- $(ls -ldn "$path" 2>/dev/null)
+	sum=
+	if [ -f "$path" ] && [ -n "$hash" ]; then
+		set -- "$1" "$2" "$3" "$4" $($hash "$path" 2>/dev/null)
+		sum=$5
+	fi
+	printf 'file\t%s\t%s %s %s\t%s\n' "$path" "$1" "$3" "$4" "$sum"
+done
@@ -0,0 +1,22 @@
+# kthreadd is PID 2 in the PID namespace of the host only
+for pid in 1 2; do
+	if [ -r "/proc/$pid/comm" ]; then
+		read -r comm <"/proc/$pid/comm"
+		printf 'comm\t%s\t%s\n' "$pid" "$comm"
+	fi
+done
+
+processes=0
+for dir in /proc/[0-9]*; do
+	processes=$((processes + 1))
+done
+printf 'processes\t%d\n' "$processes"
+
+for iface in /sys/class/net/*; do
+	[ -e "$iface" ] && printf 'interface\t%s\n' "${iface##*/}"
+done
+
+# devices of the host are only visible in privileged containers
+for device in /dev/mem /dev/kmsg /dev/sda /dev/vda /dev/xvda /dev/nvme0; do
+	[ -e "$device" ] && printf 'device\t%s\n' "$device"
+done
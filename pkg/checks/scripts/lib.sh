@@ -0,0 +1,4 @@
+# finding SEVERITY ID TITLE [DETAIL] reports a finding to kubex
+finding() {
+	printf 'FINDING\t%s\t%s\t%s\t%s\n' "$1" "$2" "$3" "$4"
+}
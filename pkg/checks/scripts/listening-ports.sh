@@ -0,0 +1,65 @@
+# prints listening sockets as: listen<TAB>proto<TAB>address<TAB>port
+
+# rev8 turns 8 hex digits of a little-endian word of /proc/net into big-endian
+rev8() {
+	h=$1 out=
+	while [ -n "$h" ]; do
+		rest=${h#??}
+		out=${h%"$rest"}$out
+		h=$rest
+	done
+	printf '%s' "$out"
+}
+
+# proc_net parses a /proc/net/{tcp,udp}{,6} table
+proc_net() {
+	proto=$1 state=$2 file=$3
+	[ -r "$file" ] || return
+	while read -r sl local remote st rest; do
+		[ "$st" = "$state" ] || continue
+		hex=${local%:*}
+		port=$((0x${local#*:}))
+		if [ ${#hex} -eq 8 ]; then
+			w=$(rev8 "$hex")
+			b=${w#??} c=${w#????}
+			addr=$((0x${w%??????})).$((0x${b%????})).$((0x${c%??})).$((0x${w#??????}))
+		else
+			addr=
+			while [ -n "$hex" ]; do
+				rest=${hex#????????}
+				w=$(rev8 "${hex%"$rest"}")
+				addr=$addr${addr:+:}${w%????}:${w#????}
+				hex=$rest
+			done
+		fi
+		printf 'listen\t%s\t%s\t%s\n' "$proto" "$addr" "$port"
+	done <"$file"
+}
+
+# split_local prints a listener given as address:port by ss or netstat
+split_local() {
+	port=${2##*:}
+	addr=${2%:*}
+	addr=${addr#[}
+	addr=${addr%]}
+	addr=${addr%%%*}
+	printf 'listen\t%s\t%s\t%s\n' "$1" "$addr" "$port"
+}
+
+if command -v ss >/dev/null 2>&1 && ss -Hlntu >/dev/null 2>&1; then
+	ss -Hlntu | while read -r netid state recvq sendq local rest; do
+		split_local "$netid" "$local"
+	done
+elif command -v netstat >/dev/null 2>&1 && netstat -lntu >/dev/null 2>&1; then
+	netstat -lntu | while read -r proto recvq sendq local rest; do
+		case "$proto" in
+		tcp* | udp*) split_local "${proto%6}" "$local" ;;
+		esac
+	done
+else
+	# TCP sockets listen in state 0A, unconnected UDP sockets are in state 07
+	proc_net tcp 0A /proc/net/tcp
+	proc_net tcp 0A /proc/net/tcp6
+	proc_net udp 07 /proc/net/udp
+	proc_net udp 07 /proc/net/udp6
+fi
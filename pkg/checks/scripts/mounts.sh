@@ -0,0 +1,3 @@
+while read -r device mountpoint fstype options rest; do
+	printf '%s\t%s\t%s\t%s\n' "$device" "$mountpoint" "$fstype" "$options"
+done </proc/mounts
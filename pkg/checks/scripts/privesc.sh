@@ -0,0 +1,26 @@
+printf 'uid\t%s\n' "$(id -u 2>/dev/null)"
+
+while read -r key value; do
+	if [ "$key" = "NoNewPrivs:" ]; then
+		printf 'nonewprivs\t%s\n' "$value"
+	fi
+done </proc/self/status
+
+for tool in sudo su pkexec doas; do
+	path=$(command -v "$tool" 2>/dev/null) || continue
+	suid=no
+	if [ -u "$path" ]; then
+		suid=yes
+	fi
+	printf 'tool\t%s\t%s\n' "$path" "$suid"
+done
+
+if command -v sudo >/dev/null 2>&1 && sudo -n true >/dev/null 2>&1; then
+	printf 'sudo-nopasswd\n'
+fi
+
+for shell in /bin/sh /bin/bash /bin/dash /bin/ash /bin/zsh /bin/busybox /usr/bin/bash /usr/bin/zsh; do
+	if [ -u "$shell" ]; then
+		printf 'suid-shell\t%s\n' "$shell"
+	fi
+done
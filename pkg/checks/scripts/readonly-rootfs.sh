@@ -0,0 +1,16 @@
+# refuse to overwrite existing files
+set -C
+
+for dir in "$@"; do
+	[ -d "$dir" ] || continue
+	probe="${dir%/}/.kubex-probe-$$"
+	if [ -e "$probe" ]; then
+		continue
+	fi
+	if (: >"$probe") 2>/dev/null; then
+		rm -f "$probe"
+		printf 'writable\t%s\n' "$dir"
+	else
+		printf 'readonly\t%s\n' "$dir"
+	fi
+done
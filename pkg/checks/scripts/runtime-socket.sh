@@ -0,0 +1,7 @@
+for sock in /var/run/docker.sock /run/docker.sock \
+	/var/run/containerd/containerd.sock /run/containerd/containerd.sock \
+	/var/run/crio/crio.sock /run/crio/crio.sock; do
+	if [ -S "$sock" ]; then
+		finding critical runtime-socket "container runtime socket is mounted" "$sock"
+	fi
+done
@@ -0,0 +1,13 @@
+printf 'id\t%s\n' "$(id 2>/dev/null)"
+printf 'uid\t%s\n' "$(id -u 2>/dev/null)"
+printf 'whoami\t%s\n' "$(whoami 2>/dev/null)"
+
+if read -r loginuid </proc/1/loginuid 2>/dev/null; then
+	printf 'loginuid\t%s\n' "$loginuid"
+fi
+
+while read -r key real effective rest; do
+	if [ "$key" = "Uid:" ]; then
+		printf 'pid1\t%s\t%s\n' "$real" "$effective"
+	fi
+done </proc/1/status
@@ -0,0 +1,7 @@
+dir=/var/run/secrets/kubernetes.io/serviceaccount
+if read -r token <"$dir/token" 2>/dev/null; then
+	printf 'token\t%s\n' "$token"
+fi
+if read -r namespace <"$dir/namespace" 2>/dev/null; then
+	printf 'namespace\t%s\n' "$namespace"
+fi
@@ -0,0 +1,4 @@
+dir=/var/run/secrets/kubernetes.io/serviceaccount
+if [ -r "$dir/token" ]; then
+	finding medium sa-token "service account token is mounted" "$dir/token"
+fi
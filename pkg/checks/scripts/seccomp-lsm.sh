@@ -0,0 +1,21 @@
+while read -r key value; do
+	case "$key" in
+	Seccomp: | NoNewPrivs:)
+		printf '%s\t%s\n' "${key%:}" "$value"
+		;;
+	esac
+done </proc/1/status
+
+for attr in /proc/1/attr/apparmor/current /proc/1/attr/current; do
+	if [ -r "$attr" ] && read -r label <"$attr" 2>/dev/null; then
+		printf 'Label\t%s\n' "$label"
+		break
+	fi
+done
+
+if [ -d /sys/fs/selinux ]; then
+	printf 'LSM\tselinux\n'
+fi
+if [ -r /sys/module/apparmor/parameters/enabled ] && read -r enabled </sys/module/apparmor/parameters/enabled && [ "$enabled" = Y ]; then
+	printf 'LSM\tapparmor\n'
+fi
@@ -0,0 +1,12 @@
+# Secret volumes are tmpfs mounts, their files are symlinks to the data
+while read -r device mountpoint fstype rest; do
+	[ "$fstype" = tmpfs ] || continue
+	case "$mountpoint" in
+	/dev | /dev/* | /proc/* | /sys/* | /run | /tmp) continue ;;
+	esac
+	for file in "$mountpoint"/*; do
+		[ -f "$file" ] || continue
+		set -- $(ls -ldnL "$file" 2>/dev/null)
+		printf '%s\t%s\t%s\t%s\t%s\n' "$1" "$3" "$4" "$mountpoint" "$file"
+	done
+done </proc/mounts
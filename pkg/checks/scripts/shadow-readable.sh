@@ -0,0 +1,3 @@
+if [ -r /etc/shadow ] && [ -s /etc/shadow ]; then
+	finding high shadow-readable "/etc/shadow is readable" "$(ls -l /etc/shadow 2>/dev/null)"
+fi
@@ -0,0 +1,5 @@
+for history in /root/.*_history /home/*/.*_history; do
+	if [ -s "$history" ]; then
+		finding low shell-history "non-empty shell history file" "$history"
+	fi
+done
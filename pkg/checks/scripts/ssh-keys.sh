@@ -0,0 +1,8 @@
+for key in /root/.ssh/id_* /home/*/.ssh/id_*; do
+	case "$key" in
+	*.pub) continue ;;
+	esac
+	if [ -f "$key" ]; then
+		finding high ssh-private-key "SSH private key found" "$key"
+	fi
+done
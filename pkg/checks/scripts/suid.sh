@@ -0,0 +1,15 @@
+command -v find >/dev/null 2>&1 || exit 127
+
+# GNU find supports -perm /mode, busybox and older find only -perm -mode
+if find / -maxdepth 0 -perm /6000 >/dev/null 2>&1; then
+	find / -xdev -type f -perm /6000 2>/dev/null
+else
+	find / -xdev -type f \( -perm -4000 -o -perm -2000 \) 2>/dev/null
+fi | while read -r file; do
+	if [ -u "$file" ]; then
+		printf 'SUID\t%s\n' "$file"
+	fi
+	if [ -g "$file" ]; then
+		printf 'SGID\t%s\n' "$file"
+	fi
+done
@@ -0,0 +1,24 @@
+# usage: tls-certs.sh [path...]
+# prints cert<TAB>file followed by the certificates of the file, private keys
+# stored in the same file are never printed
+command -v find >/dev/null 2>&1 || exit 127
+
+for root in "$@"; do
+	[ -e "$root" ] || continue
+	# trust stores of the distribution hold hundreds of CA certificates
+	find "$root" \( -path /proc -o -path /sys -o -path /etc/ssl/certs -o -path /etc/pki/ca-trust \
+		-o -path /usr/share/ca-certificates -o -path /etc/ca-certificates \) -prune -o \
+		-type f \( -name '*.pem' -o -name '*.crt' -o -name '*.cer' -o -name '*.cert' \) -size -512k -print 2>/dev/null
+done | while read -r file; do
+	printf 'cert\t%s\n' "$file"
+	inside=
+	while IFS= read -r line; do
+		case "$line" in
+		"-----BEGIN CERTIFICATE-----"*) inside=1 ;;
+		esac
+		[ -n "$inside" ] && printf '%s\n' "$line"
+		case "$line" in
+		"-----END CERTIFICATE-----"*) inside= ;;
+		esac
+	done <"$file" 2>/dev/null
+done
@@ -0,0 +1,17 @@
+command -v find >/dev/null 2>&1 || exit 127
+
+for root in "$@"; do
+	[ -e "$root" ] || continue
+	find "$root" \( -path /proc -o -path /sys \) -prune -o \
+		\( -type f -o -type d \) -perm -0002 -print 2>/dev/null | while read -r path; do
+		if [ -d "$path" ] && [ -k "$path" ]; then
+			# sticky directories like /tmp are world-writable by design
+			kind=sticky
+		elif [ -d "$path" ]; then
+			kind=dir
+		else
+			kind=file
+		fi
+		printf '%s\t%s\t%s\n' "$kind" "$root" "$path"
+	done
+done
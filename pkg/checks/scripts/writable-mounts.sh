@@ -0,0 +1,22 @@
+# refuse to overwrite existing files
+set -C
+
+while read -r device mountpoint fstype options rest; do
+	# mount points escape spaces as \040
+	dir=$(printf '%b' "$mountpoint")
+	case $dir in
+	/ | /proc | /proc/* | /sys | /sys/* | /dev | /dev/*) continue ;;
+	esac
+	[ -d "$dir" ] || continue
+
+	probe="${dir%/}/.kubex-probe-$$"
+	if [ -e "$probe" ]; then
+		continue
+	fi
+	if (: >"$probe") 2>/dev/null; then
+		rm -f "$probe"
+		printf 'writable\t%s\t%s\n' "$mountpoint" "$fstype"
+	else
+		printf 'readonly\t%s\t%s\n' "$mountpoint" "$fstype"
+	fi
+done </proc/mounts
@@ -0,0 +1,112 @@
+package checks
+
+import (
+	"bufio"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	"strings"
+)
+
+var seccompModes = map[string]string{
+	"0": "disabled",
+	"1": "strict",
+	"2": "filter",
+}
+
+func init() {
+	Register(&Check{
+		Name:        "seccomp-lsm",
+		Description: "seccomp mode and AppArmor/SELinux confinement",
+		Script:      script("seccomp-lsm.sh"),
+		Evaluate:    evaluateSeccompLSM,
+	})
+}
+
+func evaluateSeccompLSM(in Input) []Finding {
+	var seccomp, label string
+	lsms := map[string]bool{}
+
+	scanner := bufio.NewScanner(strings.NewReader(in.Status.Stdout))
+	for scanner.Scan() {
+		key, value, _ := strings.Cut(scanner.Text(), "\t")
+		switch key {
+		case "Seccomp":
+			seccomp = value
+		case "Label":
+			label = strings.TrimRight(value, "\x00")
+		case "LSM":
+			lsms[value] = true
+		}
+	}
+
+	declared := declaredSeccompProfile(in.Pod, in.Target.Container)
+	mode, ok := seccompModes[seccomp]
+	if !ok {
+		mode = "unknown"
+	}
+
+	findings := []Finding{{
+		ID:       "confinement",
+		Severity: SeverityInfo,
+		Title:    fmt.Sprintf("seccomp %s, LSM label %q", mode, label),
+		Detail:   "declared seccomp profile: " + declared,
+	}}
+
+	switch {
+	case seccomp == "0" && (declared == string(corev1.SeccompProfileTypeRuntimeDefault) || declared == string(corev1.SeccompProfileTypeLocalhost)):
+		findings = append(findings, Finding{
+			ID:       "seccomp-mismatch",
+			Severity: SeverityHigh,
+			Title:    "seccomp profile " + declared + " is declared but the container runs without seccomp",
+		})
+	case seccomp == "0":
+		findings = append(findings, Finding{
+			ID:       "seccomp-unconfined",
+			Severity: SeverityHigh,
+			Title:    "container runs without seccomp",
+			Detail:   "declared seccomp profile: " + declared,
+		})
+	}
+
+	switch {
+	case lsms["apparmor"] && (label == "" || strings.HasPrefix(label, "unconfined")):
+		findings = append(findings, Finding{
+			ID:       "apparmor-unconfined",
+			Severity: SeverityMedium,
+			Title:    "container runs without an AppArmor profile",
+			Detail:   "declared AppArmor profile: " + declaredAppArmorProfile(in.Pod, in.Target.Container),
+		})
+	case lsms["selinux"] && (strings.Contains(label, ":spc_t:") || strings.Contains(label, ":unconfined_t:")):
+		findings = append(findings, Finding{
+			ID:       "selinux-unconfined",
+			Severity: SeverityHigh,
+			Title:    "container runs in an unconfined SELinux domain",
+			Detail:   label,
+		})
+	}
+
+	return findings
+}
+
+// declaredSeccompProfile returns the type of the seccomp profile declared for
+// a container, falling back to the pod security context.
+func declaredSeccompProfile(pod *corev1.Pod, name string) string {
+	if spec := containerSpec(pod, name); spec != nil && spec.SecurityContext != nil && spec.SecurityContext.SeccompProfile != nil {
+		return string(spec.SecurityContext.SeccompProfile.Type)
+	}
+	if pod != nil && pod.Spec.SecurityContext != nil && pod.Spec.SecurityContext.SeccompProfile != nil {
+		return string(pod.Spec.SecurityContext.SeccompProfile.Type)
+	}
+	return "not set"
+}
+
+// declaredAppArmorProfile returns the AppArmor profile declared for a
+// container by the pod annotation.
+func declaredAppArmorProfile(pod *corev1.Pod, name string) string {
+	if pod != nil {
+		if profile, ok := pod.Annotations[corev1.AppArmorBetaContainerAnnotationKeyPrefix+name]; ok {
+			return profile
+		}
+	}
+	return "not set"
+}
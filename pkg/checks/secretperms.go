@@ -0,0 +1,97 @@
+package checks
+
+import (
+	"bufio"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// defaultSecretMode is the mode of Secret volume files without defaultMode.
+const defaultSecretMode = 0o644
+
+func init() {
+	Register(&Check{
+		Name:        "secret-perms",
+		Description: "permissions of files in mounted Secret volumes",
+		Script:      script("secret-perms.sh"),
+		Evaluate:    evaluateSecretPerms,
+	})
+}
+
+func evaluateSecretPerms(in Input) []Finding {
+	var findings []Finding
+
+	mounts := secretMounts(in.Pod, in.Target.Container)
+	mountPaths := map[string]bool{}
+	for _, mount := range mounts {
+		mountPaths[strings.TrimSuffix(mount.MountPath, "/")] = true
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(in.Status.Stdout))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 5 || len(fields[0]) < 10 {
+			continue
+		}
+		mode, uid, gid, mountPoint, file := fields[0], fields[1], fields[2], fields[3], fields[4]
+
+		// without the pod spec every tmpfs mount is audited
+		if in.Pod != nil && !mountPaths[mountPoint] {
+			continue
+		}
+
+		groupReadable, worldReadable := mode[4] == 'r', mode[7] == 'r'
+		detail := fmt.Sprintf("%s %s:%s %s", mode, uid, gid, file)
+		switch {
+		case isPrivateKey(file) && worldReadable:
+			findings = append(findings, Finding{ID: "secret-key-world-readable", Severity: SeverityHigh, Title: "world-readable private key in a Secret volume", Detail: detail})
+		case isPrivateKey(file) && groupReadable:
+			findings = append(findings, Finding{ID: "secret-key-group-readable", Severity: SeverityMedium, Title: "group-readable private key in a Secret volume", Detail: detail})
+		case worldReadable:
+			findings = append(findings, Finding{ID: "secret-world-readable", Severity: SeverityLow, Title: "world-readable file in a Secret volume", Detail: detail})
+		}
+	}
+
+	volumes := podVolumes(in.Pod)
+	for _, mount := range mounts {
+		volume := volumes[mount.Name]
+		if volume.Secret == nil {
+			continue
+		}
+
+		mode := int32(defaultSecretMode)
+		if volume.Secret.DefaultMode != nil {
+			mode = *volume.Secret.DefaultMode
+		}
+		switch {
+		case mode&0o022 != 0:
+			findings = append(findings, Finding{
+				ID:       "secret-defaultmode-writable",
+				Severity: SeverityMedium,
+				Title:    fmt.Sprintf("Secret volume %s has writable defaultMode %#o", volume.Name, mode),
+			})
+		case mode&0o004 != 0:
+			findings = append(findings, Finding{
+				ID:       "secret-defaultmode-readable",
+				Severity: SeverityLow,
+				Title:    fmt.Sprintf("Secret volume %s has world-readable defaultMode %#o", volume.Name, mode),
+			})
+		}
+	}
+
+	return findings
+}
+
+// isPrivateKey guesses from the file name whether it holds a private key.
+func isPrivateKey(file string) bool {
+	name := strings.ToLower(path.Base(file))
+	switch {
+	case strings.HasSuffix(name, ".pub"), strings.HasSuffix(name, ".crt"), strings.Contains(name, "public"):
+		return false
+	case strings.HasSuffix(name, ".key"), strings.HasSuffix(name, ".pem"), strings.HasPrefix(name, "id_"),
+		strings.Contains(name, "privatekey"), strings.Contains(name, "private-key"), strings.Contains(name, "private_key"):
+		return true
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package checks
+
+import (
+	"bufio"
+	"path"
+	"strings"
+)
+
+// escalationBinaries are binaries allowing to escalate privileges when they
+// have the SUID/SGID bit set, see https://gtfobins.github.io
+var escalationBinaries = map[string]bool{
+	"sh": true, "bash": true, "dash": true, "zsh": true, "ash": true, "busybox": true,
+	"python": true, "python2": true, "python3": true, "perl": true, "ruby": true, "php": true, "lua": true, "node": true,
+	"find": true, "vi": true, "vim": true, "less": true, "more": true, "nano": true, "awk": true, "gawk": true,
+	"env": true, "cp": true, "mv": true, "tar": true, "nmap": true, "tee": true, "dd": true, "chmod": true, "chown": true,
+}
+
+func init() {
+	Register(&Check{
+		Name:        "suid",
+		Description: "SUID/SGID binaries",
+		Script:      script("suid.sh"),
+		Evaluate:    evaluateSUID,
+	})
+}
+
+func evaluateSUID(in Input) []Finding {
+	if in.Status.RetCode == 127 {
+		return []Finding{{Severity: SeverityInfo, ID: "scan-incomplete", Title: "find is not available in the container"}}
+	}
+
+	var findings []Finding
+	scanner := bufio.NewScanner(strings.NewReader(in.Status.Stdout))
+	for scanner.Scan() {
+		bit, file, ok := strings.Cut(scanner.Text(), "\t")
+		if !ok {
+			continue
+		}
+
+		finding := Finding{Detail: file}
+		switch bit {
+		case "SUID":
+			finding.ID, finding.Severity, finding.Title = "suid-binary", SeverityMedium, "SUID binary"
+		case "SGID":
+			finding.ID, finding.Severity, finding.Title = "sgid-binary", SeverityLow, "SGID binary"
+		default:
+			continue
+		}
+
+		base := strings.TrimRight(path.Base(file), "0123456789.")
+		if escalationBinaries[path.Base(file)] || escalationBinaries[base] {
+			finding.Severity = SeverityHigh
+			finding.Title += " allowing privilege escalation"
+		}
+		findings = append(findings, finding)
+	}
+
+	return findings
+}
@@ -0,0 +1,38 @@
+package checks
+
+import "sort"
+
+// Summary counts containers with the same finding.
+type Summary struct {
+	ID         string   `json:"ID"`
+	Severity   Severity `json:"Severity"`
+	Title      string   `json:"Title"`
+	Detail     string   `json:"Detail,omitempty"`
+	Containers int      `json:"Containers"`
+	// Remediation is the remediation of the findings, see Finding.
+	Remediation string `json:"Remediation,omitempty"`
+}
+
+// Summarize consolidates findings which differ only by the container they
+// were found in. Summaries are sorted by the number of containers.
+func Summarize(findings []Finding) []Summary {
+	type key struct{ check, id, title, detail string }
+
+	index := map[key]int{}
+	var summaries []Summary
+	for _, finding := range findings {
+		k := key{finding.Check, finding.ID, finding.Title, finding.Detail}
+		i, ok := index[k]
+		if !ok {
+			i = len(summaries)
+			index[k] = i
+			summaries = append(summaries, Summary{ID: finding.ID, Severity: finding.Severity, Title: finding.Title, Detail: finding.Detail, Remediation: finding.Remediation})
+		}
+		summaries[i].Containers++
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Containers > summaries[j].Containers
+	})
+	return summaries
+}
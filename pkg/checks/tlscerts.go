@@ -0,0 +1,124 @@
+package checks
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// certExpiryWarning is how long before expiry certificates are reported.
+const certExpiryWarning = 30 * 24 * time.Hour
+
+// weakSignatures are signature algorithms considered broken.
+var weakSignatures = map[x509.SignatureAlgorithm]bool{
+	x509.MD2WithRSA:    true,
+	x509.MD5WithRSA:    true,
+	x509.SHA1WithRSA:   true,
+	x509.DSAWithSHA1:   true,
+	x509.ECDSAWithSHA1: true,
+}
+
+func init() {
+	Register(&Check{
+		Name:        "tls-certs",
+		Description: "expired, expiring or weak TLS certificates",
+		Script:      script("tls-certs.sh"),
+		Args:        []string{"/etc", "/opt", "/app", "/srv", "/home", "/var/run/secrets", "/run/secrets"},
+		ArgsUsage:   "[path...]",
+		Evaluate:    evaluateTLSCerts,
+	})
+}
+
+func evaluateTLSCerts(in Input) []Finding {
+	if in.Status.RetCode == 127 {
+		return []Finding{{Severity: SeverityInfo, ID: "scan-incomplete", Title: "find is not available in the container"}}
+	}
+
+	var findings []Finding
+	seen := map[[32]byte]bool{}
+	count := 0
+	now := time.Now()
+
+	for _, file := range certFiles(in.Status.Stdout) {
+		for block, rest := pem.Decode(file.data); block != nil; block, rest = pem.Decode(rest) {
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				continue
+			}
+			fingerprint := sha256.Sum256(cert.Raw)
+			if seen[fingerprint] {
+				continue
+			}
+			seen[fingerprint] = true
+			count++
+
+			subject := cert.Subject.CommonName
+			if subject == "" {
+				subject = cert.Subject.String()
+			}
+			detail := fmt.Sprintf("%s, valid %s to %s", file.name, cert.NotBefore.Format(time.DateOnly), cert.NotAfter.Format(time.DateOnly))
+
+			switch {
+			case now.After(cert.NotAfter):
+				findings = append(findings, Finding{ID: "cert-expired", Severity: SeverityHigh, Title: "expired certificate " + subject, Detail: detail})
+			case now.Add(certExpiryWarning).After(cert.NotAfter):
+				findings = append(findings, Finding{ID: "cert-expiring", Severity: SeverityMedium, Title: fmt.Sprintf("certificate %s expires in %d days", subject, int(cert.NotAfter.Sub(now).Hours()/24)), Detail: detail})
+			case now.Before(cert.NotBefore):
+				findings = append(findings, Finding{ID: "cert-not-yet-valid", Severity: SeverityLow, Title: "certificate " + subject + " is not valid yet", Detail: detail})
+			}
+
+			// signatures of self-signed roots are never verified
+			selfSigned := cert.IsCA && cert.Subject.String() == cert.Issuer.String()
+			if weakSignatures[cert.SignatureAlgorithm] && !selfSigned {
+				findings = append(findings, Finding{ID: "cert-weak-signature", Severity: SeverityMedium, Title: fmt.Sprintf("certificate %s is signed with %s", subject, cert.SignatureAlgorithm), Detail: detail})
+			}
+			if weak, size := weakKey(cert); weak {
+				findings = append(findings, Finding{ID: "cert-weak-key", Severity: SeverityMedium, Title: fmt.Sprintf("certificate %s has a %d bit %s key", subject, size, cert.PublicKeyAlgorithm), Detail: detail})
+			}
+		}
+	}
+
+	return append([]Finding{{ID: "tls-certs", Severity: SeverityInfo, Title: fmt.Sprintf("%d certificates", count)}}, findings...)
+}
+
+type certFile struct {
+	name string
+	data []byte
+}
+
+// certFiles splits the output of scripts/tls-certs.sh by files.
+func certFiles(output string) []*certFile {
+	var files []*certFile
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "cert\t"); ok {
+			files = append(files, &certFile{name: name})
+			continue
+		}
+		if len(files) > 0 {
+			file := files[len(files)-1]
+			file.data = append(file.data, line+"\n"...)
+		}
+	}
+	return files
+}
+
+// weakKey reports whether the public key of cert is too short and its size
+// in bits.
+func weakKey(cert *x509.Certificate) (bool, int) {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return key.N.BitLen() < 2048, key.N.BitLen()
+	case *ecdsa.PublicKey:
+		return key.Curve.Params().BitSize < 256, key.Curve.Params().BitSize
+	}
+	return false, 0
+}
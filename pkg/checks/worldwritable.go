@@ -0,0 +1,89 @@
+package checks
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+)
+
+// maxExamples limits the number of paths listed in a finding.
+const maxExamples = 5
+
+func init() {
+	Register(&Check{
+		Name:        "world-writable",
+		Description: "world-writable files and directories",
+		Script:      script("world-writable.sh"),
+		Args:        []string{"/etc", "/bin", "/sbin", "/usr", "/lib", "/opt", "/app", "/home", "/root", "/var/run/secrets"},
+		ArgsUsage:   "[path...]",
+		Evaluate:    evaluateWorldWritable,
+	})
+}
+
+type writableCounts struct {
+	files, dirs int
+	examples    []string
+}
+
+func evaluateWorldWritable(in Input) []Finding {
+	if in.Status.RetCode == 127 {
+		return []Finding{{Severity: SeverityInfo, ID: "scan-incomplete", Title: "find is not available in the container"}}
+	}
+
+	mounts := secretMounts(in.Pod, in.Target.Container)
+
+	var roots []string
+	counts := map[string]*writableCounts{}
+	var findings []Finding
+
+	scanner := bufio.NewScanner(strings.NewReader(in.Status.Stdout))
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\t", 3)
+		if len(fields) != 3 || fields[0] == "sticky" {
+			continue
+		}
+		kind, root, path := fields[0], fields[1], fields[2]
+
+		c, ok := counts[root]
+		if !ok {
+			c = &writableCounts{}
+			counts[root] = c
+			roots = append(roots, root)
+		}
+		if kind == "dir" {
+			c.dirs++
+		} else {
+			c.files++
+		}
+		if len(c.examples) < maxExamples {
+			c.examples = append(c.examples, path)
+		}
+
+		for _, mount := range mounts {
+			if underPath(path, mount.MountPath) {
+				findings = append(findings, Finding{
+					ID:       "world-writable-secret",
+					Severity: SeverityHigh,
+					Title:    "world-writable path in a mounted secret volume " + mount.Name,
+					Detail:   path,
+				})
+			}
+		}
+	}
+
+	for _, root := range roots {
+		c := counts[root]
+		detail := fmt.Sprintf("%d files, %d directories: %s", c.files, c.dirs, strings.Join(c.examples, ", "))
+		if c.files+c.dirs > len(c.examples) {
+			detail += ", ..."
+		}
+		findings = append(findings, Finding{
+			ID:       "world-writable",
+			Severity: SeverityMedium,
+			Title:    "world-writable paths under " + root,
+			Detail:   detail,
+		})
+	}
+
+	return findings
+}
@@ -0,0 +1,86 @@
+package checks
+
+import (
+	"bufio"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	"strings"
+)
+
+func init() {
+	Register(&Check{
+		Name:        "writable-mounts",
+		Description: "mount points writable by the container user, tested by creating and removing a file",
+		Script:      script("writable-mounts.sh"),
+		Evaluate:    evaluateWritableMounts,
+	})
+}
+
+func evaluateWritableMounts(in Input) []Finding {
+	volumes := podVolumes(in.Pod)
+	declared := map[string]corev1.Volume{}
+	if spec := containerSpec(in.Pod, in.Target.Container); spec != nil {
+		for _, mount := range spec.VolumeMounts {
+			declared[strings.TrimSuffix(mount.MountPath, "/")] = volumes[mount.Name]
+		}
+	}
+	shared := sharedVolumes(in.Pod)
+
+	var findings []Finding
+	var writable []string
+	scanner := bufio.NewScanner(strings.NewReader(in.Status.Stdout))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 3 || fields[0] != "writable" {
+			continue
+		}
+		mountPoint := strings.ReplaceAll(fields[1], `\040`, " ")
+		writable = append(writable, mountPoint)
+
+		volume, ok := declared[mountPoint]
+		switch {
+		case ok && volume.HostPath != nil:
+			finding := Finding{
+				ID:       "writable-hostpath",
+				Severity: SeverityHigh,
+				Title:    fmt.Sprintf("hostPath %s is writable at %s", volume.HostPath.Path, mountPoint),
+			}
+			if isSensitiveHostPath(volume.HostPath.Path) {
+				finding.Severity = SeverityCritical
+			}
+			findings = append(findings, finding)
+		case ok && shared[volume.Name] > 1:
+			findings = append(findings, Finding{
+				ID:       "writable-shared-volume",
+				Severity: SeverityMedium,
+				Title:    fmt.Sprintf("volume %s shared by %d containers is writable at %s", volume.Name, shared[volume.Name], mountPoint),
+			})
+		}
+	}
+
+	if len(writable) > 0 {
+		findings = append(findings, Finding{
+			ID:       "writable-mounts",
+			Severity: SeverityInfo,
+			Title:    fmt.Sprintf("%d writable mounts", len(writable)),
+			Detail:   strings.Join(writable, ", "),
+		})
+	}
+	return findings
+}
+
+// sharedVolumes counts the containers of pod mounting each volume. Init
+// containers are not counted, as they commonly prepare volumes before the
+// others start.
+func sharedVolumes(pod *corev1.Pod) map[string]int {
+	shared := map[string]int{}
+	if pod == nil {
+		return shared
+	}
+	for _, container := range pod.Spec.Containers {
+		for _, mount := range container.VolumeMounts {
+			shared[mount.Name]++
+		}
+	}
+	return shared
+}
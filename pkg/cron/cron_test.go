@@ -0,0 +1,96 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestNext(t *testing.T) {
+	utc := time.UTC
+	kolkata := mustLoad(t, "Asia/Kolkata")     // +05:30
+	kathmandu := mustLoad(t, "Asia/Kathmandu") // +05:45
+	chatham := mustLoad(t, "Pacific/Chatham")  // +12:45 or +13:45
+	newYork := mustLoad(t, "America/New_York")
+
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"next minute", "* * * * *", time.Date(2024, 5, 1, 10, 10, 30, 5, utc), time.Date(2024, 5, 1, 10, 11, 0, 0, utc)},
+		{"step", "*/15 * * * *", time.Date(2024, 5, 1, 10, 10, 0, 0, utc), time.Date(2024, 5, 1, 10, 15, 0, 0, utc)},
+		{"hour step", "0 */2 * * *", time.Date(2024, 5, 1, 10, 0, 0, 0, utc), time.Date(2024, 5, 1, 12, 0, 0, 0, utc)},
+
+		// zones offset by fractions of hours
+		{"hour step +05:30", "0 */2 * * *", time.Date(2024, 5, 1, 10, 10, 0, 0, kolkata), time.Date(2024, 5, 1, 12, 0, 0, 0, kolkata)},
+		{"hour +05:30", "0 9 * * *", time.Date(2024, 5, 1, 9, 0, 0, 0, kolkata), time.Date(2024, 5, 2, 9, 0, 0, 0, kolkata)},
+		{"hour step +05:45", "30 */3 * * *", time.Date(2024, 5, 1, 1, 40, 0, 0, kathmandu), time.Date(2024, 5, 1, 3, 30, 0, 0, kathmandu)},
+		{"daily +12:45", "@daily", time.Date(2024, 5, 1, 23, 59, 0, 0, chatham), time.Date(2024, 5, 2, 0, 0, 0, 0, chatham)},
+		{"hourly +05:45", "@hourly", time.Date(2024, 5, 1, 10, 59, 59, 0, kathmandu), time.Date(2024, 5, 1, 11, 0, 0, 0, kathmandu)},
+
+		// daylight saving time changes: 2024-03-10 02:00 EST became 03:00
+		// EDT, 2024-11-03 02:00 EDT became 01:00 EST
+		{"skipped time", "30 2 * * *", time.Date(2024, 3, 10, 0, 0, 0, 0, newYork), time.Date(2024, 3, 11, 2, 30, 0, 0, newYork)},
+		{"after skipped hour", "0 3 * * *", time.Date(2024, 3, 10, 1, 59, 0, 0, newYork), time.Date(2024, 3, 10, 3, 0, 0, 0, newYork)},
+		{"hourly over skipped hour", "@hourly", time.Date(2024, 3, 10, 1, 30, 0, 0, newYork), time.Date(2024, 3, 10, 3, 0, 0, 0, newYork)},
+		{"repeated time", "30 1 * * *", time.Date(2024, 11, 3, 0, 0, 0, 0, newYork), time.Date(2024, 11, 3, 5, 30, 0, 0, utc)},
+		{"repeated time runs once", "30 1 * * *", time.Date(2024, 11, 3, 5, 30, 0, 0, utc).In(newYork), time.Date(2024, 11, 4, 1, 30, 0, 0, newYork)},
+		{"in repeated hour", "*/20 * * * *", time.Date(2024, 11, 3, 6, 10, 0, 0, utc).In(newYork), time.Date(2024, 11, 3, 6, 20, 0, 0, utc)},
+		{"after repeated hour", "0 2 * * *", time.Date(2024, 11, 3, 1, 0, 0, 0, newYork), time.Date(2024, 11, 3, 2, 0, 0, 0, newYork)},
+
+		// rollover of days, months and years
+		{"end of month", "0 0 31 * *", time.Date(2024, 4, 1, 0, 0, 0, 0, utc), time.Date(2024, 5, 31, 0, 0, 0, 0, utc)},
+		{"leap day", "0 0 29 2 *", time.Date(2025, 1, 1, 0, 0, 0, 0, utc), time.Date(2028, 2, 29, 0, 0, 0, 0, utc)},
+		{"new year", "@yearly", time.Date(2024, 12, 31, 23, 59, 0, 0, utc), time.Date(2025, 1, 1, 0, 0, 0, 0, utc)},
+		{"last minute of year", "59 23 31 12 *", time.Date(2024, 12, 31, 23, 59, 0, 0, utc), time.Date(2025, 12, 31, 23, 59, 0, 0, utc)},
+		{"monday into next year", "0 9 * * 1", time.Date(2023, 12, 31, 12, 0, 0, 0, utc), time.Date(2024, 1, 1, 9, 0, 0, 0, utc)},
+		{"sunday as 7", "0 0 * * 7", time.Date(2024, 5, 1, 0, 0, 0, 0, utc), time.Date(2024, 5, 5, 0, 0, 0, 0, utc)},
+		{"weekday range", "0 8 * * 1-5", time.Date(2024, 5, 3, 9, 0, 0, 0, utc), time.Date(2024, 5, 6, 8, 0, 0, 0, utc)},
+		{"day of month or week", "0 0 13 * 5", time.Date(2024, 9, 1, 0, 0, 0, 0, utc), time.Date(2024, 9, 6, 0, 0, 0, 0, utc)},
+		{"day of month and any week day", "0 0 13 * *", time.Date(2024, 9, 1, 0, 0, 0, 0, utc), time.Date(2024, 9, 13, 0, 0, 0, 0, utc)},
+		{"day of week in month", "0 0 * 2 1", time.Date(2024, 3, 1, 0, 0, 0, 0, utc), time.Date(2025, 2, 3, 0, 0, 0, 0, utc)},
+		{"every", "@every 90s", time.Date(2024, 5, 1, 10, 0, 10, 0, utc), time.Date(2024, 5, 1, 10, 1, 40, 0, utc)},
+	}
+	for _, test := range tests {
+		schedule, err := Parse(test.spec)
+		if err != nil {
+			t.Errorf("%s: Parse(%q): %v", test.name, test.spec, err)
+			continue
+		}
+		if got := schedule.Next(test.from); !got.Equal(test.want) {
+			t.Errorf("%s: Next(%s) of %q = %s, want %s", test.name, test.from, test.spec, got, test.want.In(test.from.Location()))
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@every",
+		"@every -1m",
+		"@weekdays",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded, want an error", spec)
+		}
+	}
+}
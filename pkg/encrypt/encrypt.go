@@ -0,0 +1,108 @@
+// Package encrypt encrypts results written to disk with the age command line
+// tool, for age X25519 and SSH recipients.
+package encrypt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Encrypter encrypts data to recipients.
+type Encrypter struct {
+	// Age is the age binary, looked up in PATH by default.
+	Age string
+	// Recipients are age public keys (age1...), SSH public keys or files
+	// holding recipients, one per line.
+	Recipients []string
+}
+
+// Enabled reports whether recipients are configured.
+func (e *Encrypter) Enabled() bool {
+	return len(e.Recipients) > 0
+}
+
+// Validate checks recipients and that age is available.
+func (e *Encrypter) Validate() error {
+	for _, recipient := range e.Recipients {
+		if strings.HasPrefix(recipient, "age1") || strings.HasPrefix(recipient, "ssh-") {
+			continue
+		}
+		if _, err := os.Stat(recipient); err != nil {
+			return fmt.Errorf("invalid recipient %q, must be an age or SSH public key or a recipients file", recipient)
+		}
+	}
+	if _, err := exec.LookPath(e.age()); err != nil {
+		return fmt.Errorf("encryption requires age: %w", err)
+	}
+	return nil
+}
+
+func (e *Encrypter) age() string {
+	if e.Age == "" {
+		return "age"
+	}
+	return e.Age
+}
+
+func (e *Encrypter) command() *exec.Cmd {
+	var args []string
+	for _, recipient := range e.Recipients {
+		if strings.HasPrefix(recipient, "age1") || strings.HasPrefix(recipient, "ssh-") {
+			args = append(args, "-r", recipient)
+		} else {
+			args = append(args, "-R", recipient)
+		}
+	}
+	return exec.Command(e.age(), args...)
+}
+
+// Encrypt returns data encrypted to all recipients.
+func (e *Encrypter) Encrypt(data []byte) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := e.command()
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("age failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// NewWriter returns a writer encrypting what is written to it into w, the
+// encrypted file is complete once the writer is closed.
+func (e *Encrypter) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	cmd := e.command()
+	cmd.Stdout = w
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &writer{WriteCloser: stdin, cmd: cmd, stderr: stderr}, nil
+}
+
+type writer struct {
+	io.WriteCloser
+	cmd    *exec.Cmd
+	stderr *bytes.Buffer
+}
+
+// Close implements io.Closer, it waits for age to write the end of the
+// encrypted file.
+func (w *writer) Close() error {
+	err := w.WriteCloser.Close()
+	if waitErr := w.cmd.Wait(); waitErr != nil {
+		err = errors.Join(err, fmt.Errorf("age failed: %w: %s", waitErr, strings.TrimSpace(w.stderr.String())))
+	}
+	return err
+}
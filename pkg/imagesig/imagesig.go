@@ -0,0 +1,96 @@
+// Package imagesig verifies signatures and attestations of container images
+// with the cosign command line tool.
+package imagesig
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Verifier verifies images against a public key or, without it, a keyless
+// signing identity.
+type Verifier struct {
+	// Cosign is the cosign binary, looked up in PATH by default.
+	Cosign string
+	// Key is a public key file or KMS reference signatures are verified
+	// with.
+	Key string
+	// Identity and Issuer are the certificate identity and OIDC issuer of
+	// keyless signatures, both are regular expressions.
+	Identity string
+	Issuer   string
+	// AttestationType, when set, verifies attestations of the given
+	// predicate type instead of signatures, e.g. slsaprovenance.
+	AttestationType string
+}
+
+// ErrUnsigned is returned for images without a signature or attestation
+// satisfying the verifier.
+var ErrUnsigned = errors.New("no valid signature")
+
+// Validate checks that either a key or a keyless identity is configured.
+func (v *Verifier) Validate() error {
+	switch {
+	case v.Key == "" && (v.Identity == "" || v.Issuer == ""):
+		return errors.New("image verification requires a key or a certificate identity and OIDC issuer")
+	case v.Key != "" && v.Identity != "":
+		return errors.New("image verification requires either a key or a certificate identity, not both")
+	}
+	return nil
+}
+
+// Verify verifies ref. ErrUnsigned is returned when cosign rejects the image,
+// other errors when cosign could not be run at all.
+func (v *Verifier) Verify(ctx context.Context, ref string) error {
+	cosign := v.Cosign
+	if cosign == "" {
+		cosign = "cosign"
+	}
+
+	args := []string{"verify"}
+	if v.AttestationType != "" {
+		args = []string{"verify-attestation", "--type", v.AttestationType}
+	}
+	if v.Key != "" {
+		args = append(args, "--key", v.Key)
+	} else {
+		args = append(args, "--certificate-identity-regexp", v.Identity, "--certificate-oidc-issuer-regexp", v.Issuer)
+	}
+	args = append(args, "--output", "json", ref)
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, cosign, args...)
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &exitErr):
+		return fmt.Errorf("%w: %s", ErrUnsigned, lastLine(stderr.String()))
+	default:
+		return err
+	}
+}
+
+// Reference returns the reference of an image to verify, preferring the
+// digest it resolved to according to imageID of its container status so that
+// the image actually running is verified rather than what its tag points to
+// now.
+func Reference(image, imageID string) string {
+	imageID = strings.TrimPrefix(imageID, "docker-pullable://")
+	if strings.Contains(imageID, "@sha256:") {
+		return imageID
+	}
+	return image
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return lines[len(lines)-1]
+}
@@ -0,0 +1,28 @@
+package inventory
+
+import (
+	"strings"
+)
+
+// EnvironmentScript prints environment variables of the main process of a
+// container, or of the shell when they are not readable. It is executed with
+// sh.
+const EnvironmentScript = `# variables are separated by NUL in /proc/1/environ and by new lines in env output
+cat /proc/1/environ 2>/dev/null || env
+`
+
+// ParseEnvironment parses the output of EnvironmentScript.
+func ParseEnvironment(output string) map[string]string {
+	separator := "\n"
+	if strings.Contains(output, "\x00") {
+		separator = "\x00"
+	}
+
+	environment := map[string]string{}
+	for _, variable := range strings.Split(output, separator) {
+		if name, value, ok := strings.Cut(variable, "="); ok && name != "" {
+			environment[name] = value
+		}
+	}
+	return environment
+}
@@ -0,0 +1,74 @@
+package inventory
+
+import (
+	"bufio"
+	"strings"
+)
+
+// FingerprintScript prints the OS, kernel, C library and container runtime
+// of a container, it is executed with sh.
+var FingerprintScript = script("fingerprint.sh")
+
+// Fingerprint identifies the environment of a container. The kernel is the
+// one of the node the container has been fingerprinted on.
+type Fingerprint struct {
+	OS          *OSRelease `json:"OS,omitempty"`
+	OSName      string     `json:"OSName,omitempty"`
+	Kernel      string     `json:"Kernel,omitempty"`
+	Arch        string     `json:"Arch,omitempty"`
+	Libc        string     `json:"Libc,omitempty"`
+	LibcVersion string     `json:"LibcVersion,omitempty"`
+	// Runtime lists hints of the container runtime found in the container.
+	Runtime []string `json:"Runtime,omitempty"`
+	Cgroup  string   `json:"Cgroup,omitempty"`
+}
+
+// ParseFingerprint parses the output of FingerprintScript.
+func ParseFingerprint(output string) *Fingerprint {
+	fingerprint := &Fingerprint{}
+	os := &OSRelease{}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "\t")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "os-id":
+			os.ID = value
+		case "os-version":
+			os.VersionID = value
+		case "os-name":
+			fingerprint.OSName = value
+		case "kernel":
+			fingerprint.Kernel = value
+		case "arch":
+			fingerprint.Arch = value
+		case "libc":
+			fingerprint.Libc = value
+		case "libc-version":
+			fingerprint.LibcVersion = value
+		case "runtime":
+			if !contains(fingerprint.Runtime, value) {
+				fingerprint.Runtime = append(fingerprint.Runtime, value)
+			}
+		case "cgroup":
+			fingerprint.Cgroup = value
+		}
+	}
+
+	if os.ID != "" {
+		fingerprint.OS = os
+	}
+	return fingerprint
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,17 @@
+// Package inventory collects software inventories of containers.
+package inventory
+
+import (
+	"embed"
+)
+
+//go:embed scripts
+var scripts embed.FS
+
+func script(name string) string {
+	body, err := scripts.ReadFile("scripts/" + name)
+	if err != nil {
+		panic(err)
+	}
+	return string(body)
+}
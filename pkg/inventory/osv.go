@@ -0,0 +1,286 @@
+package inventory
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Vulnerability is a known vulnerability of an installed package.
+type Vulnerability struct {
+	ID       string   `json:"ID"`
+	Aliases  []string `json:"Aliases,omitempty"`
+	Package  string   `json:"Package"`
+	Version  string   `json:"Version"`
+	Fixed    string   `json:"Fixed,omitempty"`
+	Summary  string   `json:"Summary,omitempty"`
+	Severity string   `json:"Severity,omitempty"`
+}
+
+// osvEntry is the subset of the OSV schema (https://ossf.github.io/osv-schema)
+// used for matching.
+type osvEntry struct {
+	ID       string   `json:"id"`
+	Aliases  []string `json:"aliases"`
+	Summary  string   `json:"summary"`
+	Details  string   `json:"details"`
+	Affected []struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+		Ranges []struct {
+			Type   string `json:"type"`
+			Events []struct {
+				Introduced   string `json:"introduced"`
+				Fixed        string `json:"fixed"`
+				LastAffected string `json:"last_affected"`
+			} `json:"events"`
+		} `json:"ranges"`
+		Versions          []string       `json:"versions"`
+		EcosystemSpecific map[string]any `json:"ecosystem_specific"`
+		DatabaseSpecific  map[string]any `json:"database_specific"`
+	} `json:"affected"`
+	Severity []struct {
+		Type  string `json:"type"`
+		Score string `json:"score"`
+	} `json:"severity"`
+	DatabaseSpecific map[string]any `json:"database_specific"`
+}
+
+// VulnDB is an offline copy of the OSV database.
+type VulnDB struct {
+	// entries by ecosystem family (e.g. "Debian") and package name
+	entries map[string]map[string][]*osvEntry
+}
+
+// osEcosystems maps ID of /etc/os-release to OSV ecosystems.
+var osEcosystems = map[string]string{
+	"alpine":     "Alpine",
+	"debian":     "Debian",
+	"ubuntu":     "Ubuntu",
+	"rocky":      "Rocky Linux",
+	"almalinux":  "AlmaLinux",
+	"rhel":       "Red Hat",
+	"opensuse":   "openSUSE",
+	"sles":       "SUSE",
+	"wolfi":      "Wolfi",
+	"chainguard": "Chainguard",
+	"photon":     "Photon OS",
+	"mariner":    "Mariner",
+}
+
+// LoadVulnDB loads OSV entries from a directory of JSON files or from a zip
+// archive as provided by the OSV project for offline use
+// (https://osv-vulnerabilities.storage.googleapis.com/<ecosystem>/all.zip).
+func LoadVulnDB(path string) (*VulnDB, error) {
+	db := &VulnDB{entries: map[string]map[string][]*osvEntry{}}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		archive, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, fmt.Errorf("vulnerability database must be a directory or a zip archive: %w", err)
+		}
+		defer archive.Close()
+		return db, db.loadFS(archive)
+	}
+
+	return db, db.loadFS(os.DirFS(path))
+}
+
+func (db *VulnDB) loadFS(fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".json" {
+			return err
+		}
+
+		f, err := fsys.Open(path)
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+
+		entry := &osvEntry{}
+		if err := json.Unmarshal(data, entry); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		db.add(entry)
+		return nil
+	})
+}
+
+func (db *VulnDB) add(entry *osvEntry) {
+	for _, affected := range entry.Affected {
+		family, _, _ := strings.Cut(affected.Package.Ecosystem, ":")
+		if db.entries[family] == nil {
+			db.entries[family] = map[string][]*osvEntry{}
+		}
+		packages := db.entries[family]
+		if n := len(packages[affected.Package.Name]); n > 0 && packages[affected.Package.Name][n-1] == entry {
+			continue
+		}
+		packages[affected.Package.Name] = append(packages[affected.Package.Name], entry)
+	}
+}
+
+// Match returns known vulnerabilities of installed packages. Packages of
+// distributions without an OSV ecosystem are not matched.
+func (db *VulnDB) Match(packages *Packages) []Vulnerability {
+	if packages == nil || packages.OS == nil {
+		return nil
+	}
+	family, ok := osEcosystems[packages.OS.ID]
+	if !ok {
+		return nil
+	}
+	release := osvRelease(family, packages.OS.VersionID)
+
+	var vulnerabilities []Vulnerability
+	for _, p := range packages.Packages {
+		// advisories of distributions are mostly published for source packages
+		names := []string{p.Name}
+		if p.Source != "" && p.Source != p.Name {
+			names = append(names, p.Source)
+		}
+
+		seen := map[string]bool{}
+		for _, name := range names {
+			for _, entry := range db.entries[family][name] {
+				if seen[entry.ID] {
+					continue
+				}
+				if fixed, ok := entry.affects(family, release, name, p.Version); ok {
+					seen[entry.ID] = true
+					vulnerabilities = append(vulnerabilities, Vulnerability{
+						ID:       entry.ID,
+						Aliases:  entry.Aliases,
+						Package:  p.Name,
+						Version:  p.Version,
+						Fixed:    fixed,
+						Summary:  entry.summary(),
+						Severity: entry.severity(),
+					})
+				}
+			}
+		}
+	}
+
+	sort.Slice(vulnerabilities, func(i, j int) bool {
+		if vulnerabilities[i].Package != vulnerabilities[j].Package {
+			return vulnerabilities[i].Package < vulnerabilities[j].Package
+		}
+		return vulnerabilities[i].ID < vulnerabilities[j].ID
+	})
+	return vulnerabilities
+}
+
+// osvRelease returns the release part of OSV ecosystems for VERSION_ID.
+func osvRelease(family, versionID string) string {
+	switch family {
+	case "Alpine":
+		// Alpine:v3.18
+		parts := strings.SplitN(versionID, ".", 3)
+		if len(parts) >= 2 {
+			return "v" + parts[0] + "." + parts[1]
+		}
+	case "Debian":
+		// Debian:12
+		major, _, _ := strings.Cut(versionID, ".")
+		return major
+	}
+	return versionID
+}
+
+// affects reports whether version of the named package is affected by e and
+// returns the version fixing it.
+func (e *osvEntry) affects(family, release, name, version string) (string, bool) {
+	for _, affected := range e.Affected {
+		ecosystemFamily, ecosystemRelease, _ := strings.Cut(affected.Package.Ecosystem, ":")
+		if ecosystemFamily != family || affected.Package.Name != name {
+			continue
+		}
+		if ecosystemRelease != "" && release != "" && ecosystemRelease != release && !strings.HasPrefix(ecosystemRelease, release+":") {
+			continue
+		}
+
+		for _, v := range affected.Versions {
+			if v == version {
+				return "", true
+			}
+		}
+
+		for _, r := range affected.Ranges {
+			if r.Type != "ECOSYSTEM" {
+				continue
+			}
+			// events form [introduced, fixed) or [introduced, last_affected]
+			// intervals, an interval without an end is still open
+			introduced, open := "", false
+			for _, event := range r.Events {
+				switch {
+				case event.Introduced != "":
+					introduced, open = event.Introduced, true
+				case !open || !atLeast(version, introduced):
+					open = false
+				case event.Fixed != "" && CompareVersions(version, event.Fixed) < 0:
+					return event.Fixed, true
+				case event.LastAffected != "" && CompareVersions(version, event.LastAffected) <= 0:
+					return "", true
+				default:
+					open = false
+				}
+			}
+			if open && atLeast(version, introduced) {
+				return "", true
+			}
+		}
+	}
+	return "", false
+}
+
+// atLeast reports whether version is not lower than introduced, "0" stands
+// for all versions.
+func atLeast(version, introduced string) bool {
+	return introduced == "0" || CompareVersions(version, introduced) >= 0
+}
+
+func (e *osvEntry) summary() string {
+	if e.Summary != "" {
+		return e.Summary
+	}
+	details, _, _ := strings.Cut(e.Details, "\n")
+	if len(details) > 200 {
+		details = details[:200] + "..."
+	}
+	return details
+}
+
+func (e *osvEntry) severity() string {
+	if severity, ok := e.DatabaseSpecific["severity"].(string); ok {
+		return severity
+	}
+	for _, affected := range e.Affected {
+		if severity, ok := affected.EcosystemSpecific["urgency"].(string); ok {
+			return severity
+		}
+	}
+	if len(e.Severity) > 0 {
+		return e.Severity[0].Score
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+package inventory
+
+import (
+	"bufio"
+	"sort"
+	"strings"
+)
+
+// PackagesScript prints packages installed in a container, it is executed
+// with sh.
+var PackagesScript = script("packages.sh")
+
+// Package is an installed package.
+type Package struct {
+	Name    string `json:"Name"`
+	Version string `json:"Version"`
+	Arch    string `json:"Arch,omitempty"`
+	// Source is the name of the source package the package was built from.
+	Source string `json:"Source,omitempty"`
+}
+
+// OSRelease identifies the distribution of a container by ID and VERSION_ID
+// of /etc/os-release.
+type OSRelease struct {
+	ID        string `json:"ID"`
+	VersionID string `json:"VersionID"`
+}
+
+// Packages are packages installed by a package manager.
+type Packages struct {
+	// Manager is one of apk, dpkg, rpm or pacman, empty when no package
+	// manager has been detected.
+	Manager  string     `json:"Manager"`
+	OS       *OSRelease `json:"OS,omitempty"`
+	Packages []Package  `json:"Packages"`
+}
+
+// ParsePackages parses the output of PackagesScript. Packages are sorted by
+// their names.
+func ParsePackages(output string) *Packages {
+	packages := &Packages{Packages: []Package{}}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		switch {
+		case fields[0] == "manager" && len(fields) == 2:
+			packages.Manager = fields[1]
+		case fields[0] == "os" && len(fields) == 3:
+			packages.OS = &OSRelease{ID: fields[1], VersionID: fields[2]}
+		case fields[0] == "pkg" && len(fields) == 6:
+			status := strings.TrimSpace(fields[4])
+			// dpkg keeps removed packages with their configuration files
+			if status != "" && !strings.HasSuffix(status, " installed") {
+				continue
+			}
+			packages.Packages = append(packages.Packages, Package{
+				Name:    strings.TrimSpace(fields[1]),
+				Version: strings.TrimPrefix(strings.TrimSpace(fields[2]), "0:"),
+				Arch:    strings.TrimSpace(fields[3]),
+				Source:  sourceName(strings.TrimSpace(fields[5])),
+			})
+		}
+	}
+
+	sort.Slice(packages.Packages, func(i, j int) bool {
+		return packages.Packages[i].Name < packages.Packages[j].Name
+	})
+	return packages
+}
+
+// sourceName returns the name of a source package as recorded by dpkg
+// ("name (version)") or rpm ("name-version-release.src.rpm").
+func sourceName(source string) string {
+	if name, _, ok := strings.Cut(source, " "); ok {
+		return name
+	}
+	if strings.HasSuffix(source, ".src.rpm") {
+		source = strings.TrimSuffix(source, ".src.rpm")
+		for i := 0; i < 2; i++ {
+			if dash := strings.LastIndex(source, "-"); dash > 0 {
+				source = source[:dash]
+			}
+		}
+	}
+	return source
+}
@@ -0,0 +1,105 @@
+package inventory
+
+import (
+	"bufio"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ProcessesScript prints processes running in a container, it is executed
+// with sh.
+var ProcessesScript = script("processes.sh")
+
+// Process is a process running in a container.
+type Process struct {
+	PID     int    `json:"PID"`
+	PPID    int    `json:"PPID"`
+	User    string `json:"User"`
+	Name    string `json:"Name"`
+	Cmdline string `json:"Cmdline,omitempty"`
+}
+
+// UnexpectedProcess is a process which should not run in a container.
+type UnexpectedProcess struct {
+	Process
+	Reason string `json:"Reason"`
+}
+
+// unexpectedDaemons are names of processes unexpected in containers.
+var unexpectedDaemons = map[string]string{
+	"sshd":     "SSH server",
+	"dropbear": "SSH server",
+	"telnetd":  "telnet server",
+	"inetd":    "inetd super-server",
+	"xinetd":   "inetd super-server",
+	"vsftpd":   "FTP server",
+	"ftpd":     "FTP server",
+	"cron":     "cron daemon",
+	"crond":    "cron daemon",
+	"atd":      "at daemon",
+	"systemd":  "init system",
+	"tmux":     "terminal multiplexer",
+	"screen":   "terminal multiplexer",
+	"apt":      "package manager",
+	"apt-get":  "package manager",
+	"dpkg":     "package manager",
+	"yum":      "package manager",
+	"dnf":      "package manager",
+	"rpm":      "package manager",
+	"apk":      "package manager",
+	"pip":      "package manager",
+	"npm":      "package manager",
+}
+
+// ParseProcesses parses the output of ProcessesScript.
+func ParseProcesses(output string) []Process {
+	processes := []Process{}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\t", 6)
+		if len(fields) != 6 || fields[0] != "proc" {
+			continue
+		}
+
+		pid, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		ppid, _ := strconv.Atoi(fields[2])
+		processes = append(processes, Process{
+			PID:     pid,
+			PPID:    ppid,
+			User:    fields[3],
+			Name:    fields[4],
+			Cmdline: strings.TrimSpace(strings.ReplaceAll(fields[5], "\x00", " ")),
+		})
+	}
+
+	return processes
+}
+
+// UnexpectedProcesses returns daemons, package managers and other processes
+// which are not expected to run in a container.
+func UnexpectedProcesses(processes []Process) []UnexpectedProcess {
+	var unexpected []UnexpectedProcess
+	for _, p := range processes {
+		name := p.Name
+		if fields := strings.Fields(p.Cmdline); len(fields) > 0 {
+			name = path.Base(fields[0])
+		}
+
+		reason, ok := unexpectedDaemons[name]
+		if !ok {
+			reason, ok = unexpectedDaemons[p.Name]
+		}
+		if !ok && (name == "nc" || name == "ncat" || name == "socat") && strings.Contains(p.Cmdline, " -l") {
+			reason, ok = "listening netcat", true
+		}
+		if ok {
+			unexpected = append(unexpected, UnexpectedProcess{Process: p, Reason: reason})
+		}
+	}
+	return unexpected
+}
@@ -0,0 +1,76 @@
+# prints key<TAB>value lines describing the OS, kernel, C library and
+# container runtime of a container
+
+for release in /etc/os-release /usr/lib/os-release; do
+	[ -r "$release" ] || continue
+	while IFS='=' read -r key value; do
+		value=${value#[\"\']}
+		value=${value%[\"\']}
+		case "$key" in
+		ID) printf 'os-id\t%s\n' "$value" ;;
+		VERSION_ID) printf 'os-version\t%s\n' "$value" ;;
+		PRETTY_NAME) printf 'os-name\t%s\n' "$value" ;;
+		esac
+	done <"$release"
+	break
+done
+
+read -r kernel </proc/sys/kernel/osrelease 2>/dev/null && printf 'kernel\t%s\n' "$kernel"
+command -v uname >/dev/null 2>&1 && printf 'arch\t%s\n' "$(uname -m)"
+
+# C library: musl has its dynamic loader in /lib, glibc answers getconf or
+# prints its version when libc.so.6 is executed
+for loader in /lib/ld-musl-*.so.1; do
+	[ -e "$loader" ] || continue
+	printf 'libc\tmusl\n'
+	"$loader" 2>&1 | while read -r key value; do
+		[ "$key" = "Version" ] && printf 'libc-version\t%s\n' "$value"
+	done
+	break
+done
+if command -v getconf >/dev/null 2>&1 && version=$(getconf GNU_LIBC_VERSION 2>/dev/null); then
+	printf 'libc\tglibc\n'
+	printf 'libc-version\t%s\n' "${version#glibc }"
+else
+	for libc in /lib/*/libc.so.6 /lib64/libc.so.6 /lib/libc.so.6 /usr/lib/*/libc.so.6 /usr/lib64/libc.so.6; do
+		[ -x "$libc" ] || continue
+		printf 'libc\tglibc\n'
+		"$libc" 2>/dev/null | while read -r line; do
+			case "$line" in
+			*"release version "*)
+				version=${line##*release version }
+				printf 'libc-version\t%s\n' "${version%%[!0-9.]*}"
+				;;
+			esac
+			break
+		done
+		break
+	done
+fi
+
+# container runtime hints
+[ -e /.dockerenv ] && printf 'runtime\tdocker\n'
+[ -e /run/.containerenv ] && printf 'runtime\tpodman or cri-o\n'
+if [ -r /proc/1/cgroup ]; then
+	while IFS= read -r line; do
+		case "$line" in
+		*cri-containerd*) printf 'runtime\tcontainerd\n' ;;
+		*crio-*) printf 'runtime\tcri-o\n' ;;
+		*docker*) printf 'runtime\tdocker\n' ;;
+		esac
+	done </proc/1/cgroup
+fi
+if [ -r /proc/self/mountinfo ]; then
+	while read -r id parent dev root mountpoint rest; do
+		case "$root" in
+		*/io.containerd.*) printf 'runtime\tcontainerd\n' ;;
+		*/containers/storage/*) printf 'runtime\tcri-o\n' ;;
+		*/docker/containers/*) printf 'runtime\tdocker\n' ;;
+		esac
+	done </proc/self/mountinfo
+fi
+if [ -e /sys/fs/cgroup/cgroup.controllers ]; then
+	printf 'cgroup\tv2\n'
+elif [ -d /sys/fs/cgroup ]; then
+	printf 'cgroup\tv1\n'
+fi
@@ -0,0 +1,61 @@
+# stanzas FILE NAME-KEY VERSION-KEY ARCH-KEY STATUS-KEY SOURCE-KEY prints
+# packages of a database made of blank line separated key/value stanzas
+stanzas() {
+	name= version= arch= status= source=
+	while IFS= read -r line || [ -n "$line" ]; do
+		case "$line" in
+		"$2"*) name=${line#"$2"} ;;
+		"$3"*) version=${line#"$3"} ;;
+		"$4"*) arch=${line#"$4"} ;;
+		"$5"*) status=${line#"$5"} ;;
+		"$6"*) source=${line#"$6"} ;;
+		"")
+			[ -n "$name" ] && printf 'pkg\t%s\t%s\t%s\t%s\t%s\n' "$name" "$version" "$arch" "$status" "$source"
+			name= version= arch= status= source=
+			;;
+		esac
+	done <"$1"
+	[ -n "$name" ] && printf 'pkg\t%s\t%s\t%s\t%s\t%s\n' "$name" "$version" "$arch" "$status" "$source"
+	return 0
+}
+
+if [ -r /etc/os-release ]; then
+	(
+		. /etc/os-release
+		printf 'os\t%s\t%s\n' "$ID" "$VERSION_ID"
+	)
+fi
+
+if [ -f /lib/apk/db/installed ]; then
+	printf 'manager\tapk\n'
+	stanzas /lib/apk/db/installed P: V: A: s:status: o:
+elif [ -f /var/lib/dpkg/status ] || [ -d /var/lib/dpkg/status.d ]; then
+	printf 'manager\tdpkg\n'
+	# distroless images keep one file per package in status.d
+	for db in /var/lib/dpkg/status /var/lib/dpkg/status.d/*; do
+		[ -f "$db" ] && stanzas "$db" Package: Version: Architecture: Status: Source:
+	done
+elif command -v rpm >/dev/null 2>&1; then
+	printf 'manager\trpm\n'
+	rpm -qa --qf 'pkg\t%{NAME}\t%{EPOCHNUM}:%{VERSION}-%{RELEASE}\t%{ARCH}\t\t%{SOURCERPM}\n'
+elif [ -d /var/lib/pacman/local ]; then
+	printf 'manager\tpacman\n'
+	for desc in /var/lib/pacman/local/*/desc; do
+		[ -f "$desc" ] || continue
+		name= version= arch= key=
+		while IFS= read -r line; do
+			case "$line" in
+			%*%) key=$line ;;
+			"") key= ;;
+			*)
+				case "$key" in
+				%NAME%) name=$line ;;
+				%VERSION%) version=$line ;;
+				%ARCH%) arch=$line ;;
+				esac
+				;;
+			esac
+		done <"$desc"
+		printf 'pkg\t%s\t%s\t%s\t\t\n' "$name" "$version" "$arch"
+	done
+fi
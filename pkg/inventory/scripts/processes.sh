@@ -0,0 +1,24 @@
+# walk /proc so that no ps is needed, command lines keep their NUL separators
+found=
+for dir in /proc/[0-9]*; do
+	[ -r "$dir/status" ] || continue
+	pid=${dir#/proc/} name= ppid= uid=
+	while read -r key value rest; do
+		case "$key" in
+		Name:) name=$value ;;
+		PPid:) ppid=$value ;;
+		Uid:) uid=$value ;;
+		esac
+	done <"$dir/status" 2>/dev/null
+	[ "$pid" = "$$" ] && continue
+	printf 'proc\t%s\t%s\t%s\t%s\t' "$pid" "$ppid" "$uid" "$name"
+	cat "$dir/cmdline" 2>/dev/null
+	printf '\n'
+	found=1
+done
+
+if [ -z "$found" ] && command -v ps >/dev/null 2>&1; then
+	ps -o pid= -o ppid= -o user= -o comm= -o args= 2>/dev/null | while read -r pid ppid user comm args; do
+		printf 'proc\t%s\t%s\t%s\t%s\t%s\n' "$pid" "$ppid" "$user" "$comm" "$args"
+	done
+fi
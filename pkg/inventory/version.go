@@ -0,0 +1,111 @@
+package inventory
+
+import (
+	"strings"
+)
+
+// CompareVersions compares package versions using the dpkg algorithm, which
+// also orders apk and rpm versions well enough for vulnerability matching.
+// It returns -1, 0 or 1 when a is lower, equal or greater than b.
+func CompareVersions(a, b string) int {
+	aEpoch, aVersion := splitEpoch(a)
+	bEpoch, bVersion := splitEpoch(b)
+	if c := compareSegment(aEpoch, bEpoch); c != 0 {
+		return c
+	}
+
+	aUpstream, aRevision := splitRevision(aVersion)
+	bUpstream, bRevision := splitRevision(bVersion)
+	if c := compareSegment(aUpstream, bUpstream); c != 0 {
+		return c
+	}
+	return compareSegment(aRevision, bRevision)
+}
+
+func splitEpoch(version string) (string, string) {
+	if epoch, rest, ok := strings.Cut(version, ":"); ok {
+		return epoch, rest
+	}
+	return "0", version
+}
+
+func splitRevision(version string) (string, string) {
+	if dash := strings.LastIndex(version, "-"); dash >= 0 {
+		return version[:dash], version[dash+1:]
+	}
+	return version, ""
+}
+
+// compareSegment implements verrevcmp of dpkg: non-digit parts are compared
+// lexically with letters sorting before other characters and '~' before
+// anything, digit parts are compared numerically.
+func compareSegment(a, b string) int {
+	for a != "" || b != "" {
+		for (a != "" && !isDigit(a[0])) || (b != "" && !isDigit(b[0])) {
+			ac, bc := order(a), order(b)
+			if ac != bc {
+				return sign(ac - bc)
+			}
+			a, b = advance(a), advance(b)
+		}
+
+		for a != "" && a[0] == '0' {
+			a = a[1:]
+		}
+		for b != "" && b[0] == '0' {
+			b = b[1:]
+		}
+
+		diff := 0
+		for a != "" && isDigit(a[0]) && b != "" && isDigit(b[0]) {
+			if diff == 0 {
+				diff = int(a[0]) - int(b[0])
+			}
+			a, b = a[1:], b[1:]
+		}
+		if a != "" && isDigit(a[0]) {
+			return 1
+		}
+		if b != "" && isDigit(b[0]) {
+			return -1
+		}
+		if diff != 0 {
+			return sign(diff)
+		}
+	}
+	return 0
+}
+
+func order(s string) int {
+	switch {
+	case s == "", isDigit(s[0]):
+		return 0
+	case s[0] >= 'A' && s[0] <= 'Z', s[0] >= 'a' && s[0] <= 'z':
+		return int(s[0])
+	case s[0] == '~':
+		return -1
+	default:
+		return int(s[0]) + 256
+	}
+}
+
+func advance(s string) string {
+	if s == "" || isDigit(s[0]) {
+		return s
+	}
+	return s[1:]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func sign(i int) int {
+	switch {
+	case i < 0:
+		return -1
+	case i > 0:
+		return 1
+	}
+	return 0
+}
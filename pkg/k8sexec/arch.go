@@ -0,0 +1,116 @@
+package k8sexec
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// archPlaceholder matches {{arch}}, replaced with the architecture of the
+// container, and {{arch:amd64=X,arm64=Y}}, replaced with the variant of it.
+var archPlaceholder = regexp.MustCompile(`\{\{arch(?::([^}]*))?\}\}`)
+
+// unameArchs maps machines reported by uname -m to architectures in the
+// notation of Go and of the kubernetes.io/arch label.
+var unameArchs = map[string]string{
+	"x86_64":  "amd64",
+	"aarch64": "arm64",
+	"arm64":   "arm64",
+	"armv7l":  "arm",
+	"armv6l":  "arm",
+	"i386":    "386",
+	"i686":    "386",
+	"ppc64le": "ppc64le",
+	"s390x":   "s390x",
+	"riscv64": "riscv64",
+}
+
+// ArchExecutor runs commands with another executor replacing placeholders
+// of the architecture of the container in their arguments, so that a
+// command can select binaries built for it in clusters with nodes of
+// different architectures:
+//
+//	/tmp/scanner-{{arch}}                          /tmp/scanner-arm64
+//	{{arch:amd64=scanner-x64,arm64=scanner-a64}}   scanner-a64
+//
+// The architecture is returned by a function, e.g. from the
+// kubernetes.io/arch label of the node, or probed with uname -m otherwise.
+// Placeholders are left as they are when it cannot be determined.
+type ArchExecutor struct {
+	executor Executor
+	arch     func(target Target) string
+
+	mu     sync.Mutex
+	probed map[Target]string
+}
+
+// NewArchExecutor creates an ArchExecutor for commands run by executor. arch
+// returns the architecture of a target, an empty string when not known; it
+// may be nil.
+func NewArchExecutor(executor Executor, arch func(target Target) string) *ArchExecutor {
+	return &ArchExecutor{executor: executor, arch: arch, probed: map[Target]string{}}
+}
+
+// Run implements Executor.
+func (e *ArchExecutor) Run(ctx context.Context, target Target, cmd Command, streams IO) (Result, error) {
+	if !archPlaceholder.MatchString(strings.Join(cmd.Args, "\x00")) {
+		return e.executor.Run(ctx, target, cmd, streams)
+	}
+
+	arch := e.targetArch(ctx, target)
+	if arch == "" {
+		return e.executor.Run(ctx, target, cmd, streams)
+	}
+	args := make([]string, len(cmd.Args))
+	for i, arg := range cmd.Args {
+		args[i] = archPlaceholder.ReplaceAllStringFunc(arg, func(placeholder string) string {
+			variants := archPlaceholder.FindStringSubmatch(placeholder)[1]
+			if variants == "" {
+				return arch
+			}
+			for _, variant := range strings.Split(variants, ",") {
+				if name, value, ok := strings.Cut(variant, "="); ok && strings.TrimSpace(name) == arch {
+					return strings.TrimSpace(value)
+				}
+			}
+			return placeholder
+		})
+	}
+	return e.executor.Run(ctx, target, Command{Args: args}, streams)
+}
+
+// targetArch returns the architecture of target, probing it once when not
+// known.
+func (e *ArchExecutor) targetArch(ctx context.Context, target Target) string {
+	if e.arch != nil {
+		if arch := e.arch(target); arch != "" {
+			return arch
+		}
+	}
+
+	e.mu.Lock()
+	arch, ok := e.probed[target]
+	e.mu.Unlock()
+	if ok {
+		return arch
+	}
+
+	var stdout bytes.Buffer
+	result, err := e.executor.Run(ctx, target, Command{Args: []string{"uname", "-m"}}, IO{Stdout: &stdout})
+	if err != nil && ctx.Err() != nil {
+		return ""
+	}
+	if err == nil && result.ExitCode == 0 {
+		machine := strings.TrimSpace(stdout.String())
+		if arch = unameArchs[machine]; arch == "" {
+			arch = machine
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.probed[target] = arch
+	return arch
+}
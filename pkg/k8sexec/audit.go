@@ -0,0 +1,88 @@
+package k8sexec
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// AuditEntry is a line of an audit log written by AuditExecutor.
+type AuditEntry struct {
+	Time   time.Time `json:"Time"`
+	User   string    `json:"User"`
+	Target Target    `json:"Target"`
+	Args   []string  `json:"Args"`
+	// StdinSHA256 identifies the standard input, e.g. a script, without
+	// logging it.
+	StdinSHA256 string        `json:"StdinSHA256,omitempty"`
+	ExitCode    int           `json:"ExitCode"`
+	Error       string        `json:"Error,omitempty"`
+	ErrorKind   string        `json:"ErrorKind,omitempty"`
+	Duration    time.Duration `json:"Duration"`
+	// Override is set on entries of guard rules overridden for a command,
+	// they precede the entry of its execution.
+	Override string `json:"Override,omitempty"`
+}
+
+// AuditExecutor runs commands with another executor and writes an entry for
+// every command, including refused ones, as a JSON line to a writer.
+type AuditExecutor struct {
+	executor Executor
+	user     string
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewAuditExecutor creates an AuditExecutor logging commands run by executor
+// on behalf of user to w.
+func NewAuditExecutor(executor Executor, w io.Writer, user string) *AuditExecutor {
+	return &AuditExecutor{executor: executor, w: w, user: user}
+}
+
+// Run implements Executor.
+func (e *AuditExecutor) Run(ctx context.Context, target Target, cmd Command, streams IO) (Result, error) {
+	entry := &AuditEntry{Time: time.Now(), User: e.user, Target: target, Args: cmd.Args}
+	if streams.Stdin != nil {
+		stdin, err := io.ReadAll(streams.Stdin)
+		if err != nil {
+			return Result{ExitCode: -1}, err
+		}
+		streams.Stdin = bytes.NewReader(stdin)
+		sum := sha256.Sum256(stdin)
+		entry.StdinSHA256 = hex.EncodeToString(sum[:])
+	}
+
+	result, err := e.executor.Run(ctx, target, cmd, streams)
+	entry.Duration = time.Since(entry.Time)
+	entry.ExitCode = result.ExitCode
+	if err != nil {
+		entry.Error = err.Error()
+		entry.ErrorKind = ErrorKind(classify(err))
+	}
+	e.write(entry)
+
+	return result, err
+}
+
+// Override logs that rules of a guard have been overridden for cmd, it can
+// be used as Guard.OnOverride.
+func (e *AuditExecutor) Override(target Target, cmd Command, reason string) {
+	e.write(&AuditEntry{Time: time.Now(), User: e.user, Target: target, Args: cmd.Args, Override: reason})
+}
+
+func (e *AuditExecutor) write(entry *AuditEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, _ = e.w.Write(append(line, '\n'))
+}
@@ -0,0 +1,64 @@
+package k8sexec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+)
+
+// copySourceScript writes a tar archive of the file or directory $0 to
+// stdout.
+const copySourceScript = `tar cf - -C "$(dirname "$0")" "$(basename "$0")"`
+
+// copyDestinationScript extracts a tar archive holding $1 from stdin next to
+// $0 and moves it to $0, or into $0 when it is a directory, so that a
+// partially extracted archive is never left at $0.
+const copyDestinationScript = `tmp=$(mktemp -d "$(dirname "$0")/.kubex-cp.XXXXXX") || exit 1
+tar xf - -C "$tmp" && mv "$tmp/$1" "$0"
+rc=$?
+rm -rf "$tmp"
+exit $rc`
+
+// Upload writes the content of r to path in the container of target and
+// sets its mode. It requires sh and cat in the container.
+func (k *K8SExec) Upload(ctx context.Context, target Target, r io.Reader, path string, mode os.FileMode) error {
+	script := fmt.Sprintf(`cat > "$0" && chmod %o "$0"`, mode.Perm())
+	return k.run(ctx, target, []string{"sh", "-c", script, path}, r, nil)
+}
+
+// Copy copies the file or directory fromPath in the container of from to
+// toPath in the container of to, streaming it as a tar archive between both
+// containers without passing it through local disk. When toPath is an
+// existing directory, it is copied into it. Both containers need sh and tar,
+// the destination mktemp as well.
+func (k *K8SExec) Copy(ctx context.Context, from Target, fromPath string, to Target, toPath string) (*PipeStatus, error) {
+	fromPath, toPath = path.Clean(fromPath), path.Clean(toPath)
+	if fromPath == "/" || fromPath == "." || toPath == "/" || toPath == "." {
+		return nil, fmt.Errorf("cannot copy %s to %s, paths must name a file or directory", fromPath, toPath)
+	}
+	return k.Pipe(ctx,
+		from, []string{"sh", "-c", copySourceScript, fromPath},
+		to, []string{"sh", "-c", copyDestinationScript, toPath, path.Base(fromPath)},
+	), nil
+}
+
+// Remove removes path in the container of target.
+func (k *K8SExec) Remove(ctx context.Context, target Target, path string) error {
+	return k.run(ctx, target, []string{"rm", "-f", path}, nil, nil)
+}
+
+// run executes cmd in target and fails unless it succeeded.
+func (k *K8SExec) run(ctx context.Context, target Target, cmd []string, stdin io.Reader, stdout io.Writer) error {
+	var stderr bytes.Buffer
+	result, err := k.executor.Run(ctx, k.qualify(target), Command{Args: cmd}, IO{Stdin: stdin, Stdout: stdout, Stderr: &stderr})
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("%w: %s", &ErrNonZeroExit{Code: result.ExitCode}, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package k8sexec
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// envNamePattern matches valid names of environment variables.
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// shells are interpreters scripts are streamed to on stdin.
+var shells = map[string]bool{"sh": true, "bash": true, "ash": true, "dash": true, "zsh": true, "ksh": true}
+
+// EnvExecutor runs commands with another executor with additional
+// environment variables. Scripts streamed to a shell on stdin are preceded
+// by exports of them, other commands are wrapped with env.
+type EnvExecutor struct {
+	executor Executor
+	env      []string
+}
+
+// NewEnvExecutor creates an EnvExecutor setting env, a list of KEY=VALUE
+// pairs, for commands run by executor.
+func NewEnvExecutor(executor Executor, env []string) (*EnvExecutor, error) {
+	for _, pair := range env {
+		name, _, ok := strings.Cut(pair, "=")
+		if !ok || !envNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid environment variable %q, must be KEY=VALUE", pair)
+		}
+	}
+	return &EnvExecutor{executor: executor, env: env}, nil
+}
+
+// Run implements Executor.
+func (e *EnvExecutor) Run(ctx context.Context, target Target, cmd Command, streams IO) (Result, error) {
+	if len(cmd.Args) == 1 && shells[path.Base(cmd.Args[0])] && streams.Stdin != nil {
+		var preamble strings.Builder
+		for _, pair := range e.env {
+			name, value, _ := strings.Cut(pair, "=")
+			fmt.Fprintf(&preamble, "export %s='%s'\n", name, strings.ReplaceAll(value, "'", `'\''`))
+		}
+		streams.Stdin = io.MultiReader(strings.NewReader(preamble.String()), streams.Stdin)
+	} else {
+		cmd = Command{Args: append(append([]string{"env"}, e.env...), cmd.Args...)}
+	}
+	return e.executor.Run(ctx, target, cmd, streams)
+}
@@ -0,0 +1,129 @@
+package k8sexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// Errors returned by K8SExec. Failures of the Kubernetes API or of the exec
+// stream wrap one of them, so that they can be told apart from commands
+// exiting with a non-zero code using errors.Is.
+var (
+	ErrPodNotFound       = errors.New("pod not found")
+	ErrContainerNotFound = errors.New("container not found")
+	ErrForbidden         = errors.New("forbidden")
+	ErrTimeout           = errors.New("timeout")
+	ErrCanceled          = errors.New("canceled")
+	ErrTransport         = errors.New("transport error")
+	// ErrCommandNotFound is returned by ResolveExecutor for commands not
+	// found in containers.
+	ErrCommandNotFound = errors.New("command not found")
+	// ErrConnectionLost is returned when the connection of an established
+	// exec stream breaks, e.g. when it is dropped by a load balancer.
+	ErrConnectionLost = errors.New("connection lost")
+	// ErrDenied is returned by GuardExecutor for commands it does not allow.
+	ErrDenied = errors.New("command denied")
+	// ErrUnsupported is returned for containers commands cannot be
+	// executed in at all, e.g. by ToolboxExecutor.
+	ErrUnsupported = errors.New("unsupported container")
+)
+
+// ErrNonZeroExit is returned when a command has been executed but exited
+// with a non-zero code.
+type ErrNonZeroExit struct {
+	Code int
+}
+
+func (e *ErrNonZeroExit) Error() string {
+	return fmt.Sprintf("command terminated with exit code %d", e.Code)
+}
+
+// Error kinds reported in ExecutionStatus.ErrorKind.
+const (
+	KindPodNotFound       = "PodNotFound"
+	KindContainerNotFound = "ContainerNotFound"
+	KindForbidden         = "Forbidden"
+	KindTimeout           = "Timeout"
+	KindCanceled          = "Canceled"
+	KindTransport         = "Transport"
+	KindNonZeroExit       = "NonZeroExit"
+	KindDenied            = "Denied"
+	KindUnsupported       = "Unsupported"
+	KindConnectionLost    = "ConnectionLost"
+	KindCommandNotFound   = "CommandNotFound"
+)
+
+// ErrorKind returns the kind of err, or an empty string for a nil error.
+func ErrorKind(err error) string {
+	var exitErr *ErrNonZeroExit
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &exitErr):
+		return KindNonZeroExit
+	case errors.Is(err, ErrPodNotFound):
+		return KindPodNotFound
+	case errors.Is(err, ErrContainerNotFound):
+		return KindContainerNotFound
+	case errors.Is(err, ErrForbidden):
+		return KindForbidden
+	case errors.Is(err, ErrTimeout):
+		return KindTimeout
+	case errors.Is(err, ErrCanceled):
+		return KindCanceled
+	case errors.Is(err, ErrDenied):
+		return KindDenied
+	case errors.Is(err, ErrUnsupported):
+		return KindUnsupported
+	case errors.Is(err, ErrConnectionLost):
+		return KindConnectionLost
+	case errors.Is(err, ErrCommandNotFound):
+		return KindCommandNotFound
+	default:
+		return KindTransport
+	}
+}
+
+// classify wraps err returned by the Kubernetes API or the exec stream with
+// the matching sentinel error.
+func classify(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var netErr net.Error
+	var sentinel error
+	switch {
+	case errors.Is(err, ErrPodNotFound), errors.Is(err, ErrContainerNotFound), errors.Is(err, ErrForbidden),
+		errors.Is(err, ErrTimeout), errors.Is(err, ErrCanceled), errors.Is(err, ErrTransport), errors.Is(err, ErrDenied),
+		errors.Is(err, ErrUnsupported), errors.Is(err, ErrConnectionLost),
+		errors.Is(err, ErrCommandNotFound):
+		return err
+	case apierrors.IsNotFound(err):
+		sentinel = ErrPodNotFound
+	case apierrors.IsBadRequest(err) && strings.Contains(err.Error(), "container"):
+		// the API server rejects exec into unknown containers with
+		// "container <name> is not valid for pod <pod>"
+		sentinel = ErrContainerNotFound
+	case apierrors.IsForbidden(err), apierrors.IsUnauthorized(err):
+		sentinel = ErrForbidden
+	case errors.Is(err, context.DeadlineExceeded), apierrors.IsTimeout(err), apierrors.IsServerTimeout(err),
+		errors.As(err, &netErr) && netErr.Timeout():
+		sentinel = ErrTimeout
+	case errors.Is(err, context.Canceled):
+		sentinel = ErrCanceled
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EPIPE), errors.Is(err, net.ErrClosed):
+		sentinel = ErrConnectionLost
+	default:
+		sentinel = ErrTransport
+	}
+
+	return fmt.Errorf("%w: %w", sentinel, err)
+}
@@ -0,0 +1,60 @@
+package k8sexec
+
+import (
+	"context"
+	corev1 "k8s.io/api/core/v1"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+	"time"
+)
+
+// Event is a Kubernetes event of a pod.
+type Event struct {
+	Type     string    `json:"Type"`
+	Reason   string    `json:"Reason"`
+	Message  string    `json:"Message"`
+	Count    int32     `json:"Count"`
+	LastSeen time.Time `json:"LastSeen"`
+}
+
+// PodEvents returns events of the pod of target still kept by the API
+// server, oldest first.
+func (k *K8SExec) PodEvents(ctx context.Context, target Target) ([]Event, error) {
+	target = k.qualify(target)
+	list, err := k.clientset.CoreV1().Events(target.Namespace).List(ctx, metaV1.ListOptions{
+		FieldSelector: "involvedObject.kind=Pod,involvedObject.name=" + target.Pod,
+	})
+	if err != nil {
+		return nil, classify(err)
+	}
+
+	events := make([]Event, 0, len(list.Items))
+	for _, event := range list.Items {
+		events = append(events, Event{
+			Type:     event.Type,
+			Reason:   event.Reason,
+			Message:  event.Message,
+			Count:    max(event.Count, 1),
+			LastSeen: lastSeen(&event),
+		})
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].LastSeen.Before(events[j].LastSeen)
+	})
+	return events, nil
+}
+
+// lastSeen returns when event has been observed last, events.k8s.io clients
+// only set some of the timestamps.
+func lastSeen(event *corev1.Event) time.Time {
+	switch {
+	case event.Series != nil:
+		return event.Series.LastObservedTime.Time
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	default:
+		return event.FirstTimestamp.Time
+	}
+}
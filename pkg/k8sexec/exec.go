@@ -0,0 +1,288 @@
+package k8sexec
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultParallel is the number of commands executed concurrently by
+// ExecAsync and ExecAll when ExecOptions.Parallel is not set.
+const DefaultParallel = 5
+
+// DefaultRetryDelay is the delay before the first retry of a command when
+// ExecOptions.RetryDelay is not set.
+const DefaultRetryDelay = time.Second
+
+// Target identifies a container a command is executed in. An empty Namespace
+// stands for the namespace of the K8SExec.
+type Target struct {
+	Namespace string
+	Pod       string
+	Container string
+}
+
+// ExecOptions controls execution of a command in multiple containers.
+type ExecOptions struct {
+	// Stdin is streamed to the command in every container.
+	Stdin []byte
+	// Parallel limits the number of concurrently executed commands.
+	Parallel int
+	// MaxPerNode additionally limits the number of concurrently executed
+	// commands in containers scheduled on the same node, as returned by
+	// Node, so that kubelets of nodes hosting many targets are not
+	// overloaded. Targets on unknown nodes are not limited.
+	MaxPerNode int
+	// Node returns the node the pod of a target is scheduled on, or an empty
+	// string when it is not known. It is reported in statuses as well.
+	Node func(target Target) string
+	// Image returns the image reference of the container of a target and the
+	// image ID it resolved to, reported in statuses.
+	Image func(target Target) (image, imageID string)
+	// SplitLines fills StdoutLines and StderrLines of the returned statuses.
+	SplitLines bool
+	// Output, when set, is called for every target before its command is
+	// started and returns writers its output is copied to as it arrives, in
+	// addition to being collected in its status. Nil writers are ignored,
+	// writers implementing io.Closer are closed when the command finishes.
+	Output func(target Target) (stdout, stderr io.Writer)
+	// Done, when set, is called with the status of every target as soon as
+	// it completes, possibly concurrently.
+	Done func(status *ExecutionStatus)
+	// Command, when set, returns the command executed in a target instead
+	// of the command given for all of them, or nil to execute that one.
+	// Stdin is not streamed to returned commands.
+	Command func(target Target) []string
+	// Retries is the number of times a command failing with ErrTransport,
+	// i.e. before its exec stream was established, is executed again,
+	// waiting RetryDelay, or DefaultRetryDelay, longer before each retry.
+	// Commands are not retried once started, e.g. once they wrote output,
+	// as they might not be idempotent.
+	Retries    int
+	RetryDelay time.Duration
+}
+
+// Exec executes cmd in the given container and waits for it to finish.
+func (k *K8SExec) Exec(ctx context.Context, pod, container string, cmd []string, stdin io.Reader) *ExecutionStatus {
+	return k.exec(ctx, Target{Pod: pod, Container: container}, cmd, stdin, ExecOptions{})
+}
+
+// ExecTarget executes cmd in the container of target and waits for it to finish.
+func (k *K8SExec) ExecTarget(ctx context.Context, target Target, cmd []string, stdin io.Reader) *ExecutionStatus {
+	return k.exec(ctx, target, cmd, stdin, ExecOptions{})
+}
+
+// ExecAsync executes cmd in all targets and sends the status of each of them
+// to the returned channel as soon as it completes. The channel is closed once
+// all targets have been processed. Targets not started before ctx is done are
+// reported with the context error.
+func (k *K8SExec) ExecAsync(ctx context.Context, targets []Target, cmd []string, opts ExecOptions) <-chan *ExecutionStatus {
+	results := make(chan *ExecutionStatus, len(targets))
+	go func() {
+		defer close(results)
+		k.execAll(ctx, targets, cmd, opts, func(_ int, status *ExecutionStatus) {
+			results <- status
+		})
+	}()
+	return results
+}
+
+// ExecAll executes cmd in all targets and returns their statuses in the order
+// of targets.
+func (k *K8SExec) ExecAll(ctx context.Context, targets []Target, cmd []string, opts ExecOptions) []*ExecutionStatus {
+	statuses := make([]*ExecutionStatus, len(targets))
+	k.execAll(ctx, targets, cmd, opts, func(i int, status *ExecutionStatus) {
+		statuses[i] = status
+	})
+	return statuses
+}
+
+// execAll executes cmd in targets with at most opts.Parallel commands running
+// at the same time and calls done with the index of each completed target.
+func (k *K8SExec) execAll(ctx context.Context, targets []Target, cmd []string, opts ExecOptions, done func(int, *ExecutionStatus)) {
+	parallel := opts.Parallel
+	if parallel <= 0 {
+		parallel = DefaultParallel
+	}
+	if opts.Done != nil {
+		report := done
+		done = func(i int, status *ExecutionStatus) {
+			opts.Done(status)
+			report(i, status)
+		}
+	}
+	if opts.MaxPerNode > 0 && opts.Node != nil {
+		k.execAllPerNode(ctx, targets, cmd, opts, parallel, done)
+		return
+	}
+
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+
+	for i, target := range targets {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			done(i, k.newErrorStatus(target, classify(ctx.Err())))
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, target Target) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			// each execution of command will empty stdin therefore
+			// it has to be recreated for every target
+			done(i, k.exec(ctx, target, cmd, bytes.NewReader(opts.Stdin), opts))
+		}(i, target)
+	}
+	wg.Wait()
+}
+
+// execAllPerNode is execAll limiting commands running at the same time on
+// each node to opts.MaxPerNode. Targets are started in their order, skipping
+// those whose node is busy, so that a node hosting many targets does not hold
+// up the others.
+func (k *K8SExec) execAllPerNode(ctx context.Context, targets []Target, cmd []string, opts ExecOptions, parallel int, done func(int, *ExecutionStatus)) {
+	nodes := make([]string, len(targets))
+	pending := make([]int, len(targets))
+	for i, target := range targets {
+		nodes[i] = opts.Node(target)
+		pending[i] = i
+	}
+
+	running := map[string]int{}
+	active := 0
+	finished := make(chan int)
+	canceled := ctx.Done()
+	for len(pending) > 0 || active > 0 {
+		if ctx.Err() != nil {
+			for _, i := range pending {
+				done(i, k.newErrorStatus(targets[i], classify(ctx.Err())))
+			}
+			pending = nil
+			canceled = nil
+		}
+
+		waiting := pending[:0]
+		for _, i := range pending {
+			node := nodes[i]
+			if active >= parallel || (node != "" && running[node] >= opts.MaxPerNode) {
+				waiting = append(waiting, i)
+				continue
+			}
+
+			active++
+			running[node]++
+			go func() {
+				// each execution of command will empty stdin therefore
+				// it has to be recreated for every target
+				done(i, k.exec(ctx, targets[i], cmd, bytes.NewReader(opts.Stdin), opts))
+				finished <- i
+			}()
+		}
+		pending = waiting
+
+		if active == 0 {
+			continue
+		}
+		select {
+		case i := <-finished:
+			active--
+			running[nodes[i]]--
+		case <-canceled:
+		}
+	}
+}
+
+func (k *K8SExec) exec(ctx context.Context, target Target, cmd []string, stdin io.Reader, opts ExecOptions) *ExecutionStatus {
+	target = k.qualify(target)
+	status := k.newStatus(target)
+	if opts.Node != nil {
+		status.Node = opts.Node(target)
+	}
+	if opts.Image != nil {
+		status.Image, status.ImageID = opts.Image(target)
+	}
+	if opts.Command != nil {
+		if override := opts.Command(target); override != nil {
+			cmd, stdin = override, nil
+			status.Command = override
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	streams := IO{Stdin: stdin, Stdout: &stdout, Stderr: &stderr}
+	if opts.Output != nil {
+		liveStdout, liveStderr := opts.Output(target)
+		for _, live := range []struct {
+			w      io.Writer
+			stream *io.Writer
+		}{{liveStdout, &streams.Stdout}, {liveStderr, &streams.Stderr}} {
+			if live.w == nil {
+				continue
+			}
+			*live.stream = io.MultiWriter(*live.stream, live.w)
+			if closer, ok := live.w.(io.Closer); ok {
+				defer closer.Close()
+			}
+		}
+	}
+
+	result, err := k.executor.Run(ctx, target, Command{Args: cmd}, streams)
+	for attempt := 1; attempt <= opts.Retries && errors.Is(err, ErrTransport); attempt++ {
+		// commands that wrote output have started, and live writers of
+		// opts.Output would receive it twice
+		if stdout.Len() > 0 || stderr.Len() > 0 {
+			break
+		}
+		// stdin may have been partially read by the failed attempt
+		if stdin != nil {
+			seeker, ok := stdin.(io.Seeker)
+			if !ok {
+				break
+			}
+			if _, seekErr := seeker.Seek(0, io.SeekStart); seekErr != nil {
+				break
+			}
+		}
+		select {
+		case <-time.After(time.Duration(attempt) * cmp.Or(opts.RetryDelay, DefaultRetryDelay)):
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		result, err = k.executor.Run(ctx, target, Command{Args: cmd}, streams)
+		status.Attempts = attempt + 1
+	}
+	status.finish()
+
+	status.Stdout = stdout.String()
+	status.Stderr = stderr.String()
+	if opts.SplitLines {
+		status.StdoutLines = splitLines(status.Stdout)
+		status.StderrLines = splitLines(status.Stderr)
+	}
+	status.setResult(result, err)
+	return status
+}
+
+func (k *K8SExec) newErrorStatus(target Target, err error) *ExecutionStatus {
+	status := k.newStatus(target)
+	status.setError(err)
+	status.finish()
+	return status
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,139 @@
+package k8sexec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"k8s.io/client-go/kubernetes/fake"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyExecutor fails the first failures executions of commands with
+// ErrTransport, after writing partial to stdout, and then writes "ok".
+type flakyExecutor struct {
+	failures int
+	partial  string
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (e *flakyExecutor) Run(ctx context.Context, target Target, cmd Command, streams IO) (Result, error) {
+	e.mu.Lock()
+	e.calls++
+	failed := e.calls <= e.failures
+	e.mu.Unlock()
+
+	if failed {
+		_, _ = io.WriteString(streams.Stdout, e.partial)
+		return Result{ExitCode: -1}, fmt.Errorf("%w: connection reset", ErrTransport)
+	}
+	_, _ = io.WriteString(streams.Stdout, "ok\n")
+	return Result{}, nil
+}
+
+func TestExecRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		partial      string
+		wantAttempts int
+		wantStdout   string
+		wantError    bool
+	}{
+		{name: "failed to start", partial: "", wantAttempts: 2, wantStdout: "ok\n"},
+		{name: "failed after writing", partial: "part", wantAttempts: 0, wantStdout: "part", wantError: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			executor := &flakyExecutor{failures: 1, partial: test.partial}
+			k := NewK8SExecWithExecutor(fake.NewSimpleClientset(), executor, "ns")
+			var live bytes.Buffer
+			opts := ExecOptions{
+				Retries:    2,
+				RetryDelay: 1,
+				Output: func(Target) (io.Writer, io.Writer) {
+					return &live, nil
+				},
+			}
+
+			status := k.ExecAll(context.Background(), []Target{{Pod: "pod", Container: "app"}}, []string{"id"}, opts)[0]
+			if status.Attempts != test.wantAttempts {
+				t.Errorf("Attempts = %d, want %d", status.Attempts, test.wantAttempts)
+			}
+			if status.Stdout != test.wantStdout {
+				t.Errorf("Stdout = %q, want %q", status.Stdout, test.wantStdout)
+			}
+			if live.String() != test.wantStdout {
+				t.Errorf("live output = %q, want %q", live.String(), test.wantStdout)
+			}
+			if (status.Error != "") != test.wantError {
+				t.Errorf("Error = %q, want an error: %v", status.Error, test.wantError)
+			}
+		})
+	}
+}
+
+// nodeExecutor records the maximum number of commands running at the same
+// time in total and on each node of targets, given by node.
+type nodeExecutor struct {
+	node func(target Target) string
+
+	mu                  sync.Mutex
+	running, maxRunning int
+	perNode, maxPerNode map[string]int
+	executed            int
+}
+
+func (e *nodeExecutor) Run(ctx context.Context, target Target, cmd Command, streams IO) (Result, error) {
+	node := e.node(target)
+	e.mu.Lock()
+	e.running++
+	e.perNode[node]++
+	e.maxRunning = max(e.maxRunning, e.running)
+	e.maxPerNode[node] = max(e.maxPerNode[node], e.perNode[node])
+	e.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	e.mu.Lock()
+	e.running--
+	e.perNode[node]--
+	e.executed++
+	e.mu.Unlock()
+	return Result{}, nil
+}
+
+func TestExecAllMaxPerNode(t *testing.T) {
+	var targets []Target
+	nodes := map[Target]string{}
+	for i := 0; i < 30; i++ {
+		target := Target{Pod: fmt.Sprintf("pod-%d", i), Container: "app"}
+		targets = append(targets, target)
+		// most targets are on node-0, some on unknown nodes
+		nodes[target] = []string{"node-0", "node-0", "node-0", "node-1", "node-2", ""}[i%6]
+	}
+	node := func(target Target) string { return nodes[Target{Pod: target.Pod, Container: target.Container}] }
+	executor := &nodeExecutor{node: node, perNode: map[string]int{}, maxPerNode: map[string]int{}}
+	k := NewK8SExecWithExecutor(fake.NewSimpleClientset(), executor, "ns")
+
+	statuses := k.ExecAll(context.Background(), targets, []string{"id"}, ExecOptions{Parallel: 8, MaxPerNode: 2, Node: node})
+	for i, status := range statuses {
+		if status == nil || status.Pod != targets[i].Pod || status.Error != "" {
+			t.Fatalf("status %d = %+v, want a successful one of %s", i, status, targets[i].Pod)
+		}
+	}
+	if executor.executed != len(targets) {
+		t.Errorf("executed %d commands, want %d", executor.executed, len(targets))
+	}
+	if executor.maxRunning > 8 {
+		t.Errorf("%d commands ran at the same time, want at most 8", executor.maxRunning)
+	}
+	for node, running := range executor.maxPerNode {
+		if node != "" && running > 2 {
+			t.Errorf("%d commands ran at the same time on %s, want at most 2", running, node)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package k8sexec
+
+import (
+	"context"
+	"io"
+)
+
+// Command is a command executed in a container.
+type Command struct {
+	Args []string
+}
+
+// IO holds the streams connected to an executed command. Nil streams are not
+// attached.
+type IO struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Result is the result of a command that has been executed.
+type Result struct {
+	ExitCode int
+	// Path is the absolute path the command was resolved to, set by
+	// ResolveExecutor.
+	Path string
+}
+
+// Executor runs commands in containers. Implementations return an error only
+// when the command could not be executed or its streams broke; a command
+// exiting with a non-zero code is reported in Result.ExitCode. Returned
+// errors should wrap one of the sentinel errors of this package.
+//
+// SPDYExecutor is the default implementation, others can be used for tests
+// or to reach containers without the Kubernetes exec API.
+type Executor interface {
+	Run(ctx context.Context, target Target, cmd Command, streams IO) (Result, error)
+}
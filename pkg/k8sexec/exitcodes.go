@@ -0,0 +1,30 @@
+package k8sexec
+
+import (
+	"fmt"
+	"syscall"
+)
+
+var exitCodeDescriptions = map[int]string{
+	-1:  "command was not executed",
+	0:   "success",
+	1:   "general error",
+	2:   "misuse of shell builtins",
+	124: "command timed out",
+	126: "command invoked cannot execute",
+	127: "command not found",
+	128: "invalid argument to exit",
+	255: "exit status out of range",
+}
+
+// GetExitCodeDescription returns a human readable description of a shell exit code.
+func GetExitCodeDescription(code int) string {
+	if description, ok := exitCodeDescriptions[code]; ok {
+		return description
+	}
+	if code > 128 && code < 255 {
+		signal := syscall.Signal(code - 128)
+		return fmt.Sprintf("fatal error signal %d (%s)", code-128, signal.String())
+	}
+	return "unknown"
+}
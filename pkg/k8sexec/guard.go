@@ -0,0 +1,166 @@
+package k8sexec
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Guard restricts commands which may be executed in containers, e.g.
+//
+//	allow:
+//	  - '^(cat|ls|id|ps|uname|env|sh -s --)( |$)'
+//	deny:
+//	  - '\brm\b'
+//	  - '\b(kill|reboot|shutdown)\b'
+//
+// A command is denied when its command line, i.e. its arguments joined by
+// spaces, or a line of its standard input matches a deny expression. With
+// allow expressions the command line must match one of them as well. With
+// ReadOnly, commands for which MutatingOperation reports an operation are
+// denied too.
+type Guard struct {
+	Allow    []string `yaml:"allow"`
+	Deny     []string `yaml:"deny"`
+	ReadOnly bool     `yaml:"readOnly"`
+
+	// OverrideAllow and OverrideReadOnly let commands refused by the allow
+	// expressions or the read-only rule run anyway, deny expressions are
+	// never overridden. Overrides are reported to OnOverride.
+	OverrideAllow    bool                                            `yaml:"-"`
+	OverrideReadOnly bool                                            `yaml:"-"`
+	OnOverride       func(target Target, cmd Command, reason string) `yaml:"-"`
+
+	allow, deny []*regexp.Regexp
+}
+
+// LoadGuard reads guard rules from the YAML file at path.
+func LoadGuard(path string) (*Guard, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	guard := &Guard{}
+	if err := yaml.UnmarshalStrict(data, guard); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := guard.compile(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return guard, nil
+}
+
+func (g *Guard) compile() error {
+	for _, rules := range []struct {
+		exprs []string
+		res   *[]*regexp.Regexp
+	}{{g.Allow, &g.allow}, {g.Deny, &g.deny}} {
+		*rules.res = nil
+		for _, expr := range rules.exprs {
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return err
+			}
+			*rules.res = append(*rules.res, re)
+		}
+	}
+	return nil
+}
+
+// Check returns an error wrapping ErrDenied when g does not allow cmd with
+// the given standard input.
+func (g *Guard) Check(cmd Command, stdin []byte) error {
+	_, err := g.check(cmd, stdin)
+	return err
+}
+
+// check returns why cmd would have been refused without an override, or an
+// error wrapping ErrDenied when it is refused.
+func (g *Guard) check(cmd Command, stdin []byte) (string, error) {
+	line := strings.Join(cmd.Args, " ")
+	for _, re := range g.deny {
+		if re.MatchString(line) {
+			return "", fmt.Errorf("%w: %q matches %q", ErrDenied, line, re)
+		}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(stdin))
+	scanner.Buffer(nil, len(stdin)+1)
+	for scanner.Scan() {
+		for _, re := range g.deny {
+			if re.MatchString(scanner.Text()) {
+				return "", fmt.Errorf("%w: standard input line %q matches %q", ErrDenied, scanner.Text(), re)
+			}
+		}
+	}
+
+	var overrides []string
+	if g.ReadOnly {
+		if operation := MutatingOperation(line + "\n" + string(stdin)); operation != "" {
+			reason := operation + " is not allowed in read-only mode"
+			if !g.OverrideReadOnly {
+				return "", fmt.Errorf("%w: %s", ErrDenied, reason)
+			}
+			overrides = append(overrides, reason)
+		}
+	}
+
+	if len(g.allow) > 0 && !g.allowed(line) {
+		reason := fmt.Sprintf("%q is not allowed", line)
+		if !g.OverrideAllow {
+			return "", fmt.Errorf("%w: %s", ErrDenied, reason)
+		}
+		overrides = append(overrides, reason)
+	}
+
+	return strings.Join(overrides, "; "), nil
+}
+
+func (g *Guard) allowed(line string) bool {
+	for _, re := range g.allow {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// GuardExecutor runs commands allowed by a Guard with another executor.
+type GuardExecutor struct {
+	executor Executor
+	guard    *Guard
+}
+
+// NewGuardExecutor creates a GuardExecutor running commands allowed by guard
+// with executor.
+func NewGuardExecutor(executor Executor, guard *Guard) *GuardExecutor {
+	return &GuardExecutor{executor: executor, guard: guard}
+}
+
+// Run implements Executor.
+func (e *GuardExecutor) Run(ctx context.Context, target Target, cmd Command, streams IO) (Result, error) {
+	var stdin []byte
+	if streams.Stdin != nil {
+		var err error
+		if stdin, err = io.ReadAll(streams.Stdin); err != nil {
+			return Result{ExitCode: -1}, err
+		}
+		streams.Stdin = bytes.NewReader(stdin)
+	}
+
+	override, err := e.guard.check(cmd, stdin)
+	if err != nil {
+		return Result{ExitCode: -1}, err
+	}
+	if override != "" && e.guard.OnOverride != nil {
+		e.guard.OnOverride(target, cmd, override)
+	}
+	return e.executor.Run(ctx, target, cmd, streams)
+}
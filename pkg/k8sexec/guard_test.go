@@ -0,0 +1,73 @@
+package k8sexec
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGuardCheck(t *testing.T) {
+	guard := &Guard{
+		Allow: []string{`^(cat|ls|id|sh -s --)( |$)`},
+		Deny:  []string{`\brm\b`, `\b(kill|reboot)\b`},
+	}
+	if err := guard.compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args  []string
+		stdin string
+		want  string
+	}{
+		{args: []string{"id"}},
+		{args: []string{"cat", "/etc/passwd"}},
+		{args: []string{"sh", "-s", "--"}, stdin: "id\nls /tmp\n"},
+		{args: []string{"ls", "/tmp;", "rm", "-rf", "/"}, want: `matches "\\brm\\b"`},
+		{args: []string{"sh", "-s", "--"}, stdin: "id\nkill -9 1\n", want: `standard input line "kill -9 1"`},
+		{args: []string{"sh", "-s", "--"}, stdin: "id\nrm -f /tmp/x", want: `standard input line "rm -f /tmp/x"`},
+		{args: []string{"uname", "-a"}, want: `"uname -a" is not allowed`},
+		{args: []string{"sh", "-c", "id"}, want: `"sh -c id" is not allowed`},
+	}
+	for _, test := range tests {
+		err := guard.Check(Command{Args: test.args}, []byte(test.stdin))
+		switch {
+		case test.want == "" && err != nil:
+			t.Errorf("Check(%q, %q) = %v, want allowed", test.args, test.stdin, err)
+		case test.want != "" && (!errors.Is(err, ErrDenied) || !strings.Contains(err.Error(), test.want)):
+			t.Errorf("Check(%q, %q) = %v, want denied with %q", test.args, test.stdin, err, test.want)
+		}
+	}
+}
+
+func TestGuardOverrides(t *testing.T) {
+	var overrides []string
+	guard := &Guard{
+		Allow:         []string{`^id$`},
+		Deny:          []string{`\brm\b`},
+		OverrideAllow: true,
+		OnOverride: func(target Target, cmd Command, reason string) {
+			overrides = append(overrides, reason)
+		},
+	}
+	if err := guard.compile(); err != nil {
+		t.Fatal(err)
+	}
+	executor := &flakyExecutor{}
+	guarded := NewGuardExecutor(executor, guard)
+
+	// allow expressions are overridden, deny expressions never
+	if _, err := guarded.Run(context.Background(), Target{}, Command{Args: []string{"uname"}}, IO{Stdout: &strings.Builder{}}); err != nil {
+		t.Errorf("overridden command refused: %v", err)
+	}
+	if _, err := guarded.Run(context.Background(), Target{}, Command{Args: []string{"rm", "-rf", "/"}}, IO{}); !errors.Is(err, ErrDenied) {
+		t.Errorf("denied command = %v, want ErrDenied", err)
+	}
+	if executor.calls != 1 {
+		t.Errorf("executed %d commands, want 1", executor.calls)
+	}
+	if len(overrides) != 1 || overrides[0] != `"uname" is not allowed` {
+		t.Errorf("overrides %q, want the one of uname", overrides)
+	}
+}
@@ -0,0 +1,90 @@
+// Package k8sexec executes commands in Kubernetes containers.
+//
+// K8SExec executes a command in a single container with Exec, or in many
+// containers concurrently with ExecAll, ExecAsync and ExecOnTargets,
+// reporting the outcome of each as an ExecutionStatus. Commands are run by
+// an Executor, the SPDY streams of the API server by default, which
+// decorators such as GuardExecutor and AuditExecutor wrap. ExecStream gives
+// incremental access to the output of long-running commands.
+package k8sexec
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// K8SExec executes commands in containers of pods in a single namespace.
+type K8SExec struct {
+	clientset kubernetes.Interface
+	executor  Executor
+	namespace string
+}
+
+// NewK8SExec creates a K8SExec for the given kubeconfig file and namespace.
+func NewK8SExec(kubeconfig string, namespace string) (*K8SExec, error) {
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	return NewK8SExecForConfig(config, namespace)
+}
+
+// NewK8SExecForConfig creates a K8SExec from an already loaded rest config.
+func NewK8SExecForConfig(config *rest.Config, namespace string) (*K8SExec, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return NewK8SExecWithExecutor(clientset, NewSPDYExecutor(config, clientset), namespace), nil
+}
+
+// NewK8SExecWithExecutor creates a K8SExec running commands with executor,
+// e.g. a fake one in tests.
+func NewK8SExecWithExecutor(clientset kubernetes.Interface, executor Executor, namespace string) *K8SExec {
+	return &K8SExec{clientset: clientset, executor: executor, namespace: namespace}
+}
+
+// Namespace returns the namespace commands are executed in.
+func (k *K8SExec) Namespace() string {
+	return k.namespace
+}
+
+// Clientset returns the Kubernetes client used by k.
+func (k *K8SExec) Clientset() kubernetes.Interface {
+	return k.clientset
+}
+
+// Executor returns the executor used by k to run commands.
+func (k *K8SExec) Executor() Executor {
+	return k.executor
+}
+
+// qualify sets the namespace of target to the namespace of k if not set.
+func (k *K8SExec) qualify(target Target) Target {
+	if target.Namespace == "" {
+		target.Namespace = k.namespace
+	}
+	return target
+}
+
+// ContainerImage returns the image reference of a container of pod and the
+// image ID, including its digest, it resolved to according to the status of
+// the pod. Empty strings are returned for unknown pods or containers.
+func ContainerImage(pod *corev1.Pod, container string) (image, imageID string) {
+	if pod == nil {
+		return "", ""
+	}
+	for _, _container := range pod.Spec.Containers {
+		if _container.Name == container {
+			image = _container.Image
+		}
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == container {
+			imageID = status.ImageID
+		}
+	}
+	return image, imageID
+}
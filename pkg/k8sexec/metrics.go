@@ -0,0 +1,59 @@
+package k8sexec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"time"
+)
+
+// Usage is the resource usage of a container reported by the metrics API.
+type Usage struct {
+	CPUMillicores int64         `json:"CPUMillicores"`
+	MemoryBytes   int64         `json:"MemoryBytes"`
+	Timestamp     time.Time     `json:"Timestamp"`
+	Window        time.Duration `json:"Window"`
+}
+
+// podMetrics is the subset of metrics.k8s.io/v1beta1 PodMetrics used by
+// PodUsage, decoded without depending on the metrics client.
+type podMetrics struct {
+	Timestamp  time.Time `json:"timestamp"`
+	Window     string    `json:"window"`
+	Containers []struct {
+		Name  string            `json:"name"`
+		Usage map[string]string `json:"usage"`
+	} `json:"containers"`
+}
+
+// PodUsage returns the current usage of containers of the pod of target by
+// their names, as reported by the metrics.k8s.io API, e.g. of metrics-server.
+func (k *K8SExec) PodUsage(ctx context.Context, target Target) (map[string]*Usage, error) {
+	target = k.qualify(target)
+	raw, err := k.clientset.Discovery().RESTClient().Get().
+		AbsPath("/apis/metrics.k8s.io/v1beta1/namespaces", target.Namespace, "pods", target.Pod).
+		DoRaw(ctx)
+	if err != nil {
+		return nil, classify(err)
+	}
+
+	var metrics podMetrics
+	if err := json.Unmarshal(raw, &metrics); err != nil {
+		return nil, fmt.Errorf("decoding metrics of pod %s: %w", target.Pod, err)
+	}
+	window, _ := time.ParseDuration(metrics.Window)
+
+	usage := make(map[string]*Usage, len(metrics.Containers))
+	for _, _container := range metrics.Containers {
+		item := &Usage{Timestamp: metrics.Timestamp, Window: window}
+		if cpu, err := resource.ParseQuantity(_container.Usage["cpu"]); err == nil {
+			item.CPUMillicores = cpu.MilliValue()
+		}
+		if memory, err := resource.ParseQuantity(_container.Usage["memory"]); err == nil {
+			item.MemoryBytes = memory.Value()
+		}
+		usage[_container.Name] = item
+	}
+	return usage, nil
+}
@@ -0,0 +1,81 @@
+package k8sexec
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// PipeStatus is the outcome of commands connected by Pipe.
+type PipeStatus struct {
+	From *ExecutionStatus
+	To   *ExecutionStatus
+	// Bytes is the number of bytes streamed from From to To.
+	Bytes int64
+}
+
+// Pipe executes fromCmd in the container of from and toCmd in the container
+// of to concurrently, streaming stdout of fromCmd into stdin of toCmd as it
+// is produced, e.g. to restore a database dump into another one. Stdout of
+// fromCmd is not collected in its status.
+//
+// When fromCmd fails, toCmd is canceled so that it does not process partial
+// input to the end. When toCmd finishes first, fromCmd is canceled as it
+// cannot write anymore.
+func (k *K8SExec) Pipe(ctx context.Context, from Target, fromCmd []string, to Target, toCmd []string) *PipeStatus {
+	from, to = k.qualify(from), k.qualify(to)
+	status := &PipeStatus{From: k.newStatus(from), To: k.newStatus(to)}
+
+	fromCtx, cancelFrom := context.WithCancel(ctx)
+	defer cancelFrom()
+	toCtx, cancelTo := context.WithCancel(ctx)
+	defer cancelTo()
+
+	reader, writer := io.Pipe()
+	var streamed atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		var stderr bytes.Buffer
+		result, err := k.executor.Run(fromCtx, from, Command{Args: fromCmd}, IO{Stdout: &countingWriter{w: writer, n: &streamed}, Stderr: &stderr})
+		status.From.finish()
+		status.From.Stderr = stderr.String()
+		status.From.setResult(result, err)
+		if status.From.Err != nil {
+			cancelTo()
+		}
+		_ = writer.Close()
+	}()
+
+	go func() {
+		defer wg.Done()
+		var stdout, stderr bytes.Buffer
+		result, err := k.executor.Run(toCtx, to, Command{Args: toCmd}, IO{Stdin: reader, Stdout: &stdout, Stderr: &stderr})
+		status.To.finish()
+		status.To.Stdout, status.To.Stderr = stdout.String(), stderr.String()
+		status.To.setResult(result, err)
+		// nothing reads stdout of fromCmd anymore
+		_ = reader.CloseWithError(io.ErrClosedPipe)
+		cancelFrom()
+	}()
+
+	wg.Wait()
+	status.Bytes = streamed.Load()
+	return status
+}
+
+// countingWriter counts bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n *atomic.Int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n.Add(int64(n))
+	return n, err
+}
@@ -0,0 +1,146 @@
+package k8sexec
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	// commandSeparators split shell code into simple commands.
+	commandSeparators = regexp.MustCompile("[;&|()`\n]|\\$\\(")
+	// redirection matches output redirections and their targets, <> opens
+	// files for reading and writing.
+	redirection = regexp.MustCompile(`(?:^|[^<])[0-9]?(?:>>?|<>)\|?\s*([^\s;&|)]+|&[0-9-])`)
+	// comment matches shell comments.
+	comment = regexp.MustCompile(`(?m)(^|[ \t])#.*$`)
+)
+
+// mutatingCommands are commands changing files, processes or the system.
+var mutatingCommands = map[string]bool{
+	"rm": true, "rmdir": true, "mv": true, "cp": true, "dd": true, "ln": true, "install": true,
+	"chmod": true, "chown": true, "chgrp": true, "chattr": true, "setfacl": true, "setcap": true,
+	"truncate": true, "shred": true, "touch": true, "mkdir": true, "mknod": true, "mkfifo": true, "tee": true,
+	"mount": true, "umount": true, "swapon": true, "swapoff": true, "fdisk": true, "parted": true, "wipefs": true,
+	"kill": true, "pkill": true, "killall": true, "reboot": true, "shutdown": true, "halt": true, "poweroff": true, "init": true,
+	"useradd": true, "userdel": true, "usermod": true, "groupadd": true, "groupdel": true, "passwd": true, "chpasswd": true,
+	"crontab": true, "iptables": true, "ip6tables": true, "nft": true, "systemctl": true, "service": true,
+}
+
+// packageManagers mutate the system with the given subcommands or options.
+var packageManagers = map[string][]string{
+	"apt":      {"install", "remove", "purge", "upgrade", "dist-upgrade", "autoremove"},
+	"apt-get":  {"install", "remove", "purge", "upgrade", "dist-upgrade", "autoremove"},
+	"dpkg":     {"-i", "--install", "-r", "--remove", "-P", "--purge"},
+	"yum":      {"install", "remove", "erase", "update", "upgrade", "downgrade"},
+	"dnf":      {"install", "remove", "erase", "update", "upgrade", "downgrade"},
+	"microdnf": {"install", "remove", "update", "upgrade"},
+	"rpm":      {"-i", "-U", "-F", "-e", "--install", "--upgrade", "--freshen", "--erase"},
+	"apk":      {"add", "del", "upgrade", "fix"},
+	"pacman":   {"-S", "-R", "-U", "-Syu", "-Rs"},
+	"zypper":   {"install", "in", "remove", "rm", "update", "up"},
+	"pip":      {"install", "uninstall"},
+	"pip3":     {"install", "uninstall"},
+	"npm":      {"install", "i", "uninstall", "update"},
+	"gem":      {"install", "uninstall", "update"},
+}
+
+// commandWrappers run the command following them.
+var commandWrappers = map[string]bool{
+	"sudo": true, "doas": true, "env": true, "command": true, "exec": true, "nohup": true, "busybox": true,
+	"time": true, "nice": true, "ionice": true, "timeout": true, "xargs": true, "chroot": true, "su-exec": true,
+}
+
+// wrapperOptions are options of commandWrappers taking a value.
+var wrapperOptions = map[string]map[string]bool{
+	"sudo":    {"-u": true, "-g": true, "-C": true, "-D": true, "-h": true, "-p": true, "-r": true, "-t": true, "-U": true},
+	"doas":    {"-u": true, "-C": true},
+	"env":     {"-u": true, "-C": true},
+	"timeout": {"-s": true, "-k": true},
+	"nice":    {"-n": true},
+	"ionice":  {"-c": true, "-n": true},
+	"xargs":   {"-a": true, "-d": true, "-E": true, "-I": true, "-L": true, "-n": true, "-P": true, "-s": true},
+}
+
+// wrapperOperands are the numbers of operands of commandWrappers preceding
+// the command they run, e.g. the new root directory of chroot.
+var wrapperOperands = map[string]int{"chroot": 1, "su-exec": 1}
+
+// harmlessTargets are redirection targets which do not change files.
+var harmlessTargets = map[string]bool{"/dev/null": true, "/dev/stdout": true, "/dev/stderr": true, "/dev/tty": true}
+
+// MutatingOperation returns a description of the first operation of shell
+// code which would change files, processes or the system according to
+// heuristics, or an empty string when it looks read-only. Detected are
+// well-known mutating commands, package installations and removals, in-place
+// edits with sed and output redirections to files.
+func MutatingOperation(code string) string {
+	code = comment.ReplaceAllString(code, "$1")
+
+	for _, match := range redirection.FindAllStringSubmatch(code, -1) {
+		target := strings.Trim(match[1], `"'`)
+		// bash opens connections by redirections to /dev/tcp and /dev/udp
+		if !strings.HasPrefix(target, "&") && !harmlessTargets[target] && !strings.HasPrefix(target, "/dev/tcp/") && !strings.HasPrefix(target, "/dev/udp/") {
+			return "redirection to " + target
+		}
+	}
+
+	for _, simple := range commandSeparators.Split(code, -1) {
+		words := commandWords(simple)
+		if len(words) == 0 {
+			continue
+		}
+
+		name := path.Base(words[0])
+		switch {
+		case mutatingCommands[name]:
+			return name
+		case name == "sed" || name == "perl":
+			for _, word := range words[1:] {
+				if strings.HasPrefix(word, "-i") || word == "--in-place" {
+					return name + " " + word
+				}
+			}
+		case packageManagers[name] != nil:
+			for _, word := range words[1:] {
+				for _, operation := range packageManagers[name] {
+					if word == operation {
+						return name + " " + word
+					}
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// commandWords returns words of a simple command starting with the command
+// run by it, variable assignments and wrappers like sudo are skipped.
+func commandWords(simple string) []string {
+	words := strings.Fields(simple)
+	for len(words) > 0 {
+		word := strings.Trim(words[0], `"'`)
+		switch {
+		case word == "!" || word == "if" || word == "then" || word == "else" || word == "elif" || word == "do" || word == "while" || word == "until":
+		case strings.Contains(word, "=") && !strings.HasPrefix(word, "-"):
+			// variable assignment
+		case commandWrappers[path.Base(word)]:
+			// options of wrappers, e.g. timeout 5 or sudo -u user
+			wrapper := path.Base(word)
+			for len(words) > 1 && (strings.HasPrefix(words[1], "-") || strings.Trim(words[1], "0123456789.smh") == "") {
+				if wrapperOptions[wrapper][words[1]] && len(words) > 2 {
+					words = words[1:]
+				}
+				words = words[1:]
+			}
+			for n := wrapperOperands[wrapper]; n > 0 && len(words) > 1; n-- {
+				words = words[1:]
+			}
+		default:
+			words[0] = word
+			return words
+		}
+		words = words[1:]
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package k8sexec
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMutatingOperation(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		// read-only
+		{"id", ""},
+		{"cat /etc/passwd", ""},
+		{"ps aux | grep nginx", ""},
+		{"sudo -u app id", ""},
+		{"ls -l /tmp 2>/dev/null", ""},
+		{"find / -name '*.conf' 2>&1", ""},
+		{"sed -n 1,5p /etc/hosts", ""},
+		{"apt list --installed", ""},
+		{"echo rm # rm -rf /", ""},
+		{"exec 3<>/dev/tcp/10.0.0.1/80", ""},
+
+		// mutating commands, also in compound commands and after wrappers
+		{"rm -rf /tmp/x", "rm"},
+		{"/bin/rm x", "rm"},
+		{"id; rm x", "rm"},
+		{"id && kill -9 1", "kill"},
+		{"echo $(touch /tmp/x)", "touch"},
+		{"sudo -u root chmod 777 /", "chmod"},
+		{"timeout 5 mv a b", "mv"},
+		{"timeout -s KILL 5 rm x", "rm"},
+		{"chroot /host rm x", "rm"},
+		{"su-exec nobody:nobody kill 1", "kill"},
+		{"FOO=bar tee /tmp/x", "tee"},
+		{"if true; then reboot; fi", "reboot"},
+
+		// in-place edits, package managers and redirections
+		{"sed -i s/a/b/ /etc/hosts", "sed -i"},
+		{"perl --in-place -pe 1 f", "perl --in-place"},
+		{"apt-get install -y curl", "apt-get install"},
+		{"apk add curl", "apk add"},
+		{"pip install requests", "pip install"},
+		{"echo x > /etc/hosts", "redirection to /etc/hosts"},
+		{"echo x >> '/tmp/log'", "redirection to /tmp/log"},
+		{"cat <> /tmp/fifo", "redirection to /tmp/fifo"},
+	}
+	for _, test := range tests {
+		if got := MutatingOperation(test.code); got != test.want {
+			t.Errorf("MutatingOperation(%q) = %q, want %q", test.code, got, test.want)
+		}
+	}
+}
+
+func TestGuardReadOnly(t *testing.T) {
+	guard := &Guard{ReadOnly: true}
+
+	if err := guard.Check(Command{Args: []string{"cat", "/etc/os-release"}}, nil); err != nil {
+		t.Errorf("read-only command refused: %v", err)
+	}
+	if err := guard.Check(Command{Args: []string{"rm", "-f", "/tmp/x"}}, nil); !errors.Is(err, ErrDenied) {
+		t.Errorf("mutating command = %v, want ErrDenied", err)
+	}
+	if err := guard.Check(Command{Args: []string{"sh"}}, []byte("id\necho x > /etc/hosts\n")); !errors.Is(err, ErrDenied) {
+		t.Errorf("mutating standard input = %v, want ErrDenied", err)
+	}
+
+	guard.OverrideReadOnly = true
+	if err := guard.Check(Command{Args: []string{"rm", "-f", "/tmp/x"}}, nil); err != nil {
+		t.Errorf("overridden mutating command refused: %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+package k8sexec
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrNoRecording is returned by ReplayExecutor for commands that have not
+// been recorded.
+var ErrNoRecording = errors.New("no recording")
+
+const targetsFile = "targets.json"
+
+// Recording is an executed command and its outcome as persisted by
+// RecordingExecutor.
+type Recording struct {
+	Target    Target   `json:"Target"`
+	Args      []string `json:"Args"`
+	Stdin     string   `json:"Stdin,omitempty"`
+	Stdout    string   `json:"Stdout"`
+	Stderr    string   `json:"Stderr"`
+	ExitCode  int      `json:"ExitCode"`
+	Error     string   `json:"Error,omitempty"`
+	ErrorKind string   `json:"ErrorKind,omitempty"`
+}
+
+// RecordingExecutor runs commands with another executor and stores every
+// command together with its output in a directory, from which it can be
+// served by ReplayExecutor.
+type RecordingExecutor struct {
+	executor Executor
+	dir      string
+}
+
+// NewRecordingExecutor creates a RecordingExecutor storing recordings of
+// commands run by executor in dir.
+func NewRecordingExecutor(executor Executor, dir string) *RecordingExecutor {
+	return &RecordingExecutor{executor: executor, dir: dir}
+}
+
+// Run implements Executor.
+func (e *RecordingExecutor) Run(ctx context.Context, target Target, cmd Command, streams IO) (Result, error) {
+	var stdin []byte
+	if streams.Stdin != nil {
+		var err error
+		if stdin, err = io.ReadAll(streams.Stdin); err != nil {
+			return Result{ExitCode: -1}, err
+		}
+		streams.Stdin = bytes.NewReader(stdin)
+	}
+
+	var stdout, stderr bytes.Buffer
+	streams.Stdout = teeWriter(streams.Stdout, &stdout)
+	streams.Stderr = teeWriter(streams.Stderr, &stderr)
+
+	result, runErr := e.executor.Run(ctx, target, cmd, streams)
+
+	recording := Recording{
+		Target:   target,
+		Args:     cmd.Args,
+		Stdin:    string(stdin),
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
+		ExitCode: result.ExitCode,
+	}
+	if runErr != nil {
+		recording.Error = runErr.Error()
+		recording.ErrorKind = ErrorKind(runErr)
+	}
+
+	if err := writeJSON(filepath.Join(e.dir, recordingName(target, cmd, stdin)), recording); err != nil {
+		return result, fmt.Errorf("failed to save recording: %w", err)
+	}
+	return result, runErr
+}
+
+// ReplayExecutor serves commands from recordings made by RecordingExecutor
+// without contacting a cluster.
+type ReplayExecutor struct {
+	dir string
+}
+
+// NewReplayExecutor creates a ReplayExecutor serving recordings from dir.
+func NewReplayExecutor(dir string) *ReplayExecutor {
+	return &ReplayExecutor{dir: dir}
+}
+
+// Run implements Executor.
+func (e *ReplayExecutor) Run(ctx context.Context, target Target, cmd Command, streams IO) (Result, error) {
+	var stdin []byte
+	if streams.Stdin != nil {
+		var err error
+		if stdin, err = io.ReadAll(streams.Stdin); err != nil {
+			return Result{ExitCode: -1}, err
+		}
+	}
+
+	var recording Recording
+	if err := readJSON(filepath.Join(e.dir, recordingName(target, cmd, stdin)), &recording); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Result{ExitCode: -1}, fmt.Errorf("%w: %q in %s/%s/%s", ErrNoRecording, cmd.Args, target.Namespace, target.Pod, target.Container)
+		}
+		return Result{ExitCode: -1}, err
+	}
+
+	if streams.Stdout != nil {
+		if _, err := io.WriteString(streams.Stdout, recording.Stdout); err != nil {
+			return Result{ExitCode: -1}, err
+		}
+	}
+	if streams.Stderr != nil {
+		if _, err := io.WriteString(streams.Stderr, recording.Stderr); err != nil {
+			return Result{ExitCode: -1}, err
+		}
+	}
+
+	return Result{ExitCode: recording.ExitCode}, recordedError(recording)
+}
+
+// SaveTargets stores targets in dir next to the recordings, so that a replay
+// does not need to resolve them in a cluster.
+func SaveTargets(dir string, targets []Target) error {
+	return writeJSON(filepath.Join(dir, targetsFile), targets)
+}
+
+// LoadTargets loads targets stored by SaveTargets.
+func LoadTargets(dir string) ([]Target, error) {
+	var targets []Target
+	if err := readJSON(filepath.Join(dir, targetsFile), &targets); err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+// recordingName returns the file name of the recording of cmd in target.
+func recordingName(target Target, cmd Command, stdin []byte) string {
+	key, _ := json.Marshal(struct {
+		Target Target
+		Args   []string
+		Stdin  []byte
+	}{target, cmd.Args, stdin})
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:]) + ".json"
+}
+
+// recordedError recreates the error of a recording, wrapping the sentinel
+// error of its kind.
+func recordedError(recording Recording) error {
+	var sentinel error
+	switch recording.ErrorKind {
+	case "":
+		return nil
+	case KindPodNotFound:
+		sentinel = ErrPodNotFound
+	case KindContainerNotFound:
+		sentinel = ErrContainerNotFound
+	case KindForbidden:
+		sentinel = ErrForbidden
+	case KindTimeout:
+		sentinel = ErrTimeout
+	case KindCanceled:
+		sentinel = ErrCanceled
+	case KindDenied:
+		sentinel = ErrDenied
+	case KindUnsupported:
+		sentinel = ErrUnsupported
+	case KindConnectionLost:
+		sentinel = ErrConnectionLost
+	case KindCommandNotFound:
+		sentinel = ErrCommandNotFound
+	default:
+		sentinel = ErrTransport
+	}
+	return &replayedError{sentinel: sentinel, msg: recording.Error}
+}
+
+type replayedError struct {
+	sentinel error
+	msg      string
+}
+
+func (e *replayedError) Error() string { return e.msg }
+
+func (e *replayedError) Unwrap() error { return e.sentinel }
+
+func teeWriter(w io.Writer, buf *bytes.Buffer) io.Writer {
+	if w == nil {
+		return buf
+	}
+	return io.MultiWriter(w, buf)
+}
+
+// writeJSON writes v to path readable by the owner only, as recordings hold
+// outputs and stdin of commands, e.g. secrets.
+func writeJSON(path string, v any) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
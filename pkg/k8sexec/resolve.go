@@ -0,0 +1,96 @@
+package k8sexec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// resolveScript prints the absolute path of the command $0 with sh, or exits
+// with 1 when it does not exist. Shells differ in the exit code of command
+// -v, some use 127, which containers without sh exit with as well.
+const resolveScript = `command -v "$0" || exit 1`
+
+// ResolveExecutor resolves commands with command -v in containers before
+// running them with another executor. Commands are run by their absolute
+// path, reported in Result.Path, and commands not found fail with
+// ErrCommandNotFound without being run, so that they are told apart from
+// commands exiting with 127 themselves. Commands in containers without sh
+// run unresolved. Paths are resolved once per container.
+type ResolveExecutor struct {
+	executor Executor
+
+	mu    sync.Mutex
+	paths map[resolveKey]string
+}
+
+type resolveKey struct {
+	target Target
+	name   string
+}
+
+// NewResolveExecutor creates a ResolveExecutor resolving commands run by
+// executor.
+func NewResolveExecutor(executor Executor) *ResolveExecutor {
+	return &ResolveExecutor{executor: executor, paths: map[resolveKey]string{}}
+}
+
+// Run implements Executor.
+func (e *ResolveExecutor) Run(ctx context.Context, target Target, cmd Command, streams IO) (Result, error) {
+	if len(cmd.Args) == 0 {
+		return e.executor.Run(ctx, target, cmd, streams)
+	}
+
+	path, found := e.resolve(ctx, target, cmd.Args[0])
+	switch {
+	case !found:
+		return Result{ExitCode: -1}, fmt.Errorf("%w: %s", ErrCommandNotFound, cmd.Args[0])
+	case path == "":
+		return e.executor.Run(ctx, target, cmd, streams)
+	}
+
+	args := append([]string{path}, cmd.Args[1:]...)
+	result, err := e.executor.Run(ctx, target, Command{Args: args}, streams)
+	result.Path = path
+	return result, err
+}
+
+// resolve returns the absolute path of the command name in target, an empty
+// path when it could not be resolved and found set to false when it does not
+// exist.
+func (e *ResolveExecutor) resolve(ctx context.Context, target Target, name string) (path string, found bool) {
+	key := resolveKey{target: target, name: name}
+	e.mu.Lock()
+	path, ok := e.paths[key]
+	e.mu.Unlock()
+	if ok {
+		return path, path != "\x00"
+	}
+
+	var stdout bytes.Buffer
+	result, err := e.executor.Run(ctx, target, Command{Args: []string{"sh", "-c", resolveScript, name}}, IO{Stdout: &stdout})
+	switch {
+	case err != nil:
+		// not known yet when ctx is done
+		return "", true
+	case result.ExitCode == 1:
+		// remembered as not found
+		path = "\x00"
+	case result.ExitCode != 0:
+		// sh is not available, e.g. exited with 126 or 127
+		return "", true
+	default:
+		path = strings.TrimSpace(stdout.String())
+		if !strings.HasPrefix(path, "/") {
+			// builtins and aliases are run as they are
+			path = ""
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.paths[key] = path
+	return path, path != "\x00"
+}
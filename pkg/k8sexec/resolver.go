@@ -0,0 +1,36 @@
+package k8sexec
+
+import "context"
+
+// PodResolver resolves the containers commands are executed in, separately
+// from executing them, so that programs can supply targets computed
+// elsewhere, e.g. from a CMDB, or their own strategies selecting them.
+type PodResolver interface {
+	Resolve(ctx context.Context) ([]Target, error)
+}
+
+// Targets is a PodResolver resolving to pre-computed targets.
+type Targets []Target
+
+// Resolve implements PodResolver.
+func (t Targets) Resolve(context.Context) ([]Target, error) {
+	return t, nil
+}
+
+// PodResolverFunc adapts a function to a PodResolver.
+type PodResolverFunc func(ctx context.Context) ([]Target, error)
+
+// Resolve implements PodResolver.
+func (f PodResolverFunc) Resolve(ctx context.Context) ([]Target, error) {
+	return f(ctx)
+}
+
+// ExecResolved executes cmd in the targets resolved by resolver like
+// ExecOnTargets.
+func (k *K8SExec) ExecResolved(ctx context.Context, resolver PodResolver, cmd []string, opts ExecOptions) ([]*ExecutionStatus, error) {
+	targets, err := resolver.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return k.ExecOnTargets(ctx, targets, cmd, opts), nil
+}
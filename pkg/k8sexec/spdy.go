@@ -0,0 +1,157 @@
+package k8sexec
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/apimachinery/pkg/util/httpstream/spdy"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// SPDYExecutor executes commands through the exec subresource of pods
+// using the SPDY protocol.
+type SPDYExecutor struct {
+	config    *rest.Config
+	clientset kubernetes.Interface
+	// PingPeriod is the interval of pings sent on exec streams, keeping
+	// connections of long-running commands alive through load balancers
+	// dropping idle ones. Zero uses the default of client-go.
+	PingPeriod time.Duration
+	// DialTimeout limits how long establishing an exec stream may take,
+	// so that unreachable nodes fail fast with ErrTimeout. Zero waits as
+	// long as the context allows.
+	DialTimeout time.Duration
+}
+
+// defaultPingPeriod is the ping period client-go uses for exec streams.
+const defaultPingPeriod = 5 * time.Second
+
+// NewSPDYExecutor creates a SPDYExecutor using the given config and client.
+func NewSPDYExecutor(config *rest.Config, clientset kubernetes.Interface) *SPDYExecutor {
+	return &SPDYExecutor{config: config, clientset: clientset}
+}
+
+// errDialTimeout cancels streams not established within DialTimeout.
+var errDialTimeout = errors.New("dial timeout")
+
+// Run implements Executor.
+func (e *SPDYExecutor) Run(ctx context.Context, target Target, cmd Command, streams IO) (Result, error) {
+	req := e.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(target.Pod).
+		Namespace(target.Namespace).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: target.Container,
+			Command:   cmd.Args,
+			Stdin:     streams.Stdin != nil,
+			Stdout:    streams.Stdout != nil,
+			Stderr:    streams.Stderr != nil,
+			TTY:       false,
+		}, scheme.ParameterCodec)
+
+	upgrader, err := e.newUpgrader()
+	if err != nil {
+		return Result{ExitCode: -1}, classify(err)
+	}
+	transport, err := rest.HTTPWrappersForConfig(e.config, upgrader.SpdyRoundTripper)
+	if err != nil {
+		return Result{ExitCode: -1}, classify(err)
+	}
+	executor, err := remotecommand.NewSPDYExecutorForTransports(transport, upgrader, "POST", req.URL())
+	if err != nil {
+		return Result{ExitCode: -1}, classify(err)
+	}
+
+	if e.DialTimeout > 0 {
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+		timer := time.AfterFunc(e.DialTimeout, func() {
+			select {
+			case <-upgrader.upgraded:
+			default:
+				cancel(errDialTimeout)
+			}
+		})
+		defer timer.Stop()
+	}
+
+	started := time.Now()
+	streamErr := executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:  streams.Stdin,
+		Stdout: streams.Stdout,
+		Stderr: streams.Stderr,
+	})
+	retCode, err := exitCode(streamErr)
+	switch {
+	case errors.Is(context.Cause(ctx), errDialTimeout):
+		err = fmt.Errorf("%w: exec stream not established within %s", ErrTimeout, e.DialTimeout)
+	case errors.Is(err, ErrConnectionLost):
+		// commands are not restarted, as they might not be idempotent
+		err = fmt.Errorf("%w after %s: %w", ErrConnectionLost, time.Since(started).Round(time.Second), streamErr)
+	}
+	return Result{ExitCode: retCode}, err
+}
+
+// notifyingUpgrader closes upgraded once the connection of an exec stream
+// has been upgraded, i.e. the stream is established.
+type notifyingUpgrader struct {
+	*spdy.SpdyRoundTripper
+	upgraded chan struct{}
+	once     sync.Once
+}
+
+// newUpgrader creates the round tripper of an exec stream, sending pings
+// every PingPeriod.
+func (e *SPDYExecutor) newUpgrader() (*notifyingUpgrader, error) {
+	tlsConfig, err := rest.TLSConfigFor(e.config)
+	if err != nil {
+		return nil, err
+	}
+	proxy := http.ProxyFromEnvironment
+	if e.config.Proxy != nil {
+		proxy = e.config.Proxy
+	}
+	roundTripper, err := spdy.NewRoundTripperWithConfig(spdy.RoundTripperConfig{
+		TLS:        tlsConfig,
+		Proxier:    proxy,
+		PingPeriod: cmp.Or(e.PingPeriod, defaultPingPeriod),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &notifyingUpgrader{SpdyRoundTripper: roundTripper, upgraded: make(chan struct{})}, nil
+}
+
+// NewConnection implements spdy.Upgrader of client-go.
+func (u *notifyingUpgrader) NewConnection(resp *http.Response) (httpstream.Connection, error) {
+	defer u.once.Do(func() { close(u.upgraded) })
+	return u.SpdyRoundTripper.NewConnection(resp)
+}
+
+// exitCode converts the error returned by a remote command stream into the
+// exit code of the command. Errors not caused by the command exiting with
+// a non-zero code are classified and returned with the exit code set to -1.
+func exitCode(err error) (int, error) {
+	if err == nil {
+		return 0, nil
+	}
+
+	var exitErr utilexec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitStatus(), nil
+	}
+
+	return -1, classify(err)
+}
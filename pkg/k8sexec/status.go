@@ -0,0 +1,95 @@
+package k8sexec
+
+import "time"
+
+// StatusVersion is the version of the ExecutionStatus schema. It is bumped
+// whenever fields are renamed, removed or change their meaning.
+const StatusVersion = 2
+
+// ExecutionStatus is the outcome of a command executed in a container.
+type ExecutionStatus struct {
+	Version   int    `json:"Version"`
+	Namespace string `json:"Namespace"`
+	Pod       string `json:"Pod"`
+	Container string `json:"Container"`
+	// Node is the node the pod is scheduled on, set when known through
+	// ExecOptions.Node.
+	Node string `json:"Node,omitempty"`
+	// Image is the image reference of the container and ImageID the image
+	// it resolved to, set when known through ExecOptions.Image.
+	Image   string `json:"Image,omitempty"`
+	ImageID string `json:"ImageID,omitempty"`
+	// CachedFrom is the namespace/pod/container whose result is reported
+	// for this container running the same image, instead of executing the
+	// command again.
+	CachedFrom string `json:"CachedFrom,omitempty"`
+	// Path is the absolute path the command was resolved to in the
+	// container, only set when resolved with ResolveExecutor.
+	Path string `json:"Path,omitempty"`
+	// Command is the command executed in the container when it differs
+	// from the command executed in all of them, see ExecOptions.Command.
+	Command []string `json:"Command,omitempty"`
+	Stdout  string   `json:"Stdout"`
+	Stderr  string   `json:"Stderr"`
+	// StdoutLines and StderrLines are only set when requested with
+	// ExecOptions.SplitLines.
+	StdoutLines []string `json:"StdoutLines,omitempty"`
+	StderrLines []string `json:"StderrLines,omitempty"`
+	// Error describes a failure to execute the command, ErrorKind its kind.
+	// Commands exiting with a non-zero code only set ErrorKind.
+	Error      string        `json:"Error,omitempty"`
+	ErrorKind  string        `json:"ErrorKind,omitempty"`
+	RetCode    int           `json:"RetCode"`
+	StartedAt  time.Time     `json:"StartedAt"`
+	FinishedAt time.Time     `json:"FinishedAt"`
+	Duration   time.Duration `json:"Duration"`
+	// Attempts is the number of times the command was executed when it was
+	// retried, see ExecOptions.Retries.
+	Attempts int `json:"Attempts,omitempty"`
+	// Usage is the resource usage of the container after the command
+	// finished, only set when requested.
+	Usage *Usage `json:"Usage,omitempty"`
+	// Events are events of the pod, only set when requested.
+	Events []Event `json:"Events,omitempty"`
+	// Remediation is the remediation of the failure of the command, only
+	// set when mapped by programs, e.g. kubex --remediations.
+	Remediation string `json:"Remediation,omitempty"`
+	// Err is the typed error of the execution, see ErrorKind for its kind.
+	Err error `json:"-"`
+}
+
+func (k *K8SExec) newStatus(target Target) *ExecutionStatus {
+	return &ExecutionStatus{
+		Version:   StatusVersion,
+		Namespace: k.qualify(target).Namespace,
+		Pod:       target.Pod,
+		Container: target.Container,
+		StartedAt: time.Now(),
+	}
+}
+
+func (s *ExecutionStatus) finish() {
+	s.FinishedAt = time.Now()
+	s.Duration = s.FinishedAt.Sub(s.StartedAt)
+}
+
+// setResult records the outcome of the command returned by an Executor.
+func (s *ExecutionStatus) setResult(result Result, err error) {
+	s.RetCode = result.ExitCode
+	s.Path = result.Path
+	switch {
+	case err != nil:
+		s.setError(classify(err))
+	case result.ExitCode != 0:
+		s.Err = &ErrNonZeroExit{Code: result.ExitCode}
+		s.ErrorKind = KindNonZeroExit
+	}
+}
+
+// setError records a failure to execute the command.
+func (s *ExecutionStatus) setError(err error) {
+	s.Err = err
+	s.Error = err.Error()
+	s.ErrorKind = ErrorKind(err)
+	s.RetCode = -1
+}
@@ -0,0 +1,43 @@
+package k8sexec
+
+import (
+	"context"
+	"io"
+)
+
+// ExecStream starts cmd in the given container and returns its standard output
+// and standard error as they are produced. The returned wait function blocks
+// until the command has finished and returns its exit code. A non-zero exit
+// code is not reported as an error by wait.
+//
+// Both readers have to be consumed (or closed) by the caller, otherwise the
+// remote command blocks once the stream buffers are full. A transport failure
+// is reported by wait and, while reading, by both readers.
+func (k *K8SExec) ExecStream(ctx context.Context, pod, container string, cmd []string) (stdout io.ReadCloser, stderr io.ReadCloser, wait func() (int, error), err error) {
+	stdoutReader, stdoutWriter := io.Pipe()
+	stderrReader, stderrWriter := io.Pipe()
+
+	done := make(chan struct{})
+	var retCode int
+	var streamErr error
+
+	go func() {
+		defer close(done)
+		var result Result
+		result, streamErr = k.executor.Run(ctx, k.qualify(Target{Pod: pod, Container: container}), Command{Args: cmd}, IO{
+			Stdout: stdoutWriter,
+			Stderr: stderrWriter,
+		})
+		retCode = result.ExitCode
+		// nil error closes the pipes with io.EOF
+		stdoutWriter.CloseWithError(streamErr)
+		stderrWriter.CloseWithError(streamErr)
+	}()
+
+	wait = func() (int, error) {
+		<-done
+		return retCode, streamErr
+	}
+
+	return stdoutReader, stderrReader, wait, nil
+}
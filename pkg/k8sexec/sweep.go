@@ -0,0 +1,74 @@
+package k8sexec
+
+import (
+	"context"
+	metaV1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ExecOnTargets executes cmd in all targets like ExecAll, resolving targets
+// without a container to all containers of their pods first, so that
+// programs can sweep pods without listing their containers. Statuses are
+// returned in the order of the resolved targets. Pods that cannot be read
+// are reported with a status without container.
+func (k *K8SExec) ExecOnTargets(ctx context.Context, targets []Target, cmd []string, opts ExecOptions) []*ExecutionStatus {
+	resolved := k.resolveContainers(ctx, targets)
+
+	var executed []Target
+	for _, target := range resolved {
+		if target.err == nil {
+			executed = append(executed, target.Target)
+		}
+	}
+	executedStatuses := k.ExecAll(ctx, executed, cmd, opts)
+
+	statuses := make([]*ExecutionStatus, 0, len(resolved))
+	for _, target := range resolved {
+		if target.err != nil {
+			status := k.newErrorStatus(target.Target, classify(target.err))
+			if opts.Done != nil {
+				opts.Done(status)
+			}
+			statuses = append(statuses, status)
+			continue
+		}
+		statuses = append(statuses, executedStatuses[0])
+		executedStatuses = executedStatuses[1:]
+	}
+	return statuses
+}
+
+// resolvedTarget is a target resolved by resolveContainers, or the target
+// that failed to be resolved with err.
+type resolvedTarget struct {
+	Target
+	err error
+}
+
+// resolveContainers replaces targets without a container with targets of
+// all containers of their pods. Pods are read once.
+func (k *K8SExec) resolveContainers(ctx context.Context, targets []Target) []resolvedTarget {
+	pods := map[Target][]resolvedTarget{}
+	resolved := make([]resolvedTarget, 0, len(targets))
+	for _, target := range targets {
+		if target.Container != "" {
+			resolved = append(resolved, resolvedTarget{Target: target})
+			continue
+		}
+
+		target = k.qualify(target)
+		containers, ok := pods[target]
+		if !ok {
+			pod, err := k.clientset.CoreV1().Pods(target.Namespace).Get(ctx, target.Pod, metaV1.GetOptions{})
+			if err != nil {
+				containers = []resolvedTarget{{Target: target, err: err}}
+			} else {
+				for _, container := range pod.Spec.Containers {
+					containers = append(containers, resolvedTarget{Target: Target{Namespace: target.Namespace, Pod: target.Pod, Container: container.Name}})
+				}
+			}
+			pods[target] = containers
+		}
+		resolved = append(resolved, containers...)
+	}
+	return resolved
+}
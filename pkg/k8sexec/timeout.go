@@ -0,0 +1,75 @@
+package k8sexec
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ExitTimedOut is the exit code of commands killed by timeout.
+const ExitTimedOut = 124
+
+// timeoutProbeScript prints how timeout can be invoked in a container.
+const timeoutProbeScript = `if command -v timeout >/dev/null 2>&1; then echo timeout; elif command -v busybox >/dev/null 2>&1; then echo busybox; fi`
+
+// TimeoutExecutor runs commands with another executor wrapped with timeout,
+// or busybox timeout, so that they are killed in the container when they
+// run too long, and not just disconnected from. Whether and how timeout is
+// available is probed once per container with sh, commands in containers
+// without it run unwrapped.
+type TimeoutExecutor struct {
+	executor Executor
+	timeout  time.Duration
+
+	mu       sync.Mutex
+	wrappers map[Target][]string
+}
+
+// NewTimeoutExecutor creates a TimeoutExecutor killing commands run by
+// executor after timeout, rounded up to whole seconds.
+func NewTimeoutExecutor(executor Executor, timeout time.Duration) *TimeoutExecutor {
+	return &TimeoutExecutor{executor: executor, timeout: timeout, wrappers: map[Target][]string{}}
+}
+
+// Run implements Executor.
+func (e *TimeoutExecutor) Run(ctx context.Context, target Target, cmd Command, streams IO) (Result, error) {
+	if wrapper := e.wrapper(ctx, target); wrapper != nil {
+		cmd = Command{Args: append(append([]string(nil), wrapper...), cmd.Args...)}
+	}
+	return e.executor.Run(ctx, target, cmd, streams)
+}
+
+// wrapper returns the arguments commands in target are prefixed with, nil
+// when timeout is not available.
+func (e *TimeoutExecutor) wrapper(ctx context.Context, target Target) []string {
+	e.mu.Lock()
+	wrapper, ok := e.wrappers[target]
+	e.mu.Unlock()
+	if ok {
+		return wrapper
+	}
+
+	var stdout bytes.Buffer
+	result, err := e.executor.Run(ctx, target, Command{Args: []string{"sh", "-c", timeoutProbeScript}}, IO{Stdout: &stdout})
+	if err != nil && ctx.Err() != nil {
+		// not known yet, probed again by the next command
+		return nil
+	}
+
+	seconds := strconv.Itoa(int((e.timeout + time.Second - 1) / time.Second))
+	switch {
+	case err != nil || result.ExitCode != 0:
+	case strings.TrimSpace(stdout.String()) == "timeout":
+		wrapper = []string{"timeout", seconds}
+	case strings.TrimSpace(stdout.String()) == "busybox":
+		wrapper = []string{"busybox", "timeout", seconds}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.wrappers[target] = wrapper
+	return wrapper
+}
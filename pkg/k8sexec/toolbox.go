@@ -0,0 +1,129 @@
+package k8sexec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sync"
+)
+
+// ToolboxDirs are directories a toolbox is injected into, in the order they
+// are tried. Directories mounted noexec are skipped.
+var ToolboxDirs = []string{"/tmp", "/var/tmp", "/dev/shm", "/run", "/var/run"}
+
+// toolboxName is the name of injected toolboxes.
+const toolboxName = ".kubex-toolbox"
+
+// ToolboxExecutor runs commands with another executor through a toolbox,
+// usually a static busybox, injected into containers without a shell, so
+// that minimal images can be swept as well. The toolbox is injected once per
+// container on its first command and removed by Cleanup.
+//
+// Without a shell, the toolbox is written with tee and made executable with
+// chmod, containers without them cannot be injected into.
+type ToolboxExecutor struct {
+	executor Executor
+	binary   func(target Target) ([]byte, error)
+
+	mu        sync.Mutex
+	toolboxes map[Target]*toolboxState
+}
+
+type toolboxState struct {
+	once sync.Once
+	// path of the injected toolbox, empty when the container has a shell
+	path string
+	err  error
+}
+
+// NewToolboxExecutor creates a ToolboxExecutor for commands run by executor.
+// binary returns the toolbox for a target, e.g. busybox built for its
+// architecture.
+func NewToolboxExecutor(executor Executor, binary func(target Target) ([]byte, error)) *ToolboxExecutor {
+	return &ToolboxExecutor{executor: executor, binary: binary, toolboxes: map[Target]*toolboxState{}}
+}
+
+// Run implements Executor.
+func (e *ToolboxExecutor) Run(ctx context.Context, target Target, cmd Command, streams IO) (Result, error) {
+	e.mu.Lock()
+	state, ok := e.toolboxes[target]
+	if !ok {
+		state = &toolboxState{}
+		e.toolboxes[target] = state
+	}
+	e.mu.Unlock()
+
+	state.once.Do(func() {
+		state.path, state.err = e.inject(ctx, target)
+	})
+	if state.err != nil {
+		return Result{ExitCode: -1}, state.err
+	}
+	if state.path != "" && len(cmd.Args) > 0 {
+		// busybox selects applets by their names
+		args := append([]string{state.path, path.Base(cmd.Args[0])}, cmd.Args[1:]...)
+		cmd = Command{Args: args}
+	}
+	return e.executor.Run(ctx, target, cmd, streams)
+}
+
+// inject writes the toolbox into the first writable and executable directory
+// of ToolboxDirs of target and returns its path, an empty one when target
+// has a shell.
+func (e *ToolboxExecutor) inject(ctx context.Context, target Target) (string, error) {
+	if result, err := e.executor.Run(ctx, target, Command{Args: []string{"sh", "-c", "true"}}, IO{}); err == nil && result.ExitCode == 0 {
+		return "", nil
+	}
+
+	binary, err := e.binary(target)
+	if err != nil {
+		return "", err
+	}
+	for _, dir := range ToolboxDirs {
+		toolbox := path.Join(dir, toolboxName)
+		if e.run(ctx, target, []string{"tee", toolbox}, binary) != nil {
+			continue
+		}
+		if e.run(ctx, target, []string{"chmod", "755", toolbox}, nil) == nil && e.run(ctx, target, []string{toolbox, "true"}, nil) == nil {
+			return toolbox, nil
+		}
+		_ = e.run(ctx, target, []string{"rm", "-f", toolbox}, nil)
+	}
+	return "", fmt.Errorf("%w: container has no shell and the toolbox could not be injected with tee and chmod into any of %v", ErrUnsupported, ToolboxDirs)
+}
+
+// run executes args in target and fails unless they succeeded.
+func (e *ToolboxExecutor) run(ctx context.Context, target Target, args []string, stdin []byte) error {
+	// tee copies the toolbox to stdout as well
+	streams := IO{Stdout: io.Discard}
+	if stdin != nil {
+		streams.Stdin = bytes.NewReader(stdin)
+	}
+	result, err := e.executor.Run(ctx, target, Command{Args: args}, streams)
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return &ErrNonZeroExit{Code: result.ExitCode}
+	}
+	return nil
+}
+
+// Cleanup removes toolboxes from all containers they have been injected
+// into. The toolbox removes itself, as the container may not have rm.
+func (e *ToolboxExecutor) Cleanup(ctx context.Context) []error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var errs []error
+	for target, state := range e.toolboxes {
+		if state.path == "" {
+			continue
+		}
+		if err := e.run(ctx, target, []string{state.path, "rm", "-f", state.path}, nil); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove the toolbox from %s/%s: %w", target.Pod, target.Container, err))
+		}
+	}
+	return errs
+}
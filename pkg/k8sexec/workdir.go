@@ -0,0 +1,41 @@
+package k8sexec
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// workdirScript changes to the directory $0 and executes the command of
+// the remaining arguments.
+const workdirScript = `cd "$0" && exec "$@"`
+
+// WorkdirExecutor runs commands with another executor in a working
+// directory, which the exec API does not support. Scripts streamed to a
+// shell on stdin are preceded by cd, other commands are wrapped with sh.
+type WorkdirExecutor struct {
+	executor Executor
+	dir      string
+}
+
+// NewWorkdirExecutor creates a WorkdirExecutor running commands of executor
+// in the absolute directory dir.
+func NewWorkdirExecutor(executor Executor, dir string) (*WorkdirExecutor, error) {
+	if !path.IsAbs(dir) {
+		return nil, fmt.Errorf("working directory %q is not absolute", dir)
+	}
+	return &WorkdirExecutor{executor: executor, dir: dir}, nil
+}
+
+// Run implements Executor.
+func (e *WorkdirExecutor) Run(ctx context.Context, target Target, cmd Command, streams IO) (Result, error) {
+	if len(cmd.Args) == 1 && shells[path.Base(cmd.Args[0])] && streams.Stdin != nil {
+		preamble := fmt.Sprintf("cd '%s' || exit 1\n", strings.ReplaceAll(e.dir, "'", `'\''`))
+		streams.Stdin = io.MultiReader(strings.NewReader(preamble), streams.Stdin)
+	} else {
+		cmd = Command{Args: append([]string{"sh", "-c", workdirScript, e.dir}, cmd.Args...)}
+	}
+	return e.executor.Run(ctx, target, cmd, streams)
+}
@@ -0,0 +1,73 @@
+// Package netcheck probes network reachability from containers.
+//
+// Probes are TCP connection attempts made with nc, curl or bash, whichever
+// is available in a container.
+package netcheck
+
+import (
+	"bufio"
+	"embed"
+	"strings"
+)
+
+// Results of probes.
+const (
+	ResultOpen       = "open"
+	ResultClosed     = "closed"
+	ResultBlocked    = "blocked"
+	ResultUnresolved = "unresolved"
+	ResultUntested   = "untested"
+)
+
+//go:embed scripts
+var scripts embed.FS
+
+// ProbeLib defines the shell function probe HOST PORT TIMEOUT printing the
+// result of a probe and the tool used, scripts use it by prepending it.
+var ProbeLib = script("probe.sh")
+
+// Script probes destinations given to it as HOST:PORT parameters following
+// the connection timeout in seconds, it is executed with sh.
+var Script = ProbeLib + "\n" + script("netcheck.sh")
+
+// HTTPScript prints the HTTP status code of a GET request made with curl or
+// wget, it is executed with sh with the timeout in seconds, the URL and
+// headers as parameters. It exits with 127 when neither is available.
+var HTTPScript = script("http.sh")
+
+// Probe is the outcome of a connection attempt.
+type Probe struct {
+	Destination string `json:"Destination"`
+	Result      string `json:"Result"`
+	Tool        string `json:"Tool"`
+}
+
+// Reachable reports whether the destination has been reached, connections
+// refused are reachable as well.
+func (p Probe) Reachable() bool {
+	return p.Result == ResultOpen || p.Result == ResultClosed
+}
+
+func script(name string) string {
+	body, err := scripts.ReadFile("scripts/" + name)
+	if err != nil {
+		panic(err)
+	}
+	return string(body)
+}
+
+// ParseProbes parses the output of Script by destinations.
+func ParseProbes(output string) map[string]Probe {
+	probes := map[string]Probe{}
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 4 || fields[0] != "probe" {
+			continue
+		}
+		probes[fields[1]] = Probe{Destination: fields[1], Result: fields[2], Tool: fields[3]}
+	}
+
+	return probes
+}
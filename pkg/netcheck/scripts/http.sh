@@ -0,0 +1,24 @@
+# usage: http.sh TIMEOUT URL [header...]
+# prints the HTTP status code of a GET of URL, certificates are not verified
+timeout=$1 url=$2
+shift 2
+
+if command -v curl >/dev/null 2>&1; then
+	for header in "$@"; do
+		set -- "$@" -H "$header"
+		shift
+	done
+	curl -k -s -o /dev/null -w '%{http_code}\n' -m "$timeout" "$@" "$url"
+elif command -v wget >/dev/null 2>&1; then
+	for header in "$@"; do
+		set -- "$@" --header "$header"
+		shift
+	done
+	wget -S -q -O /dev/null -T "$timeout" --no-check-certificate "$@" "$url" 2>&1 | while read -r protocol code rest; do
+		case "$protocol" in
+		HTTP/*) echo "$code" ;;
+		esac
+	done
+else
+	exit 127
+fi
@@ -0,0 +1,18 @@
+# usage: netcheck.sh TIMEOUT HOST:PORT...
+# prints probe<TAB>HOST:PORT<TAB>result<TAB>tool for every destination
+timeout=$1
+shift
+
+running=0
+for destination in "$@"; do
+	(
+		set -- $(probe "${destination%:*}" "${destination##*:}" "$timeout")
+		printf 'probe\t%s\t%s\t%s\n' "$destination" "$1" "$2"
+	) &
+	running=$((running + 1))
+	if [ "$running" -ge 16 ]; then
+		wait
+		running=0
+	fi
+done
+wait
@@ -0,0 +1,34 @@
+# probe HOST PORT TIMEOUT prints the outcome of a TCP connection attempt
+# followed by the tool used: open, closed (refused), blocked, unresolved or
+# untested when no tool is available
+probe() {
+	if command -v nc >/dev/null 2>&1; then
+		if nc -z -w "$3" "$1" "$2" </dev/null >/dev/null 2>&1; then
+			echo "open nc"
+		else
+			echo "blocked nc"
+		fi
+	elif command -v curl >/dev/null 2>&1; then
+		# the telnet protocol keeps the connection open until the time is up,
+		# the time it took to connect tells whether it has been established
+		connected=$(curl -s -o /dev/null -w '%{time_connect}' --connect-timeout "$3" -m "$3" "telnet://$1:$2" </dev/null 2>/dev/null)
+		case "$?:$connected" in
+		6:*) echo "unresolved curl" ;;
+		7:*) echo "closed curl" ;;
+		*:0 | *:0.000000 | *:) echo "blocked curl" ;;
+		*) echo "open curl" ;;
+		esac
+	elif command -v bash >/dev/null 2>&1; then
+		set -- "$1" "$2" "$3" bash
+		command -v timeout >/dev/null 2>&1 && set -- "$1" "$2" "$3" timeout "$3" bash
+		host=$1 port=$2
+		shift 3
+		if "$@" -c 'exec 3<>"/dev/tcp/$0/$1"' "$host" "$port" </dev/null >/dev/null 2>&1; then
+			echo "open bash"
+		else
+			echo "blocked bash"
+		fi
+	else
+		echo "untested none"
+	fi
+}
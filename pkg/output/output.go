@@ -0,0 +1,119 @@
+// Package output renders reports of kubex in formats selected with --output.
+//
+// The text and json formats are built in. Programs embedding the cmd package
+// can add their own formats by registering them before running it:
+//
+//	func main() {
+//		output.Register("csv", output.FormatterFunc(func(w io.Writer, report any) error {
+//			switch report := report.(type) {
+//			case *cmd.EnumerationStatus:
+//				...
+//			}
+//			return output.ErrUnsupported
+//		}))
+//		if err := cmd.Execute(); err != nil {
+//			os.Exit(1)
+//		}
+//	}
+//
+// Formatters receive the report types of the cmd package, e.g.
+// *cmd.EnumerationStatus, see "kubex schema" for their structure.
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Formatter writes a report in a format.
+type Formatter interface {
+	Format(w io.Writer, report any) error
+}
+
+// FormatterFunc adapts a function to Formatter.
+type FormatterFunc func(w io.Writer, report any) error
+
+// Format implements Formatter.
+func (f FormatterFunc) Format(w io.Writer, report any) error {
+	return f(w, report)
+}
+
+// TextWriter is implemented by reports which render themselves for people,
+// the text format uses it.
+type TextWriter interface {
+	WriteText(w io.Writer) error
+}
+
+// ErrUnsupported is returned by formatters for reports they cannot render.
+var ErrUnsupported = errors.New("report not supported by the output format")
+
+var registry = struct {
+	sync.RWMutex
+	formatters map[string]Formatter
+}{formatters: map[string]Formatter{}}
+
+// Register makes a formatter available by its name, it panics when the name
+// is already taken.
+func Register(name string, formatter Formatter) {
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.formatters[name]; ok {
+		panic(fmt.Sprintf("output format %s registered twice", name))
+	}
+	registry.formatters[name] = formatter
+}
+
+// Lookup returns the formatter registered with name.
+func Lookup(name string) (Formatter, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	formatter, ok := registry.formatters[name]
+	return formatter, ok
+}
+
+// Names returns names of registered formats, sorted.
+func Names() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+	names := make([]string, 0, len(registry.formatters))
+	for name := range registry.formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Write writes report to w in the format registered with name.
+func Write(w io.Writer, name string, report any) error {
+	formatter, ok := Lookup(name)
+	if !ok {
+		return fmt.Errorf("unknown output format %q", name)
+	}
+	return formatter.Format(w, report)
+}
+
+func formatJSON(w io.Writer, report any) error {
+	jsonBuff, err := json.MarshalIndent(report, "", "    ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(jsonBuff))
+	return err
+}
+
+func formatText(w io.Writer, report any) error {
+	text, ok := report.(TextWriter)
+	if !ok {
+		return fmt.Errorf("%w: %T", ErrUnsupported, report)
+	}
+	return text.WriteText(w)
+}
+
+func init() {
+	Register("json", FormatterFunc(formatJSON))
+	Register("text", FormatterFunc(formatText))
+}
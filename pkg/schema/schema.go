@@ -0,0 +1,160 @@
+// Package schema describes machine-readable outputs of kubex with JSON
+// Schema generated from the Go types they are encoded from.
+package schema
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Version is the version of machine-readable outputs reported in their
+// schemaVersion field. It is bumped whenever fields are renamed, removed or
+// change their meaning, new fields do not bump it.
+const Version = 1
+
+// Draft is the JSON Schema dialect of generated schemas.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema is a JSON Schema document.
+type Schema map[string]any
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Generator generates schemas of Go types, sharing definitions of named
+// struct types between them.
+type Generator struct {
+	defs map[string]Schema
+}
+
+// NewGenerator creates a Generator.
+func NewGenerator() *Generator {
+	return &Generator{defs: map[string]Schema{}}
+}
+
+// Ref returns a schema of the type of value as it is encoded by
+// encoding/json, struct types are referenced from definitions.
+func (g *Generator) Ref(value any) Schema {
+	return g.schema(reflect.TypeOf(value))
+}
+
+// Document returns a complete schema document with the given title, root
+// schema and all definitions generated so far.
+func (g *Generator) Document(title string, root Schema) Schema {
+	document := Schema{"$schema": Draft, "title": title, "$defs": g.defs}
+	for key, value := range root {
+		document[key] = value
+	}
+	return document
+}
+
+func (g *Generator) schema(t reflect.Type) Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return Schema{"type": "string", "format": "date-time"}
+	case t == durationType:
+		return Schema{"type": "integer", "description": "duration in nanoseconds"}
+	case t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType):
+		// encoded by its own rules, e.g. resource.Quantity
+		return Schema{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return Schema{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return Schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Schema{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return Schema{"type": "number"}
+	case reflect.String:
+		return Schema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return Schema{"type": "string", "contentEncoding": "base64"}
+		}
+		return Schema{"type": []string{"array", "null"}, "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return Schema{"type": []string{"object", "null"}, "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name := defName(t)
+		if _, ok := g.defs[name]; !ok {
+			// registered before its fields to stop recursion
+			g.defs[name] = Schema{}
+			g.defs[name] = g.object(t)
+		}
+		return Schema{"$ref": "#/$defs/" + name}
+	default:
+		return Schema{}
+	}
+}
+
+// object returns the schema of a struct type following encoding/json rules
+// for field names, omitted and embedded fields.
+func (g *Generator) object(t reflect.Type) Schema {
+	properties := Schema{}
+	var required []string
+	g.fields(t, properties, &required)
+
+	object := Schema{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		object["required"] = required
+	}
+	return object
+}
+
+func (g *Generator) fields(t reflect.Type, properties Schema, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			g.fields(fieldType, properties, required)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := g.schema(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			*required = append(*required, name)
+			if field.Type.Kind() == reflect.Pointer {
+				// nil pointers are encoded as null
+				property = Schema{"anyOf": []Schema{property, {"type": "null"}}}
+			}
+		}
+		properties[name] = property
+	}
+}
+
+// defName names a definition after the import path and name of t, e.g.
+// k8s.io.api.core.v1.Volume, so that types of packages with the same name
+// do not collide.
+func defName(t reflect.Type) string {
+	return strings.ReplaceAll(t.PkgPath(), "/", ".") + "." + t.Name()
+}
@@ -0,0 +1,554 @@
+// Package script implements a small language orchestrating commands executed
+// in a container, e.g. running commands depending on the output of earlier
+// ones or for each path found by another.
+//
+//	# print configuration files mentioning passwords
+//	set os = run cat /etc/os-release
+//	if os ~ "ID=alpine" {
+//	    set files = run find /etc -name '*.conf'
+//	} else {
+//	    set files = run find /etc /opt -name '*.conf'
+//	}
+//	for file in files {
+//	    run grep -l password {{file}}
+//	    if status == "0" {
+//	        print password found in {{file}}
+//	    }
+//	}
+//
+// Statements are:
+//
+//	run COMMAND              executes COMMAND with sh -c, its output is part of the result
+//	set NAME = run COMMAND   executes COMMAND and sets NAME to its trimmed output
+//	set NAME = TEXT          sets NAME to TEXT
+//	print TEXT               adds TEXT to the result
+//	fail TEXT                stops the script reporting TEXT as an error
+//	if A OP B { ... } else { ... }
+//	for NAME in VARIABLE { ... }
+//
+// Every run sets the variables stdout, stderr and status (the exit code).
+// {{NAME}} in texts and commands is replaced with the value of variable NAME,
+// in commands it is quoted for the shell so that output of containers cannot
+// inject commands. Scripts writing {{NAME}} inside quotes of commands, where
+// the quoting would be taken literally, are rejected. Conditions compare variables or quoted strings with ==
+// and !=, or match them with regular expressions with ~ and !~. Loops iterate
+// over non-empty lines of a variable.
+//
+// The language is deliberately small: it has no loops other than over
+// existing values, so that scripts always terminate.
+package script
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Result is the outcome of a command executed by a script.
+type Result struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+}
+
+// Exec executes command with sh -c in a container. Errors stop the script,
+// commands exiting with a non-zero code do not.
+type Exec func(ctx context.Context, command string) (Result, error)
+
+// Output is what a script produced, Stdout holds output of commands and
+// printed texts.
+type Output struct {
+	Stdout strings.Builder
+	Stderr strings.Builder
+}
+
+// Script is a parsed script.
+type Script struct {
+	body []statement
+}
+
+type statement interface {
+	exec(ctx context.Context, env *env) error
+}
+
+type env struct {
+	exec Exec
+	vars map[string]string
+	out  *Output
+}
+
+// FailError is returned by Run for scripts stopped with fail.
+type FailError struct {
+	Message string
+}
+
+func (e *FailError) Error() string {
+	return e.Message
+}
+
+var (
+	templateRe = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+	nameRe     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Parse parses the source of a script.
+func Parse(source string) (*Script, error) {
+	p := &parser{lines: strings.Split(source, "\n")}
+	body, closing, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+	if closing != "" {
+		return nil, p.unexpected(closing)
+	}
+	return &Script{body: body}, nil
+}
+
+// Run runs the script executing its commands with exec and returns what it
+// printed. Errors of exec and fail stop it.
+func (s *Script) Run(ctx context.Context, exec Exec) (*Output, error) {
+	e := &env{exec: exec, vars: map[string]string{}, out: &Output{}}
+	return e.out, run(ctx, e, s.body)
+}
+
+func run(ctx context.Context, e *env, body []statement) error {
+	for _, stmt := range body {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := stmt.exec(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SyntaxError is returned by Parse for invalid scripts.
+type SyntaxError struct {
+	Line int
+	Err  error
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+type parser struct {
+	lines []string
+	pos   int
+}
+
+// block parses statements up to the end of source or a line closing the
+// block, which is returned.
+func (p *parser) block() ([]statement, string, error) {
+	var body []statement
+	for p.pos < len(p.lines) {
+		line := strings.TrimSpace(p.lines[p.pos])
+		p.pos++
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "}") {
+			return body, line, nil
+		}
+
+		start := p.pos
+		stmt, err := p.statement(line)
+		var syntaxErr *SyntaxError
+		if err != nil && !errors.As(err, &syntaxErr) {
+			err = &SyntaxError{Line: start, Err: err}
+		}
+		if err != nil {
+			return nil, "", err
+		}
+		body = append(body, stmt)
+	}
+	return body, "", nil
+}
+
+func (p *parser) unexpected(closing string) error {
+	return &SyntaxError{Line: p.pos, Err: fmt.Errorf("unexpected %q", closing)}
+}
+
+// nested parses the body of a statement opened on the current line.
+func (p *parser) nested() ([]statement, string, error) {
+	line := p.pos
+	body, closing, err := p.block()
+	if err != nil {
+		return nil, "", err
+	}
+	if closing == "" {
+		return nil, "", &SyntaxError{Line: line, Err: errors.New("missing }")}
+	}
+	return body, closing, nil
+}
+
+func (p *parser) statement(line string) (statement, error) {
+	keyword, rest, _ := strings.Cut(line, " ")
+	rest = strings.TrimSpace(rest)
+
+	switch keyword {
+	case "run":
+		if rest == "" {
+			return nil, fmt.Errorf("run requires a command")
+		}
+		return newRunStmt(rest, "")
+	case "set":
+		name, value, ok := strings.Cut(rest, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !nameRe.MatchString(name) {
+			return nil, fmt.Errorf("set requires NAME = VALUE")
+		}
+		value = strings.TrimSpace(value)
+		if command, ok := strings.CutPrefix(value, "run "); ok {
+			return newRunStmt(strings.TrimSpace(command), name)
+		}
+		return &setStmt{name: name, value: value}, nil
+	case "print":
+		return &printStmt{text: rest}, nil
+	case "fail":
+		return &failStmt{text: rest}, nil
+	case "if":
+		return p.ifStatement(rest)
+	case "for":
+		return p.forStatement(rest)
+	default:
+		return nil, fmt.Errorf("unknown statement %q", keyword)
+	}
+}
+
+func (p *parser) ifStatement(rest string) (statement, error) {
+	condition, ok := strings.CutSuffix(rest, "{")
+	if !ok {
+		return nil, fmt.Errorf("if must end with {")
+	}
+	cond, err := parseCondition(strings.TrimSpace(condition))
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := &ifStmt{cond: cond}
+	var closing string
+	if stmt.then, closing, err = p.nested(); err != nil {
+		return nil, err
+	}
+	switch strings.Join(strings.Fields(closing), " ") {
+	case "}":
+	case "} else {":
+		if stmt.otherwise, closing, err = p.nested(); err != nil {
+			return nil, err
+		}
+		if closing != "}" {
+			return nil, p.unexpected(closing)
+		}
+	default:
+		return nil, p.unexpected(closing)
+	}
+	return stmt, nil
+}
+
+func (p *parser) forStatement(rest string) (statement, error) {
+	fields := strings.Fields(rest)
+	if len(fields) != 4 || fields[1] != "in" || fields[3] != "{" || !nameRe.MatchString(fields[0]) || !nameRe.MatchString(fields[2]) {
+		return nil, fmt.Errorf("for requires NAME in VARIABLE {")
+	}
+
+	body, closing, err := p.nested()
+	if err != nil {
+		return nil, err
+	}
+	if closing != "}" {
+		return nil, p.unexpected(closing)
+	}
+	return &forStmt{name: fields[0], source: fields[2], body: body}, nil
+}
+
+// operand of a condition, either a quoted string or a variable.
+type operand struct {
+	literal  string
+	variable string
+}
+
+func (o operand) value(e *env) (string, error) {
+	if o.variable == "" {
+		return o.literal, nil
+	}
+	return e.lookup(o.variable)
+}
+
+type condition struct {
+	left, right operand
+	op          string
+	re          *regexp.Regexp
+}
+
+func parseCondition(s string) (*condition, error) {
+	left, s, err := parseOperand(s)
+	if err != nil {
+		return nil, err
+	}
+	op, s, _ := strings.Cut(strings.TrimSpace(s), " ")
+	right, s, err := parseOperand(strings.TrimSpace(s))
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(s) != "" {
+		return nil, fmt.Errorf("unexpected %q in condition", s)
+	}
+
+	cond := &condition{left: left, right: right, op: op}
+	switch op {
+	case "==", "!=":
+	case "~", "!~":
+		if right.variable == "" {
+			if cond.re, err = regexp.Compile(right.literal); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown operator %q, must be one of ==, !=, ~, !~", op)
+	}
+	return cond, nil
+}
+
+// parseOperand parses an operand at the beginning of s and returns the rest.
+func parseOperand(s string) (operand, string, error) {
+	if strings.HasPrefix(s, `"`) {
+		prefix, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			return operand{}, "", fmt.Errorf("invalid string in condition: %s", s)
+		}
+		literal, _ := strconv.Unquote(prefix)
+		return operand{literal: literal}, s[len(prefix):], nil
+	}
+
+	word, rest, _ := strings.Cut(s, " ")
+	if _, err := strconv.Atoi(word); err == nil {
+		return operand{literal: word}, rest, nil
+	}
+	if !nameRe.MatchString(word) {
+		return operand{}, "", fmt.Errorf("invalid operand %q, must be a variable or a quoted string", word)
+	}
+	return operand{variable: word}, rest, nil
+}
+
+func (c *condition) eval(e *env) (bool, error) {
+	left, err := c.left.value(e)
+	if err != nil {
+		return false, err
+	}
+	right, err := c.right.value(e)
+	if err != nil {
+		return false, err
+	}
+
+	switch c.op {
+	case "==":
+		return left == right, nil
+	case "!=":
+		return left != right, nil
+	}
+
+	re := c.re
+	if re == nil {
+		if re, err = regexp.Compile(right); err != nil {
+			return false, err
+		}
+	}
+	return re.MatchString(left) == (c.op == "~"), nil
+}
+
+func (e *env) lookup(name string) (string, error) {
+	value, ok := e.vars[name]
+	if !ok {
+		return "", fmt.Errorf("undefined variable %s", name)
+	}
+	return value, nil
+}
+
+// expand replaces {{NAME}} in s with values of variables, passed through
+// quote.
+func (e *env) expand(s string, quote func(string) string) (string, error) {
+	var err error
+	expanded := templateRe.ReplaceAllStringFunc(s, func(match string) string {
+		value, lookupErr := e.lookup(templateRe.FindStringSubmatch(match)[1])
+		if lookupErr != nil {
+			err = lookupErr
+		}
+		return quote(value)
+	})
+	return expanded, err
+}
+
+func raw(s string) string {
+	return s
+}
+
+// shellQuote quotes s as a single word for sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+type runStmt struct {
+	command string
+	capture string
+}
+
+func newRunStmt(command, capture string) (*runStmt, error) {
+	if name := quotedTemplate(command); name != "" {
+		return nil, fmt.Errorf("%s must not be quoted in commands, values are quoted for the shell", name)
+	}
+	return &runStmt{command: command, capture: capture}, nil
+}
+
+// quotedTemplate returns the first {{NAME}} inside single or double quotes
+// of command, an empty string when there is none. Command substitutions
+// start unquoted contexts, also inside double quotes.
+func quotedTemplate(command string) string {
+	matches := templateRe.FindAllStringIndex(command, -1)
+	// quote is the open quote of a nested context, or 0, closing is the
+	// character closing its command substitution
+	type context struct{ quote, closing byte }
+	contexts := []context{{}}
+	for i := 0; i < len(command) && len(matches) > 0; i++ {
+		for len(matches) > 0 && matches[0][0] < i {
+			// escaped
+			matches = matches[1:]
+		}
+		top := &contexts[len(contexts)-1]
+		if len(matches) > 0 && matches[0][0] == i {
+			if top.quote != 0 {
+				return command[matches[0][0]:matches[0][1]]
+			}
+			matches = matches[1:]
+		}
+
+		c := command[i]
+		switch {
+		case top.quote == '\'':
+			if c == '\'' {
+				top.quote = 0
+			}
+		case c == '\\':
+			i++
+		case c == '\'' && top.quote == 0:
+			top.quote = c
+		case c == '"':
+			top.quote ^= '"'
+		case c == '`' && top.closing == '`':
+			contexts = contexts[:len(contexts)-1]
+		case c == '`':
+			contexts = append(contexts, context{closing: '`'})
+		case c == '$' && i+1 < len(command) && command[i+1] == '(':
+			contexts = append(contexts, context{closing: ')'})
+			i++
+		case c == '(' && top.quote == 0:
+			contexts = append(contexts, context{closing: ')'})
+		case c == ')' && top.quote == 0 && top.closing == ')':
+			contexts = contexts[:len(contexts)-1]
+		}
+	}
+	return ""
+}
+
+func (s *runStmt) exec(ctx context.Context, e *env) error {
+	command, err := e.expand(s.command, shellQuote)
+	if err != nil {
+		return err
+	}
+	result, err := e.exec(ctx, command)
+	if err != nil {
+		return err
+	}
+
+	e.vars["stdout"] = result.Stdout
+	e.vars["stderr"] = result.Stderr
+	e.vars["status"] = strconv.Itoa(result.ExitCode)
+	e.out.Stderr.WriteString(result.Stderr)
+	if s.capture != "" {
+		e.vars[s.capture] = strings.TrimSpace(result.Stdout)
+	} else {
+		e.out.Stdout.WriteString(result.Stdout)
+	}
+	return nil
+}
+
+type setStmt struct {
+	name  string
+	value string
+}
+
+func (s *setStmt) exec(_ context.Context, e *env) error {
+	value, err := e.expand(s.value, raw)
+	e.vars[s.name] = value
+	return err
+}
+
+type printStmt struct {
+	text string
+}
+
+func (s *printStmt) exec(_ context.Context, e *env) error {
+	text, err := e.expand(s.text, raw)
+	e.out.Stdout.WriteString(text + "\n")
+	return err
+}
+
+type failStmt struct {
+	text string
+}
+
+func (s *failStmt) exec(_ context.Context, e *env) error {
+	text, err := e.expand(s.text, raw)
+	if err != nil {
+		return err
+	}
+	return &FailError{Message: text}
+}
+
+type ifStmt struct {
+	cond      *condition
+	then      []statement
+	otherwise []statement
+}
+
+func (s *ifStmt) exec(ctx context.Context, e *env) error {
+	ok, err := s.cond.eval(e)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return run(ctx, e, s.then)
+	}
+	return run(ctx, e, s.otherwise)
+}
+
+type forStmt struct {
+	name   string
+	source string
+	body   []statement
+}
+
+func (s *forStmt) exec(ctx context.Context, e *env) error {
+	values, err := e.lookup(s.source)
+	if err != nil {
+		return err
+	}
+	for _, value := range strings.Split(values, "\n") {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		e.vars[s.name] = value
+		if err := run(ctx, e, s.body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package script
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"run", "line 1: run requires a command"},
+		{"set x run id", "line 1: set requires NAME = VALUE"},
+		{"set 1x = a", "line 1: set requires NAME = VALUE"},
+		{"id", `line 1: unknown statement "id"`},
+		{"if a == b", "line 1: if must end with {"},
+		{"if a < b {\n}", `unknown operator "<"`},
+		{`if a ~ "(" {` + "\n}", "missing closing )"},
+		{"for x files {\n}", "for requires NAME in VARIABLE {"},
+		{"print a\nfor x in files {\nprint {{x}}", "line 2: missing }"},
+		{"}", `line 1: unexpected "}"`},
+
+		// templates must not be quoted
+		{`run grep -l password "{{file}}"`, "{{file}} must not be quoted"},
+		{`run grep -l password '{{file}}'`, "{{file}} must not be quoted"},
+		{`set x = run echo "found: {{ file }}"`, "{{ file }} must not be quoted"},
+		{`run echo "$(cat {{a}}) {{b}}"`, "{{b}} must not be quoted"},
+		{`run echo "$(echo "{{a}}")"`, "{{a}} must not be quoted"},
+		{"run echo \"`echo '{{a}}'`\"", "{{a}} must not be quoted"},
+	}
+	for _, test := range tests {
+		_, err := Parse(test.source)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("Parse(%q) = %v, want a syntax error with %q", test.source, err, test.want)
+		}
+	}
+}
+
+func TestParseUnquotedTemplates(t *testing.T) {
+	for _, source := range []string{
+		`run grep -l password {{file}}`,
+		`run echo "$(cat {{file}})"`,
+		`run echo "a" {{file}} 'b'`,
+		`run echo "it's" {{file}}`,
+		`run echo \"{{file}}`,
+		`run (cd /tmp && ls {{file}})`,
+		"run echo \"`cat {{file}}`\"",
+		`print "{{file}}"`,
+		`set x = "{{file}}"`,
+	} {
+		if _, err := Parse(source); err != nil {
+			t.Errorf("Parse(%q): %v", source, err)
+		}
+	}
+}
+
+// shell executes commands with the local sh.
+func shell(ctx context.Context, command string) (Result, error) {
+	var stdout, stderr strings.Builder
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return Result{Stdout: stdout.String(), Stderr: stderr.String(), ExitCode: exitErr.ExitCode()}, nil
+	}
+	return Result{Stdout: stdout.String(), Stderr: stderr.String()}, err
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+		err    string
+	}{
+		{
+			name: "loop and conditions",
+			source: `set files = run printf 'a.conf\n\nb.conf\n'
+for file in files {
+    if file ~ "^a" {
+        print first {{file}}
+    } else {
+        print other {{file}}
+    }
+}`,
+			want: "first a.conf\nother b.conf\n",
+		},
+		{
+			name: "status",
+			source: `run exit 3
+if status != "0" {
+    print failed with {{status}}
+}`,
+			want: "failed with 3\n",
+		},
+		{
+			name:   "output of commands",
+			source: "run echo hello\nset x = run echo captured\nprint {{x}}",
+			want:   "hello\ncaptured\n",
+		},
+		{
+			name:   "fail",
+			source: "print before\nfail stopped\nprint after",
+			want:   "before\n",
+			err:    "stopped",
+		},
+		{
+			name:   "undefined variable",
+			source: "run echo {{missing}}",
+			err:    "undefined variable missing",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			script, err := Parse(test.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := script.Run(context.Background(), shell)
+			if (err != nil || test.err != "") && (err == nil || err.Error() != test.err) {
+				t.Errorf("Run = %v, want error %q", err, test.err)
+			}
+			if out.Stdout.String() != test.want {
+				t.Errorf("Stdout = %q, want %q", out.Stdout.String(), test.want)
+			}
+		})
+	}
+}
+
+func TestRunQuotesTemplates(t *testing.T) {
+	// output of containers holding shell syntax is passed as a single word
+	for _, value := range []string{`$(echo injected)`, "`echo injected`", `a'; echo injected; '`, `a b  *`, `"; echo injected; "`} {
+		script, err := Parse("set x = run printf '%s' \"$VALUE\"\nset y = run printf '%s|' {{x}}\nprint {{y}}")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var commands []string
+		out, err := script.Run(context.Background(), func(ctx context.Context, command string) (Result, error) {
+			commands = append(commands, command)
+			return shell(ctx, "VALUE="+shellQuote(value)+"; "+command)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := value + "|\n"; out.Stdout.String() != want {
+			t.Errorf("value %q printed %q, want %q from %q", value, out.Stdout.String(), want, commands)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+// Package testspec loads specs of assertions about commands executed in
+// containers and evaluates them.
+//
+// A spec is a YAML file, e.g.
+//
+//	tests:
+//	  - name: no shell history
+//	    selector:
+//	      labels: {app: web}
+//	      container: ^nginx$
+//	    command: [sh, -c, "ls -a /root"]
+//	    expect:
+//	      notStdout: \.sh_history
+//	  - name: nginx configuration is valid
+//	    script: nginx -t
+//	    expect:
+//	      exitCode: 0
+//	      stderr: syntax is ok
+package testspec
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"k8sexec/pkg/k8sexec"
+	"os"
+	"regexp"
+)
+
+// Spec is a list of tests.
+type Spec struct {
+	Tests []*Test `yaml:"tests"`
+}
+
+// Test is a command executed in containers matching a selector and its
+// expected outcome.
+type Test struct {
+	Name     string   `yaml:"name"`
+	Selector Selector `yaml:"selector"`
+	// Command is executed in containers, it defaults to sh when a Script is
+	// given.
+	Command []string `yaml:"command"`
+	// Script is passed to Command as its standard input.
+	Script string      `yaml:"script"`
+	Expect Expectation `yaml:"expect"`
+}
+
+// Selector selects containers of tests by regular expressions, empty
+// fields match all containers.
+type Selector struct {
+	Pod       string            `yaml:"pod"`
+	Container string            `yaml:"container"`
+	Image     string            `yaml:"image"`
+	Labels    map[string]string `yaml:"labels"`
+
+	pod, container, image *regexp.Regexp
+}
+
+// Expectation is the outcome of a test command, unset fields are not
+// verified.
+type Expectation struct {
+	// ExitCode defaults to 0.
+	ExitCode *int `yaml:"exitCode"`
+	// Stdout and Stderr are regular expressions outputs must match,
+	// NotStdout one stdout must not match.
+	Stdout    string `yaml:"stdout"`
+	NotStdout string `yaml:"notStdout"`
+	Stderr    string `yaml:"stderr"`
+	// Empty expects no output at all.
+	Empty bool `yaml:"empty"`
+
+	stdout, notStdout, stderr *regexp.Regexp
+}
+
+// Load reads and validates the spec at path.
+func Load(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := &Spec{}
+	if err := yaml.UnmarshalStrict(data, spec); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(spec.Tests) == 0 {
+		return nil, fmt.Errorf("%s: no tests", path)
+	}
+
+	for i, test := range spec.Tests {
+		if test.Name == "" {
+			test.Name = fmt.Sprintf("test %d", i+1)
+		}
+		if len(test.Command) == 0 && test.Script == "" {
+			return nil, fmt.Errorf("%s: %s has neither command nor script", path, test.Name)
+		}
+		if len(test.Command) == 0 {
+			test.Command = []string{"sh"}
+		}
+
+		for _, re := range []struct {
+			expr   string
+			target **regexp.Regexp
+		}{
+			{test.Selector.Pod, &test.Selector.pod},
+			{test.Selector.Container, &test.Selector.container},
+			{test.Selector.Image, &test.Selector.image},
+			{test.Expect.Stdout, &test.Expect.stdout},
+			{test.Expect.NotStdout, &test.Expect.notStdout},
+			{test.Expect.Stderr, &test.Expect.stderr},
+		} {
+			if re.expr == "" {
+				continue
+			}
+			if *re.target, err = regexp.Compile(re.expr); err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", path, test.Name, err)
+			}
+		}
+	}
+
+	return spec, nil
+}
+
+// Matches reports whether s selects the container of target whose image and
+// pod labels are given.
+func (s *Selector) Matches(target k8sexec.Target, image string, labels map[string]string) bool {
+	switch {
+	case s.pod != nil && !s.pod.MatchString(target.Pod):
+		return false
+	case s.container != nil && !s.container.MatchString(target.Container):
+		return false
+	case s.image != nil && !s.image.MatchString(image):
+		return false
+	}
+	for key, value := range s.Labels {
+		if v, ok := labels[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Evaluate returns failed expectations of status, none when the test passed.
+func (e *Expectation) Evaluate(status *k8sexec.ExecutionStatus) []string {
+	if status.Error != "" {
+		return []string{fmt.Sprintf("execution failed [%s]: %s", status.ErrorKind, status.Error)}
+	}
+
+	var failures []string
+	exitCode := 0
+	if e.ExitCode != nil {
+		exitCode = *e.ExitCode
+	}
+	if status.RetCode != exitCode {
+		failures = append(failures, fmt.Sprintf("exit code %d, expected %d", status.RetCode, exitCode))
+	}
+	if e.stdout != nil && !e.stdout.MatchString(status.Stdout) {
+		failures = append(failures, fmt.Sprintf("stdout does not match %q", e.Stdout))
+	}
+	if e.notStdout != nil && e.notStdout.MatchString(status.Stdout) {
+		failures = append(failures, fmt.Sprintf("stdout matches %q", e.NotStdout))
+	}
+	if e.stderr != nil && !e.stderr.MatchString(status.Stderr) {
+		failures = append(failures, fmt.Sprintf("stderr does not match %q", e.Stderr))
+	}
+	if e.Empty && (status.Stdout != "" || status.Stderr != "") {
+		failures = append(failures, "output is not empty")
+	}
+	return failures
+}
@@ -0,0 +1,37 @@
+// Package toolbox holds static busybox binaries embedded into kubex, which
+// are injected into containers without a shell. None are part of the source
+// tree, see bin/README.md for how to add them to a build.
+package toolbox
+
+import (
+	"embed"
+	"strings"
+)
+
+//go:embed bin
+var binaries embed.FS
+
+// Busybox returns the embedded busybox binary built for arch, e.g. amd64,
+// false when there is none.
+func Busybox(arch string) ([]byte, bool) {
+	if arch == "" {
+		return nil, false
+	}
+	binary, err := binaries.ReadFile("bin/busybox-" + arch)
+	if err != nil {
+		return nil, false
+	}
+	return binary, true
+}
+
+// Architectures returns architectures busybox binaries are embedded for.
+func Architectures() []string {
+	entries, _ := binaries.ReadDir("bin")
+	var archs []string
+	for _, entry := range entries {
+		if arch, ok := strings.CutPrefix(entry.Name(), "busybox-"); ok {
+			archs = append(archs, arch)
+		}
+	}
+	return archs
+}
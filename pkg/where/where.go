@@ -0,0 +1,508 @@
+// Package where filters structs, e.g. results of commands, with expressions
+// over their fields:
+//
+//	RetCode != 0 && Stdout contains "root"
+//	!(Pod startsWith "web-") || Duration > "5s"
+//	Image matches "^registry\.internal/" && ErrorKind == ""
+//	ErrorKind in ["Timeout", "ConnectionFailed"]
+//
+// Operands are names of fields, strings in double quotes with Go escapes,
+// numbers and true or false. Fields of type string, bool, integer, float and
+// time.Duration are supported, durations are compared with strings like
+// "1m30s".
+//
+// Operators, from the lowest precedence: ||, &&, !, and the comparisons ==,
+// !=, <, <=, >, >=, contains, startsWith, endsWith, matches and in. The right
+// operand of matches is a regular expression in a string, the one of in a
+// list of literals in brackets. Parentheses group expressions. Expressions
+// are type checked when they are parsed.
+package where
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type kind int
+
+const (
+	kindBool kind = iota
+	kindString
+	kindNumber
+	kindDuration
+)
+
+func (k kind) String() string {
+	return [...]string{"bool", "string", "number", "duration"}[k]
+}
+
+// Expr is a parsed expression.
+type Expr struct {
+	source string
+	typ    reflect.Type
+	root   node
+}
+
+type node interface {
+	kind() kind
+	eval(v reflect.Value) any
+}
+
+// Parse parses expr evaluated over fields of structs of type t, or pointers
+// to them.
+func Parse(expr string, t reflect.Type) (*Expr, error) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, typ: t}
+	root, err := p.or()
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", expr, err)
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("invalid expression %q: unexpected %s", expr, p.tokens[p.pos].text)
+	}
+	if root.kind() != kindBool {
+		return nil, fmt.Errorf("invalid expression %q: %s is not a condition", expr, root.kind())
+	}
+	return &Expr{source: expr, typ: t, root: root}, nil
+}
+
+// Match reports whether v, a struct of the type the expression was parsed
+// for or a pointer to it, matches the expression.
+func (e *Expr) Match(v any) bool {
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return false
+		}
+		value = value.Elem()
+	}
+	if value.Type() != e.typ {
+		panic(fmt.Sprintf("where: expression parsed for %s matched against %s", e.typ, value.Type()))
+	}
+	return e.root.eval(value).(bool)
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.source
+}
+
+type token struct {
+	text string
+	// literal is set for strings and numbers
+	literal any
+}
+
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "(", ")", "[", "]", ","}
+
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(expr) && expr[end] != '"' {
+				if expr[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expr) {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			value, err := strconv.Unquote(expr[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s", expr[i:end+1])
+			}
+			tokens = append(tokens, token{text: expr[i : end+1], literal: value})
+			i = end + 1
+		case c >= '0' && c <= '9' || c == '-':
+			end := i + 1
+			for end < len(expr) && (expr[end] >= '0' && expr[end] <= '9' || expr[end] == '.') {
+				end++
+			}
+			value, err := strconv.ParseFloat(expr[i:end], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %s", expr[i:end])
+			}
+			tokens = append(tokens, token{text: expr[i:end], literal: value})
+			i = end
+		case isIdentifier(c):
+			end := i + 1
+			for end < len(expr) && (isIdentifier(expr[end]) || expr[end] >= '0' && expr[end] <= '9') {
+				end++
+			}
+			tokens = append(tokens, token{text: expr[i:end]})
+			i = end
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(expr[i:], op) {
+					tokens = append(tokens, token{text: op})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+		}
+	}
+	return tokens, nil
+}
+
+func isIdentifier(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	typ    reflect.Type
+}
+
+// peek returns the text of the next token, an empty string at the end.
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos].text
+	}
+	return ""
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	for err == nil && p.peek() == "||" {
+		p.pos++
+		var right node
+		if right, err = p.and(); err == nil {
+			left, err = logical("||", left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.unary()
+	for err == nil && p.peek() == "&&" {
+		p.pos++
+		var right node
+		if right, err = p.unary(); err == nil {
+			left, err = logical("&&", left, right)
+		}
+	}
+	return left, err
+}
+
+func (p *parser) unary() (node, error) {
+	if p.peek() != "!" {
+		return p.comparison()
+	}
+	p.pos++
+	x, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	if x.kind() != kindBool {
+		return nil, fmt.Errorf("! of %s", x.kind())
+	}
+	return notNode{x}, nil
+}
+
+func (p *parser) comparison() (node, error) {
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	switch op := p.peek(); op {
+	case "==", "!=", "<", "<=", ">", ">=", "contains", "startsWith", "endsWith", "matches":
+		p.pos++
+		right, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		return compare(op, left, right)
+	case "in":
+		p.pos++
+		return p.in(left)
+	}
+	return left, nil
+}
+
+// in parses the list of literals of the in operator of x.
+func (p *parser) in(x node) (node, error) {
+	if p.peek() != "[" {
+		return nil, fmt.Errorf("in requires a list in brackets")
+	}
+	p.pos++
+	var values []any
+	for p.peek() != "]" {
+		if len(values) > 0 {
+			if p.peek() != "," {
+				return nil, fmt.Errorf("missing ]")
+			}
+			p.pos++
+		}
+		item, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		// checked as x == item
+		item, err = compare("==", x, item)
+		if err != nil {
+			return nil, fmt.Errorf("in list: %w", err)
+		}
+		lit, ok := item.(compareNode).right.(literal)
+		if !ok {
+			return nil, fmt.Errorf("in requires a list of literals")
+		}
+		values = append(values, lit.value)
+	}
+	p.pos++
+	return inNode{x: x, values: values}, nil
+}
+
+func (p *parser) operand() (node, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	switch {
+	case tok.text == "(":
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return x, nil
+	case tok.literal != nil:
+		if s, ok := tok.literal.(string); ok {
+			return literal{value: s, k: kindString}, nil
+		}
+		return literal{value: tok.literal, k: kindNumber}, nil
+	case tok.text == "true" || tok.text == "false":
+		return literal{value: tok.text == "true", k: kindBool}, nil
+	case isIdentifier(tok.text[0]):
+		return p.field(tok.text)
+	}
+	return nil, fmt.Errorf("unexpected %s", tok.text)
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func (p *parser) field(name string) (node, error) {
+	f, ok := p.typ.FieldByName(name)
+	if !ok || !f.IsExported() {
+		return nil, fmt.Errorf("unknown field %s", name)
+	}
+	switch {
+	case f.Type == durationType:
+		return field{index: f.Index, k: kindDuration}, nil
+	case f.Type.Kind() == reflect.String:
+		return field{index: f.Index, k: kindString}, nil
+	case f.Type.Kind() == reflect.Bool:
+		return field{index: f.Index, k: kindBool}, nil
+	case f.Type.Kind() >= reflect.Int && f.Type.Kind() <= reflect.Float64:
+		return field{index: f.Index, k: kindNumber}, nil
+	}
+	return nil, fmt.Errorf("field %s of type %s cannot be used", name, f.Type)
+}
+
+// logical returns && or || of left and right.
+func logical(op string, left, right node) (node, error) {
+	if left.kind() != kindBool || right.kind() != kindBool {
+		return nil, fmt.Errorf("%s of %s and %s", op, left.kind(), right.kind())
+	}
+	return logicalNode{op: op, left: left, right: right}, nil
+}
+
+// compare returns the comparison op of left and right.
+func compare(op string, left, right node) (node, error) {
+	// durations are written as strings
+	for _, pair := range [][2]*node{{&left, &right}, {&right, &left}} {
+		if (*pair[0]).kind() == kindDuration {
+			if lit, ok := (*pair[1]).(literal); ok && lit.k == kindString {
+				d, err := time.ParseDuration(lit.value.(string))
+				if err != nil {
+					return nil, fmt.Errorf("invalid duration %q", lit.value)
+				}
+				*pair[1] = literal{value: d, k: kindDuration}
+			}
+		}
+	}
+
+	switch op {
+	case "contains", "startsWith", "endsWith", "matches":
+		if left.kind() != kindString || right.kind() != kindString {
+			return nil, fmt.Errorf("%s of %s and %s", op, left.kind(), right.kind())
+		}
+		if op == "matches" {
+			lit, ok := right.(literal)
+			if !ok {
+				return nil, fmt.Errorf("matches requires a regular expression in a string")
+			}
+			re, err := regexp.Compile(lit.value.(string))
+			if err != nil {
+				return nil, err
+			}
+			return matchNode{x: left, re: re}, nil
+		}
+	case "<", "<=", ">", ">=":
+		if left.kind() != right.kind() || left.kind() == kindBool {
+			return nil, fmt.Errorf("%s of %s and %s", op, left.kind(), right.kind())
+		}
+	default:
+		if left.kind() != right.kind() {
+			return nil, fmt.Errorf("%s of %s and %s", op, left.kind(), right.kind())
+		}
+	}
+	return compareNode{op: op, left: left, right: right}, nil
+}
+
+type literal struct {
+	value any
+	k     kind
+}
+
+func (l literal) kind() kind             { return l.k }
+func (l literal) eval(reflect.Value) any { return l.value }
+
+type field struct {
+	index []int
+	k     kind
+}
+
+func (f field) kind() kind { return f.k }
+
+func (f field) eval(v reflect.Value) any {
+	value := v.FieldByIndex(f.index)
+	switch f.k {
+	case kindDuration:
+		return time.Duration(value.Int())
+	case kindString:
+		return value.String()
+	case kindBool:
+		return value.Bool()
+	}
+	switch {
+	case value.CanInt():
+		return float64(value.Int())
+	case value.CanUint():
+		return float64(value.Uint())
+	}
+	return value.Float()
+}
+
+type notNode struct{ x node }
+
+func (n notNode) kind() kind               { return kindBool }
+func (n notNode) eval(v reflect.Value) any { return !n.x.eval(v).(bool) }
+
+type logicalNode struct {
+	op          string
+	left, right node
+}
+
+func (n logicalNode) kind() kind { return kindBool }
+
+func (n logicalNode) eval(v reflect.Value) any {
+	left := n.left.eval(v).(bool)
+	if n.op == "&&" {
+		return left && n.right.eval(v).(bool)
+	}
+	return left || n.right.eval(v).(bool)
+}
+
+type matchNode struct {
+	x  node
+	re *regexp.Regexp
+}
+
+func (n matchNode) kind() kind               { return kindBool }
+func (n matchNode) eval(v reflect.Value) any { return n.re.MatchString(n.x.eval(v).(string)) }
+
+type inNode struct {
+	x      node
+	values []any
+}
+
+func (n inNode) kind() kind { return kindBool }
+
+func (n inNode) eval(v reflect.Value) any {
+	x := n.x.eval(v)
+	for _, value := range n.values {
+		if x == value {
+			return true
+		}
+	}
+	return false
+}
+
+type compareNode struct {
+	op          string
+	left, right node
+}
+
+func (n compareNode) kind() kind { return kindBool }
+
+func (n compareNode) eval(v reflect.Value) any {
+	left, right := n.left.eval(v), n.right.eval(v)
+	switch n.op {
+	case "contains":
+		return strings.Contains(left.(string), right.(string))
+	case "startsWith":
+		return strings.HasPrefix(left.(string), right.(string))
+	case "endsWith":
+		return strings.HasSuffix(left.(string), right.(string))
+	case "==":
+		return left == right
+	case "!=":
+		return left != right
+	}
+
+	var order int
+	switch l := left.(type) {
+	case string:
+		order = strings.Compare(l, right.(string))
+	case float64:
+		order = cmpOrdered(l, right.(float64))
+	case time.Duration:
+		order = cmpOrdered(l, right.(time.Duration))
+	}
+	switch n.op {
+	case "<":
+		return order < 0
+	case "<=":
+		return order <= 0
+	case ">":
+		return order > 0
+	}
+	return order >= 0
+}
+
+func cmpOrdered[T float64 | time.Duration](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
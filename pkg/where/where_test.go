@@ -0,0 +1,147 @@
+package where
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type result struct {
+	Pod      string
+	Stdout   string
+	RetCode  int
+	Timeout  bool
+	Ratio    float64
+	Duration time.Duration
+	Lines    []string
+	internal string
+}
+
+var sample = result{
+	Pod:      "web-1",
+	Stdout:   "uid=0(root) gid=0(root)",
+	RetCode:  1,
+	Ratio:    0.5,
+	Duration: 90 * time.Second,
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`RetCode != 0 && Stdout contains "root"`, true},
+		{`RetCode == 0 || Stdout contains "root"`, true},
+		{`RetCode == 0 || Stdout contains "nobody"`, false},
+
+		// precedence: ! binds tighter than &&, && tighter than ||
+		{`RetCode == 0 && Pod == "web-1" || Ratio < 1`, true},
+		{`RetCode == 0 && (Pod == "web-1" || Ratio < 1)`, false},
+		{`Ratio < 1 || RetCode == 0 && Timeout`, true},
+		{`(Ratio < 1 || RetCode == 0) && Timeout`, false},
+		{`!Timeout && RetCode == 1`, true},
+		{`!(Timeout || RetCode == 1)`, false},
+		{`!!Timeout`, false},
+
+		// string literals
+		{`Pod startsWith "web-"`, true},
+		{`Pod endsWith "-1"`, true},
+		{`Pod matches "^web-[0-9]+$"`, true},
+		{`Pod == "web-\x31"`, true},
+		{`Stdout contains "\"root\""`, false},
+		{`Pod < "x"`, true},
+		{`"web-1" == Pod`, true},
+
+		// numbers, booleans and durations
+		{`RetCode >= 1 && RetCode <= 1`, true},
+		{`RetCode > -1`, true},
+		{`Ratio == 0.5`, true},
+		{`Timeout == false`, true},
+		{`Duration > "1m" && Duration < "2m"`, true},
+		{`"1m30s" == Duration`, true},
+
+		// list literals
+		{`Pod in ["web-0", "web-1"]`, true},
+		{`Pod in ["web-0"]`, false},
+		{`Pod in []`, false},
+		{`RetCode in [0, 1, 2]`, true},
+		{`Duration in ["90s"]`, true},
+		{`!(RetCode in [126, 127]) && Pod in ["web-1"]`, true},
+	}
+	for _, test := range tests {
+		expr, err := Parse(test.expr, reflect.TypeOf(result{}))
+		if err != nil {
+			t.Errorf("Parse(%s): %v", test.expr, err)
+			continue
+		}
+		if got := expr.Match(&sample); got != test.want {
+			t.Errorf("%s = %v, want %v", test.expr, got, test.want)
+		}
+		if got := expr.Match(sample); got != test.want {
+			t.Errorf("%s of a value = %v, want %v", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		// type errors
+		{`RetCode`, "number is not a condition"},
+		{`Pod`, "string is not a condition"},
+		{`RetCode == "1"`, "== of number and string"},
+		{`Timeout < true`, "< of bool and bool"},
+		{`RetCode contains "1"`, "contains of number and string"},
+		{`Pod && Timeout`, "&& of string and bool"},
+		{`!Pod`, "! of string"},
+		{`Duration > "soon"`, `invalid duration "soon"`},
+		{`Pod matches Stdout`, "matches requires a regular expression in a string"},
+		{`Pod matches "("`, "missing closing )"},
+		{`Pod in [1]`, "in list: == of string and number"},
+		{`Pod in [Stdout]`, "in requires a list of literals"},
+		{`Pod in "web-1"`, "in requires a list in brackets"},
+
+		// fields
+		{`Node == ""`, "unknown field Node"},
+		{`internal == ""`, "unknown field internal"},
+		{`Lines == ""`, "field Lines of type []string cannot be used"},
+
+		// malformed input
+		{``, "unexpected end"},
+		{`RetCode ==`, "unexpected end"},
+		{`(RetCode == 0`, "missing )"},
+		{`RetCode == 0)`, "unexpected )"},
+		{`RetCode == 0 Timeout`, "unexpected Timeout"},
+		{`Pod == "web`, "unterminated string"},
+		{`Pod == "\q"`, "invalid string"},
+		{`RetCode == 1.2.3`, "invalid number 1.2.3"},
+		{`RetCode == -`, "invalid number -"},
+		{`RetCode = 0`, "unexpected '='"},
+		{`Pod in ["web-1"`, "missing ]"},
+		{`Pod in ["web-0" "web-1"]`, "missing ]"},
+		{`&& Timeout`, "unexpected &&"},
+	}
+	for _, test := range tests {
+		_, err := Parse(test.expr, reflect.TypeOf(&result{}))
+		if err == nil {
+			t.Errorf("Parse(%s) succeeded, want %q", test.expr, test.want)
+			continue
+		}
+		if !strings.Contains(err.Error(), test.want) {
+			t.Errorf("Parse(%s) = %v, want %q", test.expr, err, test.want)
+		}
+	}
+}
+
+func TestMatchNil(t *testing.T) {
+	expr, err := Parse(`RetCode == 0`, reflect.TypeOf(result{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expr.Match((*result)(nil)) {
+		t.Error("nil matched")
+	}
+}